@@ -2,11 +2,15 @@ package cleanup
 
 import (
 	"log"
+	"time"
 )
 
 type CleanerContext struct {
 	DryRun        bool
 	Repair        bool
+	// Unused images newer than this are kept, defaults to
+	// DefaultImageRetention
+	ImageRetention time.Duration
 	LogInfo      *log.Logger
 	LogError     *log.Logger
 }
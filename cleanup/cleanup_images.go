@@ -0,0 +1,177 @@
+package cleanup
+
+import (
+	"github.com/openshift/geard/config"
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/docker"
+	"github.com/openshift/geard/systemd"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// How long unused images are kept by default
+const DefaultImageRetention = 7 * 24 * time.Hour
+
+type ImagesCleanup struct {
+	dockerSocket string
+	retentionAge time.Duration
+}
+
+func init() {
+	dockerURI := os.Getenv("DOCKER_URI")
+	if dockerURI == "" {
+		dockerURI = "unix:///var/run/docker.sock"
+	}
+
+	AddCleaner(&ImagesCleanup{dockerSocket: dockerURI, retentionAge: DefaultImageRetention})
+}
+
+// Remove images from the host that are
+// * not referenced by an installed container or a schedule
+// * not used by any container
+// * older than the retention of the context, or retentionAge
+func (r *ImagesCleanup) Clean(ctx *CleanerContext) {
+	ctx.LogInfo.Println("--- IMAGES CLEANUP ---")
+
+	retention := r.retentionAge
+	if ctx.ImageRetention > 0 {
+		retention = ctx.ImageRetention
+	}
+
+	client, err := docker.GetConnection(r.dockerSocket)
+	if err != nil {
+		ctx.LogError.Printf("Unable connect to docker: %s. Is daemon running?", r.dockerSocket)
+		return
+	}
+
+	used := referencedImages(ctx)
+
+	gears, err := client.ListContainers()
+	if err != nil {
+		ctx.LogError.Printf("Unable to list containers: %s", err.Error())
+		return
+	}
+	for _, cinfo := range gears {
+		used[normalizeImageName(cinfo.Image)] = true
+	}
+
+	images, err := client.ListImages()
+	if err != nil {
+		ctx.LogError.Printf("Unable to list images: %s", err.Error())
+		return
+	}
+
+	var freed int64
+	for _, image := range images {
+		if time.Since(time.Unix(image.Created, 0)) < retention || imageInUse(image.ID, image.RepoTags, used) {
+			continue
+		}
+
+		names := taggedNames(image.RepoTags)
+		if len(names) == 0 {
+			names = []string{image.ID}
+		}
+
+		if ctx.DryRun {
+			ctx.LogInfo.Printf("Image %s could be removed as it is unused (%d bytes).", strings.Join(names, ", "), image.Size)
+			freed += image.Size
+			continue
+		}
+
+		ctx.LogInfo.Printf("Removing unused image %s (%d bytes).", strings.Join(names, ", "), image.Size)
+		removed := true
+		// an image with several tags is deleted with its last tag
+		for _, name := range names {
+			if err := client.RemoveImage(name); err != nil {
+				ctx.LogError.Printf("Unable to remove image %s: %s", name, err.Error())
+				removed = false
+			}
+		}
+		if removed {
+			freed += image.Size
+		}
+	}
+
+	if ctx.DryRun {
+		ctx.LogInfo.Printf("%d bytes could be freed by removing images.", freed)
+	} else {
+		ctx.LogInfo.Printf("Freed %d bytes by removing images.", freed)
+	}
+}
+
+// The images named by installed unit files, including every version of
+// a unit that can be rolled back to, and by schedules.
+func referencedImages(ctx *CleanerContext) map[string]bool {
+	used := make(map[string]bool)
+
+	unitsPath := filepath.Join(config.ContainerBasePath(), "units")
+	filepath.Walk(unitsPath, func(path string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			ctx.LogError.Printf("cleanup_images: Can't read %s: %v", path, err)
+			return nil
+		}
+		// versions of a unit are named by the request that wrote them and
+		// have no extension
+		if info.IsDir() {
+			return nil
+		}
+
+		props, er := systemd.GetUnitFileProperties(path)
+		if er != nil {
+			ctx.LogError.Printf("cleanup_images: Can't read %s: %v", path, er)
+			return nil
+		}
+		if image := props["X-ContainerImage"]; image != "" {
+			used[normalizeImageName(image)] = true
+		}
+		return nil
+	})
+
+	schedules, err := containers.ListSchedules()
+	if err != nil && !os.IsNotExist(err) {
+		ctx.LogError.Printf("cleanup_images: Can't read schedules: %v", err)
+	}
+	for i := range schedules {
+		used[normalizeImageName(schedules[i].Image)] = true
+	}
+
+	return used
+}
+
+// Image names without a tag refer to the latest tag.
+func normalizeImageName(name string) string {
+	if _, _, tag := docker.ParseImageName(name); tag == "" {
+		return name + ":latest"
+	}
+	return name
+}
+
+func imageInUse(id string, tags []string, used map[string]bool) bool {
+	for _, tag := range tags {
+		if used[tag] {
+			return true
+		}
+	}
+	// containers of untagged images report at least the short image id
+	for name := range used {
+		if prefix := strings.TrimSuffix(name, ":latest"); len(prefix) >= 12 && strings.HasPrefix(id, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func taggedNames(tags []string) []string {
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if tag != "<none>:<none>" {
+			names = append(names, tag)
+		}
+	}
+	return names
+}
@@ -0,0 +1,96 @@
+package cleanup
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func imagesPayload() string {
+	return fmt.Sprintf(`[
+		{"Id":"f745cf264f86d6b819721e75126ce16af74c7dbb6a9087386a5377dab31f21c7","RepoTags":["pmorie/sti-html-app:latest"],"Created":1398871144,"Size":100},
+		{"Id":"8dbd9e392a964056420e5d58ca5cc376ef18e2de93b5cc90e868a1bbc8318c1c","RepoTags":["openshift/old-app:latest","openshift/old-app:1.0"],"Created":1398871144,"Size":200},
+		{"Id":"511136ea3c5a64f264b78b5433614aec563103b4d4702f3ba7d4d2698e22c158","RepoTags":["openshift/new-app:latest"],"Created":%d,"Size":400}
+	]`, time.Now().Unix())
+}
+
+func TestImagesDryRun(t *testing.T) {
+	routes := map[string]string{
+		"/info":                  info_payload,
+		"/containers/json?all=1": containers_payload,
+		"/images/json?all=0":     imagesPayload(),
+	}
+	server := httptest.NewServer(http.HandlerFunc(newHandler(t, routes)))
+	defer server.Close()
+
+	context, info, error := newContext(true, false)
+
+	plugin := &ImagesCleanup{dockerSocket: server.URL, retentionAge: 72 * time.Hour}
+	plugin.Clean(context)
+
+	if 0 != error.Len() {
+		t.Log(info)
+		t.Error(error)
+	}
+	if !strings.Contains(info.String(), "Image openshift/old-app:latest, openshift/old-app:1.0 could be removed") {
+		t.Errorf("Expected the unused image to be reported: \n%s", info)
+	}
+	if strings.Contains(info.String(), "pmorie/sti-html-app") || strings.Contains(info.String(), "openshift/new-app") {
+		t.Errorf("Reported an image that is in use or too new: \n%s", info)
+	}
+	if !strings.Contains(info.String(), "200 bytes could be freed") {
+		t.Errorf("Expected the freed bytes to be reported: \n%s", info)
+	}
+}
+
+func TestImagesRetention(t *testing.T) {
+	routes := map[string]string{
+		"/info":                  info_payload,
+		"/containers/json?all=1": containers_payload,
+		"/images/json?all=0":     imagesPayload(),
+	}
+	server := httptest.NewServer(http.HandlerFunc(newHandler(t, routes)))
+	defer server.Close()
+
+	context, info, error := newContext(true, false)
+	context.ImageRetention = 1000000 * time.Hour
+
+	plugin := &ImagesCleanup{dockerSocket: server.URL, retentionAge: 72 * time.Hour}
+	plugin.Clean(context)
+
+	if 0 != error.Len() {
+		t.Log(info)
+		t.Error(error)
+	}
+	if strings.Contains(info.String(), "could be removed") || !strings.Contains(info.String(), "0 bytes could be freed") {
+		t.Errorf("Expected images within the retention of the context to be kept: \n%s", info)
+	}
+}
+
+func TestImagesRemove(t *testing.T) {
+	routes := map[string]string{
+		"/info":                            info_payload,
+		"/containers/json?all=1":           containers_payload,
+		"/images/json?all=0":               imagesPayload(),
+		"/images/openshift/old-app:latest": "{}",
+		"/images/openshift/old-app:1.0":    "{}",
+	}
+	server := httptest.NewServer(http.HandlerFunc(newHandler(t, routes)))
+	defer server.Close()
+
+	context, info, error := newContext(false, false)
+
+	plugin := &ImagesCleanup{dockerSocket: server.URL, retentionAge: 72 * time.Hour}
+	plugin.Clean(context)
+
+	if 0 != error.Len() {
+		t.Log(info)
+		t.Error(error)
+	}
+	if !strings.Contains(info.String(), "Freed 200 bytes") {
+		t.Errorf("Failed to remove image: \n%s\n%s", info, error)
+	}
+}
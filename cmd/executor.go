@@ -94,6 +94,38 @@ func (e Executor) Stream() (failures []error) {
 	return
 }
 
+// Run the job of a single locator and copy its output to w unchanged.
+// Unlike Stream, which prefixes each line, the output may be binary,
+// like an archive, and is written as it arrives instead of buffered.
+func (e Executor) StreamTo(w io.Writer) error {
+	if len(e.On) != 1 {
+		return errors.New("Only a single locator may be streamed")
+	}
+	jobs := e.jobs(e.On)
+	if err := jobs.check(); err != nil {
+		return err
+	}
+	if len(jobs) != 1 {
+		return errors.New("Only a single job may be streamed")
+	}
+	job := jobs[0]
+	if locator := e.On[0].TransportLocator(); locator != transport.Local {
+		remote, err := e.Transport.RemoteJobFor(locator, job)
+		if err != nil {
+			return err
+		}
+		job = remote
+	} else if e.LocalInit != nil {
+		if err := e.LocalInit(); err != nil {
+			return err
+		}
+	}
+
+	response := &CliJobResponse{Output: w}
+	job.Execute(response)
+	return response.Error
+}
+
 func (e Executor) StreamAndExit() {
 	if errors := e.Stream(); len(errors) > 0 {
 		if e.OnFailure == nil {
@@ -3,11 +3,13 @@ package cmd
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/openshift/geard/containers"
 	"github.com/openshift/geard/port"
 	"log"
 	"os"
+	"strings"
 )
 
 func GenerateId() string {
@@ -71,9 +73,127 @@ func (n *NetworkLinks) Set(s string) error {
 	return nil
 }
 
+// A repeatable flag that collects health checks
+type HealthChecks struct {
+	containers.HealthChecks
+}
+
+func (h *HealthChecks) String() string {
+	checks := make([]string, len(h.HealthChecks))
+	for i := range h.HealthChecks {
+		checks[i] = h.HealthChecks[i].String()
+	}
+	return strings.Join(checks, ",")
+}
+
+func (h *HealthChecks) Set(s string) error {
+	check, err := containers.NewHealthCheckFromString(s)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return err
+	}
+	h.HealthChecks = append(h.HealthChecks, *check)
+	return nil
+}
+
+// Apply shared interval, timeout, and threshold values to each check
+func (h *HealthChecks) WithSettings(interval, timeout, threshold int) containers.HealthChecks {
+	checks := make(containers.HealthChecks, len(h.HealthChecks))
+	for i := range h.HealthChecks {
+		checks[i] = h.HealthChecks[i]
+		checks[i].Interval = interval
+		checks[i].Timeout = timeout
+		checks[i].Threshold = threshold
+	}
+	return checks
+}
+
+// A repeatable flag that collects lifecycle hooks
+type LifecycleHooks struct {
+	containers.LifecycleHooks
+}
+
+func (h *LifecycleHooks) String() string {
+	hooks := make([]string, len(h.LifecycleHooks))
+	for i := range h.LifecycleHooks {
+		hooks[i] = h.LifecycleHooks[i].String()
+	}
+	return strings.Join(hooks, ",")
+}
+
+func (h *LifecycleHooks) Set(s string) error {
+	hook, err := containers.NewLifecycleHookFromString(s)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return err
+	}
+	h.LifecycleHooks = append(h.LifecycleHooks, *hook)
+	return nil
+}
+
+// Apply a shared timeout to each hook
+func (h *LifecycleHooks) WithTimeout(timeout int) containers.LifecycleHooks {
+	hooks := make(containers.LifecycleHooks, len(h.LifecycleHooks))
+	for i := range h.LifecycleHooks {
+		hooks[i] = h.LifecycleHooks[i]
+		hooks[i].Timeout = timeout
+	}
+	return hooks
+}
+
+type VolumeMounts struct {
+	containers.VolumeMounts
+}
+
+func (v *VolumeMounts) String() string {
+	mounts := make([]string, len(v.VolumeMounts))
+	for i := range v.VolumeMounts {
+		mounts[i] = v.VolumeMounts[i].String()
+	}
+	return strings.Join(mounts, ",")
+}
+
+func (v *VolumeMounts) Set(s string) error {
+	mount, err := containers.NewVolumeMountFromString(s)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return err
+	}
+	v.VolumeMounts = append(v.VolumeMounts, *mount)
+	return nil
+}
+
+type ContainerDependencies struct {
+	containers.ContainerDependencies
+}
+
+func (d *ContainerDependencies) String() string {
+	deps := make([]string, len(d.ContainerDependencies))
+	for i := range d.ContainerDependencies {
+		deps[i] = d.ContainerDependencies[i].String()
+	}
+	return strings.Join(deps, ",")
+}
+
+func (d *ContainerDependencies) Set(s string) error {
+	dep, err := containers.NewContainerDependencyFromString(s)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return err
+	}
+	d.ContainerDependencies = append(d.ContainerDependencies, *dep)
+	return nil
+}
+
 type EnvironmentDescription struct {
 	Description containers.EnvironmentDescription
 	Path        string
+	// KEY=VALUE pairs stored as secrets
+	Secrets EnvironmentVariables
+	// How Description.Source is fetched, and the <user>:<password> sent
+	// to it
+	SourceOptions containers.EnvironmentSourceOptions
+	SourceUser    string
 }
 
 func (e *EnvironmentDescription) ExtractVariablesFrom(args *[]string, generateId bool) error {
@@ -93,9 +213,61 @@ func (e *EnvironmentDescription) ExtractVariablesFrom(args *[]string, generateId
 		return err
 	}
 	e.Description.Variables = append(e.Description.Variables, env...)
+	for _, s := range e.Secrets {
+		secret := containers.Environment{Secret: true}
+		secret.FromString(s)
+		e.Description.Variables = append(e.Description.Variables, secret)
+	}
+	if e.SourceUser != "" {
+		pair := strings.SplitN(e.SourceUser, ":", 2)
+		e.SourceOptions.Username = pair[0]
+		if len(pair) == 2 {
+			e.SourceOptions.Password = pair[1]
+		}
+	}
+	if e.SourceOptions != (containers.EnvironmentSourceOptions{}) {
+		if e.Description.Source == "" {
+			return errors.New("The environment source options require --env-url")
+		}
+		options := e.SourceOptions
+		e.Description.SourceOptions = &options
+	}
 	if generateId && !e.Description.Empty() && e.Description.Id == "" {
 		e.Description.Id = containers.Identifier(GenerateId())
 		log.Printf("Setting --env-id to %s", e.Description.Id)
 	}
 	return nil
 }
+
+// A repeatable flag that collects KEY=VALUE pairs
+type EnvironmentVariables []string
+
+func (e *EnvironmentVariables) String() string {
+	return strings.Join(*e, ",")
+}
+
+func (e *EnvironmentVariables) Set(s string) error {
+	if !strings.Contains(s, "=") || strings.HasPrefix(s, "=") {
+		err := fmt.Errorf("The environment variable '%s' must be of the form KEY=VALUE", s)
+		fmt.Fprintln(os.Stderr, err.Error())
+		return err
+	}
+	*e = append(*e, s)
+	return nil
+}
+
+// A repeatable flag that collects comma separated capability names
+type Capabilities []string
+
+func (c *Capabilities) String() string {
+	return strings.Join(*c, ",")
+}
+
+func (c *Capabilities) Set(s string) error {
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			*c = append(*c, name)
+		}
+	}
+	return nil
+}
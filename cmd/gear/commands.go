@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -10,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/openshift/docker-source-to-images/go"
@@ -20,12 +22,14 @@ import (
 	cjobs "github.com/openshift/geard/containers/jobs"
 	"github.com/openshift/geard/deployment"
 	"github.com/openshift/geard/dispatcher"
+	"github.com/openshift/geard/docker"
 	"github.com/openshift/geard/encrypted"
 	"github.com/openshift/geard/http"
 	"github.com/openshift/geard/jobs"
 	"github.com/openshift/geard/port"
 	"github.com/openshift/geard/systemd"
 	"github.com/openshift/geard/transport"
+	"github.com/openshift/geard/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -34,14 +38,63 @@ var (
 	post   bool
 	follow bool
 
-	resetEnv bool
+	resetEnv      bool
+	restartEnv    bool
+	envRollbackTo int
 
 	start   bool
 	isolate bool
+	userns  bool
 	sockAct bool
 
-	keyPath   string
-	expiresAt int64
+	keyPath    string
+	expiresAt  int64
+	imageToken string
+
+	restartPolicy      string
+	restartBackoff     int
+	restartMaxAttempts int
+
+	healthChecks    HealthChecks
+	healthInterval  int
+	healthTimeout   int
+	healthThreshold int
+	healthWait      int
+
+	hooks       LifecycleHooks
+	hookTimeout int
+	hookMethod  string
+	hookURL     string
+
+	sliceName  string
+	sliceLimit containers.SliceDescription
+
+	volumeMounts VolumeMounts
+	dependencies ContainerDependencies
+	pullFirst    bool
+
+	schedule containers.ScheduleDescription
+
+	profileName    string
+	profile        containers.SecurityProfile
+	profileCapDrop Capabilities
+	profileCapAdd  Capabilities
+
+	labels        = containers.Labels{}
+	labelSelector string
+
+	rollbackVersion string
+
+	logOptions systemd.LogOptions
+	logTimeout int
+	logOutput  string
+
+	statsAll      bool
+	statsInterval int
+
+	execInteractive bool
+	execTty         bool
+	execEnvironment EnvironmentVariables
 
 	environment  EnvironmentDescription
 	portPairs    PortPairs
@@ -60,8 +113,9 @@ var (
 
 	listenAddr string
 
-	dryRun bool
-	repair bool
+	dryRun         bool
+	repair         bool
+	imageRetention time.Duration
 
 	defaultTransport transport.TransportFlag
 )
@@ -97,6 +151,7 @@ func Execute() {
 	gearCmd.PersistentFlags().StringVarP(&(conf.Docker.Socket), "docker-socket", "S", "unix:///var/run/docker.sock", "Set the docker socket to use")
 	gearCmd.PersistentFlags().BoolVar(&(config.SystemDockerFeatures.EnvironmentFile), "has-env-file", false, "(experimental) Use --env-file with Docker, requires master from Apr 1st")
 	gearCmd.PersistentFlags().BoolVar(&(config.SystemDockerFeatures.ForegroundRun), "has-foreground", false, "(experimental) Use --foreground with Docker, requires alexlarsson/forking-run")
+	gearCmd.PersistentFlags().BoolVar(&(config.SystemDockerFeatures.UserNamespaces), "has-userns", false, "(experimental) Use --uidmap and --gidmap with Docker to run containers in a user namespace")
 	gearCmd.PersistentFlags().StringVar(&deploymentPath, "with", "", "Provide a deployment descriptor to operate on")
 	gearCmd.PersistentFlags().Var(&defaultTransport, "transport", "Specify an alternate mechanism to connect to the gear agent")
 
@@ -116,21 +171,45 @@ func Execute() {
 		Run:   installImage,
 	}
 	installImageCmd.Flags().VarP(&portPairs, "ports", "p", "List of comma separated port pairs to bind '<internal>:<external>,...'. Use zero to request a port be assigned.")
-	installImageCmd.Flags().VarP(&networkLinks, "net-links", "n", "List of comma separated port pairs to wire '<local_host>:<local_port>:<remote_host>:<remote_port>,...'. local_host may be empty. It defaults to 127.0.0.1.")
+	installImageCmd.Flags().VarP(&networkLinks, "net-links", "n", "List of comma separated port pairs to wire '[<name>=]<local_host>:<local_port>:<remote_host>:<remote_port>,...'. local_host may be empty. It defaults to 127.0.0.1. A named link sets LINK_<NAME>_HOST and LINK_<NAME>_PORT in the environment.")
 	installImageCmd.Flags().BoolVar(&start, "start", false, "Start the container immediately")
 	installImageCmd.Flags().BoolVar(&isolate, "isolate", false, "Use an isolated container running as a user")
+	installImageCmd.Flags().BoolVar(&userns, "userns", false, "Use an isolated container whose users are mapped to a range of host users (experimental, requires --has-userns)")
 	installImageCmd.Flags().BoolVar(&sockAct, "socket-activated", false, "Use a socket-activated container (experimental, requires Docker branch)")
 	installImageCmd.Flags().StringVar(&environment.Path, "env-file", "", "Path to an environment file to load")
-	installImageCmd.Flags().StringVar(&environment.Description.Source, "env-url", "", "A url to download environment files from")
+	installImageCmd.Flags().Var(&environment.Secrets, "secret", "A variable encrypted at rest and redacted when the environment is read: 'KEY=VALUE'. May be repeated.")
+	installImageCmd.Flags().StringVar(&environment.Description.Source, "env-url", "", "A url to download environment files from: http, https, file (inside /etc/geard/env) or git://<repository>/<path>[#<ref>]")
+	installImageCmd.Flags().StringVar(&environment.SourceOptions.CAPath, "env-ca", "", "The path of a PEM bundle of the CAs trusted for an https --env-url")
+	installImageCmd.Flags().StringVar(&environment.SourceOptions.BearerToken, "env-token", "", "A bearer token sent to an https --env-url")
+	installImageCmd.Flags().StringVar(&environment.SourceUser, "env-user", "", "A '<user>:<password>' sent to an https --env-url")
+	installImageCmd.Flags().StringVar(&environment.SourceOptions.Checksum, "env-sha256", "", "The hex SHA-256 digest the content of --env-url must match")
+	installImageCmd.Flags().IntVar(&environment.SourceOptions.RefreshInterval, "env-refresh", 0, "Seconds between fetches of --env-url by the daemon, each change is recorded as a new version")
+	installImageCmd.Flags().BoolVar(&environment.SourceOptions.RestartOnChange, "env-restart-on-change", false, "Restart the running containers that use the environment when a refresh of --env-url changes it")
 	installImageCmd.Flags().StringVar((*string)(&environment.Description.Id), "env-id", "", "An optional identifier for the environment being set")
+	installImageCmd.Flags().StringVar(&restartPolicy, "restart", "", "Restart the container when it exits: 'no', 'on-failure', or 'always'")
+	installImageCmd.Flags().IntVar(&restartBackoff, "restart-backoff", containers.DefaultRestartBackoff, "Seconds to wait before restarting the container")
+	installImageCmd.Flags().IntVar(&restartMaxAttempts, "restart-max", containers.DefaultRestartMaxAttempts, "Restarts allowed in a short period before the container is considered to be crash looping")
+	installImageCmd.Flags().Var(&healthChecks, "health-check", "A check that determines whether the container is available: 'tcp:<port>', 'http:<port>[/<path>]', or 'cmd:<command>'. May be repeated.")
+	installImageCmd.Flags().IntVar(&healthInterval, "health-interval", containers.DefaultHealthInterval, "Seconds between health checks")
+	installImageCmd.Flags().IntVar(&healthTimeout, "health-timeout", containers.DefaultHealthTimeout, "Seconds before a health check is considered failed")
+	installImageCmd.Flags().IntVar(&healthThreshold, "health-threshold", containers.DefaultHealthThreshold, "Consecutive health check results required to change the health of the container")
+	installImageCmd.Flags().Var(&volumeMounts, "volume", "A named volume to mount in the container: '<name>:<path>[:ro]'. May be repeated.")
+	installImageCmd.Flags().Var(&dependencies, "depends", "Another container on this host to start first: '<name>[:required]'. A required container also stops this one when it stops. May be repeated.")
+	installImageCmd.Flags().Var(labels, "label", "A label to attach to the container: '<key>=<value>'. May be repeated.")
+	installImageCmd.Flags().StringVar(&sliceName, "slice", string(containers.DefaultSlice), "The slice the container is placed in, which determines its resource limits")
+	installImageCmd.Flags().BoolVar(&pullFirst, "pull-first", false, "Pull the image before installing, and fail if it cannot be fetched")
+	installImageCmd.Flags().Var(&hooks, "hook", "An action run at a stage of the container lifecycle: '[-]<stage>:cmd:<command>' or '[-]<stage>:http:[<method> ]<url>', where stage is pre-start, post-start, pre-stop, or post-stop. A leading '-' ignores failures. May be repeated.")
+	installImageCmd.Flags().IntVar(&hookTimeout, "hook-timeout", containers.DefaultHookTimeout, "Seconds before a lifecycle hook is stopped and considered failed")
+	installImageCmd.Flags().StringVar(&profileName, "profile", "", "The security profile the container runs with (defaults to the profile of the daemon)")
 	AddCommand(gearCmd, installImageCmd, false)
 
 	deleteCmd := &cobra.Command{
-		Use:   "delete <name>...",
+		Use:   "delete [<name>... | -l <selector> <host>...]",
 		Short: "Delete an installed container",
 		Long:  "Deletes one or more installed containers from the system.  Will not clean up unused images.",
 		Run:   deleteContainer,
 	}
+	deleteCmd.Flags().StringVarP(&labelSelector, "selector", "l", "", "Act on the containers whose labels match a selector, e.g. 'app=shop,tier!=db'. The arguments are the hosts to search.")
 	AddCommand(gearCmd, deleteCmd, false)
 
 	buildCmd := &cobra.Command{
@@ -157,6 +236,15 @@ func Execute() {
 	}
 	setEnvCmd.Flags().BoolVar(&resetEnv, "reset", false, "Remove any existing values")
 	setEnvCmd.Flags().StringVar(&environment.Path, "env-file", "", "Path to an environment file to load")
+	setEnvCmd.Flags().Var(&environment.Secrets, "secret", "A variable encrypted at rest and redacted when the environment is read: 'KEY=VALUE'. May be repeated.")
+	setEnvCmd.Flags().BoolVar(&restartEnv, "restart", false, "Restart the running containers that use the environment")
+	setEnvCmd.Flags().StringVar(&environment.Description.Source, "env-url", "", "A url to download environment files from: http, https, file (inside /etc/geard/env) or git://<repository>/<path>[#<ref>]")
+	setEnvCmd.Flags().StringVar(&environment.SourceOptions.CAPath, "env-ca", "", "The path of a PEM bundle of the CAs trusted for an https --env-url")
+	setEnvCmd.Flags().StringVar(&environment.SourceOptions.BearerToken, "env-token", "", "A bearer token sent to an https --env-url")
+	setEnvCmd.Flags().StringVar(&environment.SourceUser, "env-user", "", "A '<user>:<password>' sent to an https --env-url")
+	setEnvCmd.Flags().StringVar(&environment.SourceOptions.Checksum, "env-sha256", "", "The hex SHA-256 digest the content of --env-url must match")
+	setEnvCmd.Flags().IntVar(&environment.SourceOptions.RefreshInterval, "env-refresh", 0, "Seconds between fetches of --env-url by the daemon, each change is recorded as a new version")
+	setEnvCmd.Flags().BoolVar(&environment.SourceOptions.RestartOnChange, "env-restart-on-change", false, "Restart the running containers that use the environment when a refresh of --env-url changes it")
 	AddCommand(gearCmd, setEnvCmd, false)
 
 	envCmd := &cobra.Command{
@@ -167,57 +255,312 @@ func Execute() {
 	}
 	AddCommand(gearCmd, envCmd, false)
 
+	envVersionsCmd := &cobra.Command{
+		Use:   "env-versions <name>...",
+		Short: "List the versions of one or more environments",
+		Long:  "Shows each version recorded by a change to the environment, newest first, with the variables it added (+), removed (-) or changed (~).  The current version is marked with '*'.",
+		Run:   environmentVersions,
+	}
+	AddCommand(gearCmd, envVersionsCmd, false)
+
+	rollbackEnvCmd := &cobra.Command{
+		Use:   "rollback-env <name>...",
+		Short: "Return environments to an earlier version",
+		Long:  "Restores the variables and secrets of an earlier version of each environment as a new version.  Defaults to the version before the current one.",
+		Run:   rollbackEnvironment,
+	}
+	rollbackEnvCmd.Flags().IntVar(&envRollbackTo, "to", 0, "The version to restore (see 'gear env-versions')")
+	rollbackEnvCmd.Flags().BoolVar(&restartEnv, "restart", false, "Restart the running containers that use the environment")
+	AddCommand(gearCmd, rollbackEnvCmd, false)
+
 	linkCmd := &cobra.Command{
 		Use:   "link <name>...",
 		Short: "Set network links for the named containers",
 		Long:  "Sets the network links for the named containers. A restart may be required to use the latest links.",
 		Run:   linkContainers,
 	}
-	linkCmd.Flags().VarP(&networkLinks, "net-links", "n", "List of comma separated port pairs to wire '<local_host>:local_port>:<host>:<remote_port>,...'. local_host may be empty. It defaults to 127.0.0.1")
+	linkCmd.Flags().VarP(&networkLinks, "net-links", "n", "List of comma separated port pairs to wire '[<name>=]<local_host>:local_port>:<host>:<remote_port>,...'. local_host may be empty. It defaults to 127.0.0.1")
 	gearCmd.AddCommand(linkCmd)
 
 	startCmd := &cobra.Command{
-		Use:   "start <name>...",
+		Use:   "start [<name>... | -l <selector> <host>...]",
 		Short: "Invoke systemd to start a container",
 		Long:  "Queues the start and immediately returns.", //  Use -f to attach to the logs.",
 		Run:   startContainer,
 	}
 	//startCmd.Flags().BoolVarP(&follow, "follow", "f", false, "Attach to the logs after startup")
+	startCmd.Flags().StringVarP(&labelSelector, "selector", "l", "", "Act on the containers whose labels match a selector, e.g. 'app=shop,tier!=db'. The arguments are the hosts to search.")
 	AddCommand(gearCmd, startCmd, false)
 
 	stopCmd := &cobra.Command{
-		Use:   "stop <name>...",
+		Use:   "stop [<name>... | -l <selector> <host>...]",
 		Short: "Invoke systemd to stop a container",
 		Long:  ``,
 		Run:   stopContainer,
 	}
+	stopCmd.Flags().StringVarP(&labelSelector, "selector", "l", "", "Act on the containers whose labels match a selector, e.g. 'app=shop,tier!=db'. The arguments are the hosts to search.")
 	AddCommand(gearCmd, stopCmd, false)
 
 	restartCmd := &cobra.Command{
-		Use:   "restart <name>...",
+		Use:   "restart [<name>... | -l <selector> <host>...]",
 		Short: "Invoke systemd to restart a container",
 		Long:  "Queues the restart and immediately returns.", //  Use -f to attach to the logs.",
 		Run:   restartContainer,
 	}
 	//startCmd.Flags().BoolVarP(&follow, "follow", "f", false, "Attach to the logs after startup")
+	restartCmd.Flags().StringVarP(&labelSelector, "selector", "l", "", "Act on the containers whose labels match a selector, e.g. 'app=shop,tier!=db'. The arguments are the hosts to search.")
 	AddCommand(gearCmd, restartCmd, false)
 
+	logsCmd := &cobra.Command{
+		Use:   "logs <name>...",
+		Short: "Retrieve the journal entries of one or more containers",
+		Long:  "Shows the most recent journal entries of each container, or the entries in a time range.  Use -f to follow new entries as they are written.",
+		Run:   containerLogs,
+	}
+	logsCmd.Flags().StringVar(&logOptions.Since, "since", "", "Show entries at or after a time: an RFC3339 timestamp or a journalctl time like 'today' or '-1h'")
+	logsCmd.Flags().StringVar(&logOptions.Until, "until", "", "Show entries before a time: an RFC3339 timestamp or a journalctl time")
+	logsCmd.Flags().IntVarP(&logOptions.Lines, "lines", "n", 0, "The number of recent entries to show, -1 for all entries (default 30 without --since or --until)")
+	logsCmd.Flags().StringVar(&logOptions.Cursor, "cursor", "", "Show only the entries after the entry with this journal cursor (see '--output=json')")
+	logsCmd.Flags().StringVarP(&logOptions.Priority, "priority", "p", "", "Show entries at or above a priority from 0 (emerg) to 7 (debug), or a range like 'err..info'")
+	logsCmd.Flags().BoolVarP(&follow, "follow", "f", false, "Continue to show new entries as they are written")
+	logsCmd.Flags().IntVar(&logTimeout, "timeout", 0, "Stop following after this many seconds, 0 to follow until interrupted")
+	logsCmd.Flags().StringVarP(&logOutput, "output", "o", "text", "The format of each entry: 'text' or 'json'")
+	AddCommand(gearCmd, logsCmd, false)
+
 	statusCmd := &cobra.Command{
-		Use:   "status <name>...",
+		Use:   "status [<name>... | -l <selector> <host>...]",
 		Short: "Retrieve the systemd status of one or more containers",
 		Long:  "Shows the equivalent of 'systemctl status ctr-<name>' for each listed unit",
 		Run:   containerStatus,
 	}
+	statusCmd.Flags().StringVarP(&labelSelector, "selector", "l", "", "Act on the containers whose labels match a selector, e.g. 'app=shop,tier!=db'. The arguments are the hosts to search.")
 	AddCommand(gearCmd, statusCmd, false)
 
+	waitHealthyCmd := &cobra.Command{
+		Use:   "wait-healthy <name>...",
+		Short: "Wait for the health checks of containers to pass",
+		Long:  "Waits until the health checks of each container report a new result, and fails if a container is unhealthy or no result arrives before the timeout.  Use after install or start to block until the containers are available.",
+		Run:   waitHealthy,
+	}
+	waitHealthyCmd.Flags().IntVar(&healthWait, "timeout", cjobs.DefaultHealthWait, "Seconds to wait for a result")
+	waitHealthyCmd.Flags().StringVarP(&labelSelector, "selector", "l", "", "Act on the containers whose labels match a selector, e.g. 'app=shop,tier!=db'. The arguments are the hosts to search.")
+	AddCommand(gearCmd, waitHealthyCmd, false)
+
 	listUnitsCmd := &cobra.Command{
 		Use:   "list-units <host>...",
 		Short: "Retrieve the list of services across all hosts",
 		Long:  "Shows the equivalent of 'systemctl list-units ctr-<name>' for each installed container",
 		Run:   listUnits,
 	}
+	listUnitsCmd.Flags().StringVarP(&labelSelector, "selector", "l", "", "Only list the containers whose labels match a selector, e.g. 'app=shop,tier!=db'")
 	AddCommand(gearCmd, listUnitsCmd, false)
 
+	statsCmd := &cobra.Command{
+		Use:   "stats [<name>... | --all <host>... | -l <selector> <host>...]",
+		Short: "Show the resource usage of running containers",
+		Long:  "Shows the CPU time, memory, task count and block IO of each listed container, read from the cgroup of its unit.  With --all, or with no arguments, every running container on the listed hosts is shown.",
+		Run:   containerStats,
+	}
+	statsCmd.Flags().BoolVar(&statsAll, "all", false, "Show every running container on the listed hosts")
+	statsCmd.Flags().IntVar(&statsInterval, "interval", 0, "Refresh the usage every interval seconds")
+	statsCmd.Flags().StringVarP(&labelSelector, "selector", "l", "", "Act on the containers whose labels match a selector, e.g. 'app=shop,tier!=db'. The arguments are the hosts to search.")
+	AddCommand(gearCmd, statsCmd, false)
+
+	createSliceCmd := &cobra.Command{
+		Use:   "create-slice <name>...",
+		Short: "Create or update a resource slice for containers",
+		Long:  "Defines a systemd slice that containers may be placed in.  The name of a slice must begin with the name of its parent, e.g. 'container-large' is nested under 'container'.  Updating an existing slice applies the new limits to running containers.",
+		Run:   createSlice,
+	}
+	createSliceCmd.Flags().StringVar(&sliceLimit.MemoryLimit, "memory", "", "The maximum memory available to all containers in the slice (e.g. 512M, 2G)")
+	createSliceCmd.Flags().Uint64Var(&sliceLimit.CPUShares, "cpu-shares", 0, "The relative CPU weight of the slice")
+	createSliceCmd.Flags().Uint64Var(&sliceLimit.TasksMax, "tasks", 0, "The maximum number of tasks that may run in the slice")
+	createSliceCmd.Flags().StringVar((*string)(&sliceLimit.Parent), "parent", "", "The parent slice (defaults to the name up to the last dash)")
+	AddCommand(gearCmd, createSliceCmd, false)
+
+	deleteSliceCmd := &cobra.Command{
+		Use:   "delete-slice <name>...",
+		Short: "Delete a resource slice",
+		Long:  "Removes a slice that is no longer referenced by any container or child slice.  The default slices may not be deleted.",
+		Run:   deleteSlice,
+	}
+	AddCommand(gearCmd, deleteSliceCmd, false)
+
+	listSlicesCmd := &cobra.Command{
+		Use:   "list-slices <host>...",
+		Short: "Retrieve the resource slices defined on each host",
+		Long:  "Shows the slices containers may be placed in and their resource limits",
+		Run:   listSlices,
+	}
+	AddCommand(gearCmd, listSlicesCmd, false)
+
+	setSliceCmd := &cobra.Command{
+		Use:   "set-slice <slice> <name>...",
+		Short: "Move installed containers into a different slice",
+		Long:  "Changes the slice of the named containers.  Running containers are moved immediately when systemd allows it, otherwise the change takes effect on the next restart.",
+		Run:   setContainerSlice,
+	}
+	AddCommand(gearCmd, setSliceCmd, false)
+
+	createVolumeCmd := &cobra.Command{
+		Use:   "create-volume <name>...",
+		Short: "Create a named volume for containers",
+		Long:  "Creates a persistent directory that may be mounted into containers with 'install --volume <name>:<path>'.  Volumes are kept when the containers that mount them are deleted.",
+		Run:   createVolume,
+	}
+	AddCommand(gearCmd, createVolumeCmd, false)
+
+	deleteVolumeCmd := &cobra.Command{
+		Use:   "delete-volume <name>...",
+		Short: "Delete a named volume and its contents",
+		Long:  "Removes a volume that is no longer mounted by any installed container.",
+		Run:   deleteVolume,
+	}
+	AddCommand(gearCmd, deleteVolumeCmd, false)
+
+	listVolumesCmd := &cobra.Command{
+		Use:   "list-volumes <host>...",
+		Short: "Retrieve the named volumes defined on each host",
+		Long:  "Shows the volumes that may be mounted into containers",
+		Run:   listVolumes,
+	}
+	AddCommand(gearCmd, listVolumesCmd, false)
+
+	createProfileCmd := &cobra.Command{
+		Use:   "create-profile <name>...",
+		Short: "Create or update a security profile for containers",
+		Long:  "Defines the capabilities, seccomp profile, root filesystem and SELinux type of the containers installed with 'install --profile <name>'.  Installed containers keep the options they were installed with.",
+		Run:   createProfile,
+	}
+	createProfileCmd.Flags().Var(&profileCapDrop, "cap-drop", "Capabilities to remove from the container, e.g. 'ALL' or 'NET_RAW,MKNOD'. May be repeated.")
+	createProfileCmd.Flags().Var(&profileCapAdd, "cap-add", "Capabilities to add to the container, e.g. 'NET_BIND_SERVICE'. May be repeated.")
+	createProfileCmd.Flags().StringVar(&profile.Seccomp, "seccomp", "", "The path of a seccomp profile on the host, or 'unconfined'")
+	createProfileCmd.Flags().BoolVar(&profile.ReadOnlyRootfs, "read-only", false, "Mount the root filesystem of the container read-only")
+	createProfileCmd.Flags().BoolVar(&profile.NoNewPrivileges, "no-new-privileges", false, "Prevent the processes of the container from gaining privileges")
+	createProfileCmd.Flags().StringVar(&profile.SELinuxType, "selinux-type", "", "The SELinux type the processes of the container run as")
+	AddCommand(gearCmd, createProfileCmd, false)
+
+	deleteProfileCmd := &cobra.Command{
+		Use:   "delete-profile <name>...",
+		Short: "Delete a security profile",
+		Long:  "Removes a security profile that is no longer used by any installed container.",
+		Run:   deleteProfile,
+	}
+	AddCommand(gearCmd, deleteProfileCmd, false)
+
+	listProfilesCmd := &cobra.Command{
+		Use:   "list-profiles <host>...",
+		Short: "Retrieve the security profiles defined on each host",
+		Long:  "Shows the security profiles containers may be installed with",
+		Run:   listProfiles,
+	}
+	AddCommand(gearCmd, listProfilesCmd, false)
+
+	pullCmd := &cobra.Command{
+		Use:   "pull <image> <host>...",
+		Short: "Pull an image onto one or more hosts",
+		Long:  "Downloads the image to each host ahead of a rollout, so that containers do not wait for it on their first start.  The image is named as [<registry>/]<repository>[:<tag>].",
+		Run:   pullImage,
+	}
+	AddCommand(gearCmd, pullCmd, false)
+
+	copyImageCmd := &cobra.Command{
+		Use:   "copy-image <image> <source_host> <host>...",
+		Short: "Copy an image from one host directly to others",
+		Long:  "Each host loads the image exported by the source host, without a registry.  The hosts are authorized with a content token signed with the keys in --key-path, or the token passed with --token (see create-token).",
+		Run:   copyImage,
+	}
+	copyImageCmd.Flags().StringVar(&imageToken, "token", "", "A token created with 'gear create-token image <image>'")
+	copyImageCmd.Flags().Int64Var(&expiresAt, "expires-at", time.Now().Unix()+3600, "Specify the token expiration time in seconds after the Unix epoch")
+	AddCommand(gearCmd, copyImageCmd, false)
+
+	createScheduleCmd := &cobra.Command{
+		Use:   "create-schedule <name> --calendar=<spec> <image> [-- <arg>...]",
+		Short: "Run an image on a schedule",
+		Long:  "Creates or updates a job that runs the image whenever the calendar specification elapses, e.g. 'daily' or 'Mon..Fri *-*-* 02:00:00' (see systemd.time(7)).  Arguments after the image are passed to it, use -- before arguments that begin with a dash.",
+		Run:   createSchedule,
+	}
+	createScheduleCmd.Flags().StringVar(&schedule.Calendar, "calendar", "", "When the job runs, in systemd calendar syntax")
+	createScheduleCmd.Flags().StringVar(&schedule.Command, "entrypoint", "", "Override the entrypoint of the image")
+	createScheduleCmd.Flags().BoolVar(&schedule.Persistent, "persistent", false, "Run at startup if a run was missed while the host was down")
+	AddCommand(gearCmd, createScheduleCmd, false)
+
+	deleteScheduleCmd := &cobra.Command{
+		Use:   "delete-schedule <name>...",
+		Short: "Delete a scheduled job and its run history",
+		Long:  "Removes the timer and service of the scheduled job.  A run in progress is stopped.",
+		Run:   deleteSchedule,
+	}
+	AddCommand(gearCmd, deleteScheduleCmd, false)
+
+	listSchedulesCmd := &cobra.Command{
+		Use:   "list-schedules <host>...",
+		Short: "Retrieve the scheduled jobs defined on each host",
+		Long:  "Shows each scheduled job, its calendar specification and the result of its last run",
+		Run:   listSchedules,
+	}
+	AddCommand(gearCmd, listSchedulesCmd, false)
+
+	scheduleRunsCmd := &cobra.Command{
+		Use:   "schedule-runs <name>...",
+		Short: "Show the past runs of a scheduled job",
+		Long:  "Shows when each recent run started, how long it took and its result.  The output of a run follows its journal cursor: 'journalctl -u sched-<name> --after-cursor=<cursor>'.",
+		Run:   scheduleRuns,
+	}
+	AddCommand(gearCmd, scheduleRunsCmd, false)
+
+	runScheduleCmd := &cobra.Command{
+		Use:   "run-schedule <name>...",
+		Short: "Start a run of a scheduled job now",
+		Long:  "Runs the job immediately without changing its schedule.  Has no effect while a run is in progress.",
+		Run:   runSchedule,
+	}
+	AddCommand(gearCmd, runScheduleCmd, false)
+
+	versionsCmd := &cobra.Command{
+		Use:   "versions <name>...",
+		Short: "List the installed versions of one or more containers",
+		Long:  "Shows each unit definition written by an install of the container, newest first.  The active version is marked with '*'.",
+		Run:   containerVersions,
+	}
+	AddCommand(gearCmd, versionsCmd, false)
+
+	rollbackCmd := &cobra.Command{
+		Use:   "rollback <name>...",
+		Short: "Return containers to a previously installed version",
+		Long:  "Activates an earlier unit definition for each container, reserving its ports and restarting the container if it is running.  Defaults to the version installed before the active one.",
+		Run:   rollbackContainer,
+	}
+	rollbackCmd.Flags().StringVar(&rollbackVersion, "to", "", "The version to activate (see 'gear versions')")
+	AddCommand(gearCmd, rollbackCmd, false)
+
+	execCmd := &cobra.Command{
+		Use:   "exec <name> -- <command> [<arg>...]",
+		Short: "Run a command inside a running container",
+		Long:  "Runs the command in the namespaces of the container and exits with the status of the command.  Use --interactive to pass input to the command and --tty to allocate a terminal for it.",
+		Run:   execContainer,
+	}
+	execCmd.Flags().BoolVarP(&execInteractive, "interactive", "i", false, "Pass standard input to the command")
+	execCmd.Flags().BoolVarP(&execTty, "tty", "t", false, "Allocate a terminal for the command, implies --interactive")
+	execCmd.Flags().Var(&execEnvironment, "env", "An environment variable to set for the command: 'KEY=VALUE'. May be repeated.")
+	AddCommand(gearCmd, execCmd, false)
+
+	copyCmd := &cobra.Command{
+		Use:   "cp <name>:<path> <dir> | <path> <name>:<path>",
+		Short: "Copy files into or out of a container",
+		Long:  "Copies a file or directory from the container into a local directory, or a local file or directory into a directory in the container.  Paths inside a stopped container are only available within its volumes.",
+		Run:   copyFiles,
+	}
+	AddCommand(gearCmd, copyCmd, false)
+
+	moveCmd := &cobra.Command{
+		Use:   "move <host>/<name> <host>",
+		Short: "Move a container to another host",
+		Long:  "Stops the container and installs it on the destination host with its environment, network links, SSH access keys and data volumes, then deletes it from the source host.  New ports are reserved on the destination, so links to the container must be updated with the ports that are printed.",
+		Run:   moveContainer,
+	}
+	AddCommand(gearCmd, moveCmd, false)
+
 	ExtendCommands(gearCmd, false)
 
 	daemonCmd := &cobra.Command{
@@ -227,6 +570,8 @@ func Execute() {
 		Run:   daemon,
 	}
 	daemonCmd.Flags().StringVarP(&listenAddr, "listen-address", "A", ":43273", "Set the address for the http endpoint to listen on")
+	daemonCmd.Flags().StringVar(&config.DefaultSecurityProfile, "default-profile", "", "The security profile of containers installed without one")
+	daemonCmd.Flags().StringVar(&config.EnvironmentSourcePath, "env-source-path", config.EnvironmentSourcePath, "The directory file environment sources must be inside")
 	AddCommand(gearCmd, daemonCmd, true)
 
 	cleanCmd := &cobra.Command{
@@ -237,6 +582,7 @@ func Execute() {
 	}
 	cleanCmd.Flags().BoolVarP(&dryRun, "dry-run", "", false, "List the cleanups, but do not execute.")
 	cleanCmd.Flags().BoolVarP(&repair, "repair", "", false, "Perform potentially irrecoverable cleanups.")
+	cleanCmd.Flags().DurationVar(&imageRetention, "image-retention", cleanup.DefaultImageRetention, "Keep unused images newer than this, e.g. '72h'.")
 	AddCommand(gearCmd, cleanCmd, true)
 
 	purgeCmd := &cobra.Command{
@@ -257,6 +603,35 @@ func Execute() {
 	initGearCmd.Flags().BoolVarP(&post, "post", "", false, "Perform post-start initialization")
 	AddCommand(gearCmd, initGearCmd, true)
 
+	hookCmd := &cobra.Command{
+		Use:   "hook <name> <stage> [-- <command> <arg>...]",
+		Short: "(Local) Run a lifecycle hook of a container",
+		Long:  "",
+		Run:   runHook,
+	}
+	hookCmd.Flags().IntVar(&hookTimeout, "timeout", containers.DefaultHookTimeout, "Seconds before the hook is stopped and considered failed")
+	hookCmd.Flags().StringVar(&hookMethod, "method", "", "The HTTP method of the request")
+	hookCmd.Flags().StringVar(&hookURL, "url", "", "Send an HTTP request to the URL instead of running a command")
+	AddCommand(gearCmd, hookCmd, true)
+
+	runJobCmd := &cobra.Command{
+		Use:   "run-job <name>",
+		Short: "(Local) Run a one-off container and record its result",
+		Long:  "",
+		Run:   runJob,
+	}
+	AddCommand(gearCmd, runJobCmd, true)
+
+	recordRunCmd := &cobra.Command{
+		Use:   "record-run <name>",
+		Short: "(Local) Record the start or end of a run of a scheduled job",
+		Long:  "",
+		Run:   recordRun,
+	}
+	recordRunCmd.Flags().BoolVarP(&pre, "pre", "", false, "Record the start of a run")
+	recordRunCmd.Flags().BoolVarP(&post, "post", "", false, "Record the end of a run")
+	AddCommand(gearCmd, recordRunCmd, true)
+
 	createTokenCmd := &cobra.Command{
 		Use:   "create-token <type> <content_id>",
 		Short: "(Local) Generate a content request token",
@@ -351,6 +726,20 @@ func deployContainers(cmd *cobra.Command, args []string) {
 		Serial: func(on Locator) jobs.Job {
 			instance, _ := changes.Instances.Find(AsIdentifier(on))
 			links := instance.NetworkLinks()
+			var checks containers.HealthChecks
+			var restart *containers.RestartPolicy
+			var volumes containers.VolumeMounts
+			var labels containers.Labels
+			var profile containers.ProfileIdentifier
+			var hooks containers.LifecycleHooks
+			if c, found := changes.Containers.Find(instance.From); found {
+				checks = c.HealthChecks
+				restart = c.RestartPolicy
+				volumes = c.Volumes
+				labels = c.Labels
+				profile = c.SecurityProfile
+				hooks = c.Hooks
+			}
 			return &cjobs.InstallContainerRequest{
 				RequestIdentifier: jobs.NewRequestIdentifier(),
 
@@ -358,8 +747,15 @@ func deployContainers(cmd *cobra.Command, args []string) {
 				Image:   instance.Image,
 				Isolate: isolate,
 
-				Ports:        instance.Ports.PortPairs(),
-				NetworkLinks: &links,
+				Ports:           instance.Ports.PortPairs(),
+				NetworkLinks:    &links,
+				HealthChecks:    checks,
+				RestartPolicy:   restart,
+				Volumes:         volumes,
+				Labels:          labels,
+				Dependencies:    instance.Dependencies(),
+				SecurityProfile: profile,
+				Hooks:           hooks,
 			}
 		},
 		OnSuccess: func(r *CliJobResponse, w io.Writer, job interface{}) {
@@ -469,11 +865,29 @@ func installImage(cmd *cobra.Command, args []string) {
 				Image:            imageId,
 				Started:          start,
 				Isolate:          isolate,
+				UserNamespace:    userns,
 				SocketActivation: sockAct,
+				SecurityProfile:  containers.ProfileIdentifier(profileName),
 
 				Ports:        *portPairs.Get().(*port.PortPairs),
 				Environment:  &environment.Description,
 				NetworkLinks: networkLinks.NetworkLinks,
+				HealthChecks: healthChecks.WithSettings(healthInterval, healthTimeout, healthThreshold),
+				Hooks:        hooks.WithTimeout(hookTimeout),
+				Slice:        containers.SliceIdentifier(sliceName),
+				Volumes:      volumeMounts.VolumeMounts,
+				Labels:       labels,
+				Dependencies: dependencies.ContainerDependencies,
+
+				PullFirst:    pullFirst,
+				DockerSocket: conf.Docker.Socket,
+			}
+			if restartPolicy != "" {
+				r.RestartPolicy = &containers.RestartPolicy{
+					Policy:      containers.RestartPolicyType(restartPolicy),
+					Backoff:     restartBackoff,
+					MaxAttempts: restartMaxAttempts,
+				}
 			}
 			return &r
 		},
@@ -538,11 +952,11 @@ func setEnvironment(cmd *cobra.Command, args []string) {
 		On: ids,
 		Serial: func(on Locator) jobs.Job {
 			environment.Description.Id = AsIdentifier(on)
-			if resetEnv {
-				return &cjobs.PutEnvironmentRequest{environment.Description}
+			if resetEnv || environment.Description.Source != "" {
+				return &cjobs.PutEnvironmentRequest{EnvironmentDescription: environment.Description, Restart: restartEnv}
 			}
 
-			return &cjobs.PatchEnvironmentRequest{environment.Description}
+			return &cjobs.PatchEnvironmentRequest{EnvironmentDescription: environment.Description, Restart: restartEnv}
 		},
 		Output:    os.Stdout,
 		LocalInit: needsSystemdAndData,
@@ -586,10 +1000,79 @@ func showEnvironment(cmd *cobra.Command, args []string) {
 	os.Exit(0)
 }
 
+func environmentVersions(cmd *cobra.Command, args []string) {
+	if len(args) < 1 {
+		Fail(1, "Valid arguments: <id> ...")
+	}
+	ids, err := NewContainerLocators(defaultTransport.Get(), args...)
+	if err != nil {
+		Fail(1, "You must pass one or more valid environment ids: %s", err.Error())
+	}
+
+	data, errors := Executor{
+		On: ids,
+		Serial: func(on Locator) jobs.Job {
+			return &cjobs.EnvironmentVersionsRequest{
+				Id:    AsIdentifier(on),
+				Label: on.Identity(),
+			}
+		},
+		Output:    os.Stdout,
+		LocalInit: needsData,
+		Transport: defaultTransport.Get(),
+	}.Gather()
+
+	for i := range data {
+		if r, ok := data[i].(*cjobs.EnvironmentVersionsResponse); ok {
+			if i > 0 {
+				fmt.Fprintf(os.Stdout, "\n")
+			}
+			r.WriteTableTo(os.Stdout)
+		}
+	}
+	if len(errors) > 0 {
+		for i := range errors {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", errors[i])
+		}
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func rollbackEnvironment(cmd *cobra.Command, args []string) {
+	if len(args) < 1 {
+		Fail(1, "Valid arguments: <id> ...")
+	}
+	if envRollbackTo < 0 {
+		Fail(1, "The version to restore must be a positive number")
+	}
+	ids, err := NewContainerLocators(defaultTransport.Get(), args...)
+	if err != nil {
+		Fail(1, "You must pass one or more valid environment ids: %s", err.Error())
+	}
+
+	Executor{
+		On: ids,
+		Serial: func(on Locator) jobs.Job {
+			return &cjobs.RollbackEnvironmentRequest{
+				Id:      AsIdentifier(on),
+				Version: envRollbackTo,
+				Restart: restartEnv,
+			}
+		},
+		Output:    os.Stdout,
+		LocalInit: needsSystemdAndData,
+		Transport: defaultTransport.Get(),
+	}.StreamAndExit()
+}
+
 func deleteContainer(cmd *cobra.Command, args []string) {
 	if err := ExtractContainerLocatorsFromDeployment(defaultTransport.Get(), deploymentPath, &args); err != nil {
 		Fail(1, err.Error())
 	}
+	if err := extractContainerLocatorsFromSelector(labelSelector, &args); err != nil {
+		Fail(1, err.Error())
+	}
 
 	if len(args) < 1 {
 		Fail(1, "Valid arguments: <id> ...")
@@ -656,6 +1139,9 @@ func startContainer(cmd *cobra.Command, args []string) {
 	if err := ExtractContainerLocatorsFromDeployment(defaultTransport.Get(), deploymentPath, &args); err != nil {
 		Fail(1, err.Error())
 	}
+	if err := extractContainerLocatorsFromSelector(labelSelector, &args); err != nil {
+		Fail(1, err.Error())
+	}
 	if len(args) < 1 {
 		Fail(1, "Valid arguments: <id> ...")
 	}
@@ -681,6 +1167,9 @@ func stopContainer(cmd *cobra.Command, args []string) {
 	if err := ExtractContainerLocatorsFromDeployment(defaultTransport.Get(), deploymentPath, &args); err != nil {
 		Fail(1, err.Error())
 	}
+	if err := extractContainerLocatorsFromSelector(labelSelector, &args); err != nil {
+		Fail(1, err.Error())
+	}
 	if len(args) < 1 {
 		Fail(1, "Valid arguments: <id> ...")
 	}
@@ -706,6 +1195,9 @@ func restartContainer(cmd *cobra.Command, args []string) {
 	if err := ExtractContainerLocatorsFromDeployment(defaultTransport.Get(), deploymentPath, &args); err != nil {
 		Fail(1, err.Error())
 	}
+	if err := extractContainerLocatorsFromSelector(labelSelector, &args); err != nil {
+		Fail(1, err.Error())
+	}
 	if len(args) < 1 {
 		Fail(1, "Valid arguments: <id> ...")
 	}
@@ -727,10 +1219,74 @@ func restartContainer(cmd *cobra.Command, args []string) {
 	}.StreamAndExit()
 }
 
+func containerLogs(cmd *cobra.Command, args []string) {
+	if err := ExtractContainerLocatorsFromDeployment(defaultTransport.Get(), deploymentPath, &args); err != nil {
+		Fail(1, err.Error())
+	}
+	if len(args) < 1 {
+		Fail(1, "Valid arguments: <id> ...")
+	}
+	ids, err := NewContainerLocators(defaultTransport.Get(), args...)
+	if err != nil {
+		Fail(1, "You must pass one or more valid service names: %s", err.Error())
+	}
+	switch logOutput {
+	case "json":
+		logOptions.Json = true
+	case "text":
+	default:
+		Fail(1, "The output must be 'text' or 'json'")
+	}
+	logOptions.Follow = follow
+
+	Executor{
+		On: ids,
+		Serial: func(on Locator) jobs.Job {
+			return &cjobs.ContainerLogRequest{
+				Id:         AsIdentifier(on),
+				LogOptions: logOptions,
+				Timeout:    logTimeout,
+			}
+		},
+		Output:    os.Stdout,
+		LocalInit: needsData,
+		Transport: defaultTransport.Get(),
+	}.StreamAndExit()
+}
+
+func waitHealthy(cmd *cobra.Command, args []string) {
+	if err := ExtractContainerLocatorsFromDeployment(defaultTransport.Get(), deploymentPath, &args); err != nil {
+		Fail(1, err.Error())
+	}
+	if err := extractContainerLocatorsFromSelector(labelSelector, &args); err != nil {
+		Fail(1, err.Error())
+	}
+	if len(args) < 1 {
+		Fail(1, "Valid arguments: <id> ...")
+	}
+	ids, err := NewContainerLocators(defaultTransport.Get(), args...)
+	if err != nil {
+		Fail(1, "You must pass one or more valid service names: %s", err.Error())
+	}
+
+	Executor{
+		On: ids,
+		Serial: func(on Locator) jobs.Job {
+			return &cjobs.WaitForHealthRequest{Id: AsIdentifier(on), Timeout: healthWait}
+		},
+		Output:    os.Stdout,
+		LocalInit: needsSystemd,
+		Transport: defaultTransport.Get(),
+	}.StreamAndExit()
+}
+
 func containerStatus(cmd *cobra.Command, args []string) {
 	if err := ExtractContainerLocatorsFromDeployment(defaultTransport.Get(), deploymentPath, &args); err != nil {
 		Fail(1, err.Error())
 	}
+	if err := extractContainerLocatorsFromSelector(labelSelector, &args); err != nil {
+		Fail(1, err.Error())
+	}
 	if len(args) < 1 {
 		Fail(1, "Valid arguments: <id> ...")
 	}
@@ -776,11 +1332,15 @@ func listUnits(cmd *cobra.Command, args []string) {
 	if err != nil {
 		Fail(1, "You must pass zero or more valid host names (use '%s' or pass no arguments for the current server): %s", transport.Local.String(), err.Error())
 	}
+	selector, err := containers.NewLabelSelector(labelSelector)
+	if err != nil {
+		Fail(1, err.Error())
+	}
 
 	data, errors := Executor{
 		On: servers,
 		Group: func(on ...Locator) jobs.Job {
-			return &cjobs.ListContainersRequest{on[0].TransportLocator().String()}
+			return &cjobs.ListContainersRequest{Label: on[0].TransportLocator().String(), Selector: selector}
 		},
 		Output:    os.Stdout,
 		LocalInit: needsSystemd,
@@ -806,45 +1366,1163 @@ func listUnits(cmd *cobra.Command, args []string) {
 	os.Exit(0)
 }
 
-func createToken(cmd *cobra.Command, args []string) {
-	if len(args) != 2 {
-		Fail(1, "Valid arguments: <type> <content_id>")
+func createSlice(cmd *cobra.Command, args []string) {
+	if len(args) < 1 {
+		Fail(1, "Valid arguments: <name> ...")
+	}
+	ids, err := NewSliceLocators(defaultTransport.Get(), args...)
+	if err != nil {
+		Fail(1, "You must pass one or more valid slice names: %s", err.Error())
 	}
 
-	if keyPath == "" {
-		Fail(1, "You must specify --key-path to create a token")
+	Executor{
+		On: ids,
+		Serial: func(on Locator) jobs.Job {
+			slice := sliceLimit
+			slice.Id = AsSliceIdentifier(on)
+			if err := slice.Check(); err != nil {
+				Fail(1, err.Error())
+			}
+			return &cjobs.PutSliceRequest{SliceDescription: slice}
+		},
+		Output: os.Stdout,
+		OnSuccess: func(r *CliJobResponse, w io.Writer, job interface{}) {
+			fmt.Fprintf(w, "Slice %s updated\n", string(job.(*cjobs.PutSliceRequest).Id))
+		},
+		LocalInit: needsSystemdAndData,
+		Transport: defaultTransport.Get(),
+	}.StreamAndExit()
+}
+
+func deleteSlice(cmd *cobra.Command, args []string) {
+	if len(args) < 1 {
+		Fail(1, "Valid arguments: <name> ...")
 	}
-	config, err := encrypted.NewTokenConfiguration(filepath.Join(keyPath, "client"), filepath.Join(keyPath, "server.pub"))
+	ids, err := NewSliceLocators(defaultTransport.Get(), args...)
 	if err != nil {
-		Fail(1, "Unable to load token configuration: %s", err.Error())
+		Fail(1, "You must pass one or more valid slice names: %s", err.Error())
 	}
 
-	job := &cjobs.ContentRequest{Locator: args[1], Type: args[0]}
-	value, err := config.Sign(job, "key", expiresAt)
+	Executor{
+		On: ids,
+		Serial: func(on Locator) jobs.Job {
+			return &cjobs.DeleteSliceRequest{
+				Id:    AsSliceIdentifier(on),
+				Label: on.Identity(),
+			}
+		},
+		Output: os.Stdout,
+		OnSuccess: func(r *CliJobResponse, w io.Writer, job interface{}) {
+			fmt.Fprintf(w, "Deleted %s\n", string(job.(*cjobs.DeleteSliceRequest).Id))
+		},
+		LocalInit: needsSystemdAndData,
+		Transport: defaultTransport.Get(),
+	}.StreamAndExit()
+}
+
+func listSlices(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		args = []string{transport.Local.String()}
+	}
+	servers, err := NewHostLocators(defaultTransport.Get(), args[0:]...)
 	if err != nil {
-		Fail(1, "Unable to sign this request: %s", err.Error())
+		Fail(1, "You must pass zero or more valid host names (use '%s' or pass no arguments for the current server): %s", transport.Local.String(), err.Error())
+	}
+
+	data, errors := Executor{
+		On: servers,
+		Group: func(on ...Locator) jobs.Job {
+			return &cjobs.ListSlicesRequest{Label: on[0].TransportLocator().String()}
+		},
+		Output:    os.Stdout,
+		LocalInit: needsData,
+		Transport: defaultTransport.Get(),
+	}.Gather()
+
+	combined := cjobs.ListSlicesResponse{}
+	for i := range data {
+		if r, ok := data[i].(*cjobs.ListSlicesResponse); ok {
+			combined.Append(r)
+		}
+	}
+	combined.Sort()
+	combined.WriteTableTo(os.Stdout)
+	if len(errors) > 0 {
+		for i := range errors {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", errors[i])
+		}
+		os.Exit(1)
 	}
-	fmt.Printf("%s", value)
 	os.Exit(0)
 }
 
-func initGear(cmd *cobra.Command, args []string) {
-	if len(args) != 2 || !(pre || post) || (pre && post) {
-		Fail(1, "Valid arguments: <id> <image_name> (--pre|--post)")
+func setContainerSlice(cmd *cobra.Command, args []string) {
+	if err := ExtractContainerLocatorsFromDeployment(defaultTransport.Get(), deploymentPath, &args); err != nil {
+		Fail(1, err.Error())
 	}
-	containerId, err := containers.NewIdentifier(args[0])
+	if len(args) < 2 {
+		Fail(1, "Valid arguments: <slice> <id> ...")
+	}
+	slice, err := containers.NewSliceIdentifier(args[0])
 	if err != nil {
-		Fail(1, "Argument 1 must be a valid gear identifier: %s", err.Error())
+		Fail(1, "You must pass a valid slice name: %s", err.Error())
+	}
+	ids, err := NewContainerLocators(defaultTransport.Get(), args[1:]...)
+	if err != nil {
+		Fail(1, "You must pass one or more valid service names: %s", err.Error())
 	}
 
-	switch {
-	case pre:
-		if err := InitPreStart(conf.Docker.Socket, containerId, args[1]); err != nil {
-			Fail(2, "Unable to initialize container %s", err.Error())
-		}
-	case post:
-		if err := InitPostStart(conf.Docker.Socket, containerId); err != nil {
-			Fail(2, "Unable to initialize container %s", err.Error())
+	Executor{
+		On: ids,
+		Serial: func(on Locator) jobs.Job {
+			return &cjobs.ContainerSliceRequest{
+				RequestIdentifier: jobs.NewRequestIdentifier(),
+
+				Id:    AsIdentifier(on),
+				Slice: slice,
+			}
+		},
+		Output:    os.Stdout,
+		LocalInit: needsSystemdAndData,
+		Transport: defaultTransport.Get(),
+	}.StreamAndExit()
+}
+
+func createVolume(cmd *cobra.Command, args []string) {
+	if len(args) < 1 {
+		Fail(1, "Valid arguments: <name> ...")
+	}
+	ids, err := NewVolumeLocators(defaultTransport.Get(), args...)
+	if err != nil {
+		Fail(1, "You must pass one or more valid volume names: %s", err.Error())
+	}
+
+	Executor{
+		On: ids,
+		Serial: func(on Locator) jobs.Job {
+			return &cjobs.PutVolumeRequest{
+				Id:    AsVolumeIdentifier(on),
+				Label: on.Identity(),
+			}
+		},
+		Output: os.Stdout,
+		OnSuccess: func(r *CliJobResponse, w io.Writer, job interface{}) {
+			fmt.Fprintf(w, "Volume %s created\n", string(job.(*cjobs.PutVolumeRequest).Id))
+		},
+		LocalInit: needsData,
+		Transport: defaultTransport.Get(),
+	}.StreamAndExit()
+}
+
+func deleteVolume(cmd *cobra.Command, args []string) {
+	if len(args) < 1 {
+		Fail(1, "Valid arguments: <name> ...")
+	}
+	ids, err := NewVolumeLocators(defaultTransport.Get(), args...)
+	if err != nil {
+		Fail(1, "You must pass one or more valid volume names: %s", err.Error())
+	}
+
+	Executor{
+		On: ids,
+		Serial: func(on Locator) jobs.Job {
+			return &cjobs.DeleteVolumeRequest{
+				Id:    AsVolumeIdentifier(on),
+				Label: on.Identity(),
+			}
+		},
+		Output: os.Stdout,
+		OnSuccess: func(r *CliJobResponse, w io.Writer, job interface{}) {
+			fmt.Fprintf(w, "Deleted %s\n", string(job.(*cjobs.DeleteVolumeRequest).Id))
+		},
+		LocalInit: needsData,
+		Transport: defaultTransport.Get(),
+	}.StreamAndExit()
+}
+
+func listVolumes(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		args = []string{transport.Local.String()}
+	}
+	servers, err := NewHostLocators(defaultTransport.Get(), args[0:]...)
+	if err != nil {
+		Fail(1, "You must pass zero or more valid host names (use '%s' or pass no arguments for the current server): %s", transport.Local.String(), err.Error())
+	}
+
+	data, errors := Executor{
+		On: servers,
+		Group: func(on ...Locator) jobs.Job {
+			return &cjobs.ListVolumesRequest{Label: on[0].TransportLocator().String()}
+		},
+		Output:    os.Stdout,
+		LocalInit: needsData,
+		Transport: defaultTransport.Get(),
+	}.Gather()
+
+	combined := cjobs.ListVolumesResponse{}
+	for i := range data {
+		if r, ok := data[i].(*cjobs.ListVolumesResponse); ok {
+			combined.Append(r)
+		}
+	}
+	combined.Sort()
+	combined.WriteTableTo(os.Stdout)
+	if len(errors) > 0 {
+		for i := range errors {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", errors[i])
+		}
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func createProfile(cmd *cobra.Command, args []string) {
+	if len(args) < 1 {
+		Fail(1, "Valid arguments: <name> ...")
+	}
+	ids, err := NewProfileLocators(defaultTransport.Get(), args...)
+	if err != nil {
+		Fail(1, "You must pass one or more valid profile names: %s", err.Error())
+	}
+
+	Executor{
+		On: ids,
+		Serial: func(on Locator) jobs.Job {
+			p := profile
+			p.Id = AsProfileIdentifier(on)
+			p.CapDrop = append([]string{}, profileCapDrop...)
+			p.CapAdd = append([]string{}, profileCapAdd...)
+			if err := p.Check(); err != nil {
+				Fail(1, err.Error())
+			}
+			return &cjobs.PutProfileRequest{SecurityProfile: p}
+		},
+		Output: os.Stdout,
+		OnSuccess: func(r *CliJobResponse, w io.Writer, job interface{}) {
+			fmt.Fprintf(w, "Profile %s updated\n", string(job.(*cjobs.PutProfileRequest).Id))
+		},
+		LocalInit: needsData,
+		Transport: defaultTransport.Get(),
+	}.StreamAndExit()
+}
+
+func deleteProfile(cmd *cobra.Command, args []string) {
+	if len(args) < 1 {
+		Fail(1, "Valid arguments: <name> ...")
+	}
+	ids, err := NewProfileLocators(defaultTransport.Get(), args...)
+	if err != nil {
+		Fail(1, "You must pass one or more valid profile names: %s", err.Error())
+	}
+
+	Executor{
+		On: ids,
+		Serial: func(on Locator) jobs.Job {
+			return &cjobs.DeleteProfileRequest{
+				Id:    AsProfileIdentifier(on),
+				Label: on.Identity(),
+			}
+		},
+		Output: os.Stdout,
+		OnSuccess: func(r *CliJobResponse, w io.Writer, job interface{}) {
+			fmt.Fprintf(w, "Deleted %s\n", string(job.(*cjobs.DeleteProfileRequest).Id))
+		},
+		LocalInit: needsData,
+		Transport: defaultTransport.Get(),
+	}.StreamAndExit()
+}
+
+func listProfiles(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		args = []string{transport.Local.String()}
+	}
+	servers, err := NewHostLocators(defaultTransport.Get(), args[0:]...)
+	if err != nil {
+		Fail(1, "You must pass zero or more valid host names (use '%s' or pass no arguments for the current server): %s", transport.Local.String(), err.Error())
+	}
+
+	data, errors := Executor{
+		On: servers,
+		Group: func(on ...Locator) jobs.Job {
+			return &cjobs.ListProfilesRequest{Label: on[0].TransportLocator().String()}
+		},
+		Output:    os.Stdout,
+		LocalInit: needsData,
+		Transport: defaultTransport.Get(),
+	}.Gather()
+
+	combined := cjobs.ListProfilesResponse{}
+	for i := range data {
+		if r, ok := data[i].(*cjobs.ListProfilesResponse); ok {
+			combined.Append(r)
+		}
+	}
+	combined.Sort()
+	combined.WriteTableTo(os.Stdout)
+	if len(errors) > 0 {
+		for i := range errors {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", errors[i])
+		}
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// Replace the hosts in args with the containers on those hosts whose
+// labels match the selector.
+func extractContainerLocatorsFromSelector(value string, args *[]string) error {
+	if value == "" {
+		return nil
+	}
+	selector, err := containers.NewLabelSelector(value)
+	if err != nil {
+		return err
+	}
+	hosts := *args
+	if len(hosts) == 0 {
+		hosts = []string{transport.Local.String()}
+	}
+	servers, err := NewHostLocators(defaultTransport.Get(), hosts...)
+	if err != nil {
+		return errors.New(fmt.Sprintf("With a selector you must pass zero or more valid host names: %s", err.Error()))
+	}
+
+	data, failures := Executor{
+		On: servers,
+		Group: func(on ...Locator) jobs.Job {
+			return &cjobs.ListContainersRequest{Label: on[0].TransportLocator().String(), Selector: selector}
+		},
+		LocalInit: needsSystemd,
+		Transport: defaultTransport.Get(),
+	}.Gather()
+	if len(failures) > 0 {
+		return failures[0]
+	}
+
+	found := []string{}
+	for i := range data {
+		var list *cjobs.ListContainersResponse
+		if r, ok := data[i].(*http.ListContainersResponse); ok {
+			list = &r.ListContainersResponse
+		} else if j, ok := data[i].(*cjobs.ListContainersResponse); ok {
+			list = j
+		} else {
+			continue
+		}
+		for _, c := range list.Containers {
+			if c.Server == "" || c.Server == transport.Local.String() {
+				found = append(found, c.Id)
+			} else {
+				found = append(found, c.Server+"/"+c.Id)
+			}
+		}
+	}
+	if len(found) == 0 {
+		return errors.New(fmt.Sprintf("No containers match the selector '%s'", selector))
+	}
+	*args = found
+	return nil
+}
+
+func containerStats(cmd *cobra.Command, args []string) {
+	if statsInterval < 0 {
+		Fail(1, "The interval must be zero or a positive number of seconds")
+	}
+	if labelSelector != "" && statsAll {
+		Fail(1, "--all and --selector may not be used together")
+	}
+	if err := extractContainerLocatorsFromSelector(labelSelector, &args); err != nil {
+		Fail(1, err.Error())
+	}
+
+	executor := Executor{
+		Output:    os.Stdout,
+		LocalInit: needsSystemd,
+		Transport: defaultTransport.Get(),
+	}
+	if statsAll || len(args) == 0 {
+		if len(args) == 0 {
+			args = []string{transport.Local.String()}
+		}
+		servers, err := NewHostLocators(defaultTransport.Get(), args[0:]...)
+		if err != nil {
+			Fail(1, "You must pass zero or more valid host names (use '%s' or pass no arguments for the current server): %s", transport.Local.String(), err.Error())
+		}
+		executor.On = servers
+		executor.Group = func(on ...Locator) jobs.Job {
+			return &cjobs.ListContainerStatsRequest{Label: on[0].TransportLocator().String()}
+		}
+	} else {
+		ids, err := NewContainerLocators(defaultTransport.Get(), args...)
+		if err != nil {
+			Fail(1, "You must pass one or more valid service names: %s", err.Error())
+		}
+		executor.On = ids
+		executor.Serial = func(on Locator) jobs.Job {
+			return &cjobs.ContainerStatsRequest{Id: AsIdentifier(on), Label: on.TransportLocator().String()}
+		}
+	}
+
+	for {
+		data, errors := executor.Gather()
+
+		combined := cjobs.ContainerStatsResponse{}
+		for i := range data {
+			if r, ok := data[i].(*cjobs.ContainerStatsResponse); ok {
+				combined.Append(r)
+			}
+		}
+		combined.Sort()
+		combined.WriteTableTo(os.Stdout)
+		for i := range errors {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", errors[i])
+		}
+		if statsInterval == 0 {
+			if len(errors) > 0 {
+				os.Exit(1)
+			}
+			os.Exit(0)
+		}
+		time.Sleep(time.Duration(statsInterval) * time.Second)
+		fmt.Fprintln(os.Stdout)
+	}
+}
+
+func pullImage(cmd *cobra.Command, args []string) {
+	if len(args) < 1 {
+		Fail(1, "Valid arguments: <image> <host>...")
+	}
+	registry, repository, tag := docker.ParseImageName(args[0])
+	hosts := args[1:]
+	if len(hosts) == 0 {
+		hosts = []string{transport.Local.String()}
+	}
+	servers, err := NewHostLocators(defaultTransport.Get(), hosts...)
+	if err != nil {
+		Fail(1, "You must pass zero or more valid host names (use '%s' or pass no arguments for the current server): %s", transport.Local.String(), err.Error())
+	}
+
+	Executor{
+		On: servers,
+		Group: func(on ...Locator) jobs.Job {
+			job := &cjobs.PullImageRequest{
+				Registry:     registry,
+				Repository:   repository,
+				Tag:          tag,
+				Label:        on[0].TransportLocator().String(),
+				DockerSocket: conf.Docker.Socket,
+			}
+			if err := job.Check(); err != nil {
+				Fail(1, err.Error())
+			}
+			return job
+		},
+		Output:    os.Stdout,
+		Transport: defaultTransport.Get(),
+	}.StreamAndExit()
+}
+
+func copyImage(cmd *cobra.Command, args []string) {
+	if len(args) < 3 {
+		Fail(1, "Valid arguments: <image> <source_host> <host>...")
+	}
+	source, err := transport.NewHostLocator(args[1])
+	if err != nil {
+		Fail(1, "Argument 2 must be a valid host name: %s", err.Error())
+	}
+	sourceUrl, err := http.UrlForLocator(source)
+	if err != nil {
+		Fail(1, "Argument 2 must be a valid host name: %s", err.Error())
+	}
+	servers, err := NewHostLocators(defaultTransport.Get(), args[2:]...)
+	if err != nil {
+		Fail(1, "You must pass one or more valid host names: %s", err.Error())
+	}
+
+	token := imageToken
+	if token == "" {
+		if keyPath == "" {
+			Fail(1, "You must specify --key-path or --token to authorize the copy")
+		}
+		config, err := encrypted.NewTokenConfiguration(filepath.Join(keyPath, "client"), filepath.Join(keyPath, "server.pub"))
+		if err != nil {
+			Fail(1, "Unable to load token configuration: %s", err.Error())
+		}
+		token, err = config.Sign(&cjobs.ContentRequest{Locator: args[0], Type: cjobs.ContentTypeImage}, "key", expiresAt)
+		if err != nil {
+			Fail(1, "Unable to sign this request: %s", err.Error())
+		}
+	}
+
+	Executor{
+		On: servers,
+		Group: func(on ...Locator) jobs.Job {
+			job := &cjobs.ImportImageRequest{
+				Source:       sourceUrl.String(),
+				Token:        token,
+				Label:        on[0].TransportLocator().String(),
+				DockerSocket: conf.Docker.Socket,
+			}
+			if err := job.Check(); err != nil {
+				Fail(1, err.Error())
+			}
+			return job
+		},
+		Output:    os.Stdout,
+		Transport: defaultTransport.Get(),
+	}.StreamAndExit()
+}
+
+func createSchedule(cmd *cobra.Command, args []string) {
+	if len(args) < 2 {
+		Fail(1, "Valid arguments: <name> <image> [<arg>...]")
+	}
+	ids, err := NewScheduleLocators(defaultTransport.Get(), args[0])
+	if err != nil {
+		Fail(1, "You must pass one valid schedule name: %s", err.Error())
+	}
+	schedule.Image = args[1]
+	schedule.Arguments = args[2:]
+
+	Executor{
+		On: ids,
+		Serial: func(on Locator) jobs.Job {
+			s := schedule
+			s.Id = AsScheduleIdentifier(on)
+			if err := s.Check(); err != nil {
+				Fail(1, err.Error())
+			}
+			return &cjobs.PutScheduleRequest{ScheduleDescription: s}
+		},
+		Output: os.Stdout,
+		OnSuccess: func(r *CliJobResponse, w io.Writer, job interface{}) {
+			fmt.Fprintf(w, "Schedule %s updated\n", string(job.(*cjobs.PutScheduleRequest).Id))
+		},
+		LocalInit: needsSystemdAndData,
+		Transport: defaultTransport.Get(),
+	}.StreamAndExit()
+}
+
+func deleteSchedule(cmd *cobra.Command, args []string) {
+	if len(args) < 1 {
+		Fail(1, "Valid arguments: <name> ...")
+	}
+	ids, err := NewScheduleLocators(defaultTransport.Get(), args...)
+	if err != nil {
+		Fail(1, "You must pass one or more valid schedule names: %s", err.Error())
+	}
+
+	Executor{
+		On: ids,
+		Serial: func(on Locator) jobs.Job {
+			return &cjobs.DeleteScheduleRequest{
+				Id:    AsScheduleIdentifier(on),
+				Label: on.Identity(),
+			}
+		},
+		Output: os.Stdout,
+		OnSuccess: func(r *CliJobResponse, w io.Writer, job interface{}) {
+			fmt.Fprintf(w, "Deleted %s\n", string(job.(*cjobs.DeleteScheduleRequest).Id))
+		},
+		LocalInit: needsSystemdAndData,
+		Transport: defaultTransport.Get(),
+	}.StreamAndExit()
+}
+
+func runSchedule(cmd *cobra.Command, args []string) {
+	if len(args) < 1 {
+		Fail(1, "Valid arguments: <name> ...")
+	}
+	ids, err := NewScheduleLocators(defaultTransport.Get(), args...)
+	if err != nil {
+		Fail(1, "You must pass one or more valid schedule names: %s", err.Error())
+	}
+
+	Executor{
+		On: ids,
+		Serial: func(on Locator) jobs.Job {
+			return &cjobs.RunScheduleRequest{
+				Id:    AsScheduleIdentifier(on),
+				Label: on.Identity(),
+			}
+		},
+		Output:    os.Stdout,
+		LocalInit: needsSystemdAndData,
+		Transport: defaultTransport.Get(),
+	}.StreamAndExit()
+}
+
+func scheduleRuns(cmd *cobra.Command, args []string) {
+	if len(args) < 1 {
+		Fail(1, "Valid arguments: <name> ...")
+	}
+	ids, err := NewScheduleLocators(defaultTransport.Get(), args...)
+	if err != nil {
+		Fail(1, "You must pass one or more valid schedule names: %s", err.Error())
+	}
+
+	data, errors := Executor{
+		On: ids,
+		Serial: func(on Locator) jobs.Job {
+			return &cjobs.ScheduleRunsRequest{
+				Id:    AsScheduleIdentifier(on),
+				Label: on.TransportLocator().String(),
+			}
+		},
+		Output:    os.Stdout,
+		LocalInit: needsData,
+		Transport: defaultTransport.Get(),
+	}.Gather()
+
+	combined := cjobs.ScheduleRunsResponse{}
+	for i := range data {
+		if r, ok := data[i].(*cjobs.ScheduleRunsResponse); ok {
+			combined.Append(r)
+		}
+	}
+	combined.WriteTableTo(os.Stdout)
+	if len(errors) > 0 {
+		for i := range errors {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", errors[i])
+		}
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func listSchedules(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		args = []string{transport.Local.String()}
+	}
+	servers, err := NewHostLocators(defaultTransport.Get(), args[0:]...)
+	if err != nil {
+		Fail(1, "You must pass zero or more valid host names (use '%s' or pass no arguments for the current server): %s", transport.Local.String(), err.Error())
+	}
+
+	data, errors := Executor{
+		On: servers,
+		Group: func(on ...Locator) jobs.Job {
+			return &cjobs.ListSchedulesRequest{Label: on[0].TransportLocator().String()}
+		},
+		Output:    os.Stdout,
+		LocalInit: needsData,
+		Transport: defaultTransport.Get(),
+	}.Gather()
+
+	combined := cjobs.ListSchedulesResponse{}
+	for i := range data {
+		if r, ok := data[i].(*cjobs.ListSchedulesResponse); ok {
+			combined.Append(r)
+		}
+	}
+	combined.Sort()
+	combined.WriteTableTo(os.Stdout)
+	if len(errors) > 0 {
+		for i := range errors {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", errors[i])
+		}
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func containerVersions(cmd *cobra.Command, args []string) {
+	if err := ExtractContainerLocatorsFromDeployment(defaultTransport.Get(), deploymentPath, &args); err != nil {
+		Fail(1, err.Error())
+	}
+	if len(args) < 1 {
+		Fail(1, "Valid arguments: <id> ...")
+	}
+	ids, err := NewContainerLocators(defaultTransport.Get(), args...)
+	if err != nil {
+		Fail(1, "You must pass one or more valid service names: %s", err.Error())
+	}
+
+	data, errors := Executor{
+		On: ids,
+		Serial: func(on Locator) jobs.Job {
+			return &cjobs.ContainerVersionsRequest{
+				Id:    AsIdentifier(on),
+				Label: on.Identity(),
+			}
+		},
+		Output:    os.Stdout,
+		LocalInit: needsData,
+		Transport: defaultTransport.Get(),
+	}.Gather()
+
+	for i := range data {
+		if r, ok := data[i].(*cjobs.ContainerVersionsResponse); ok {
+			if i > 0 {
+				fmt.Fprintf(os.Stdout, "\n")
+			}
+			r.WriteTableTo(os.Stdout)
+		}
+	}
+	if len(errors) > 0 {
+		for i := range errors {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", errors[i])
+		}
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func rollbackContainer(cmd *cobra.Command, args []string) {
+	if err := ExtractContainerLocatorsFromDeployment(defaultTransport.Get(), deploymentPath, &args); err != nil {
+		Fail(1, err.Error())
+	}
+	if len(args) < 1 {
+		Fail(1, "Valid arguments: <id> ...")
+	}
+	ids, err := NewContainerLocators(defaultTransport.Get(), args...)
+	if err != nil {
+		Fail(1, "You must pass one or more valid service names: %s", err.Error())
+	}
+
+	Executor{
+		On: ids,
+		Serial: func(on Locator) jobs.Job {
+			return &cjobs.RollbackContainerRequest{
+				Id:      AsIdentifier(on),
+				Version: rollbackVersion,
+			}
+		},
+		Output:    os.Stdout,
+		LocalInit: needsSystemdAndData,
+		Transport: defaultTransport.Get(),
+	}.StreamAndExit()
+}
+
+func execContainer(cmd *cobra.Command, args []string) {
+	if len(args) < 2 {
+		Fail(1, "Valid arguments: <id> -- <command> [<arg>...]")
+	}
+	ids, err := NewContainerLocators(defaultTransport.Get(), args[0])
+	if err != nil {
+		Fail(1, "You must pass one valid service name: %s", err.Error())
+	}
+
+	job := &cjobs.ExecContainerRequest{
+		Id:          AsIdentifier(ids[0]),
+		Command:     args[1:],
+		Environment: []string(execEnvironment),
+		Interactive: execInteractive,
+		Tty:         execTty,
+	}
+	// attached commands bypass the line oriented output of the executor
+	var terminal *utils.TerminalState
+	if job.Attached() {
+		job.Stdin, job.Stdout, job.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if job.Tty && utils.IsTerminal(os.Stdin.Fd()) {
+			if terminal, err = utils.MakeRawTerminal(os.Stdin.Fd()); err != nil {
+				Fail(1, "Unable to configure the terminal: %s", err.Error())
+			}
+		}
+	}
+
+	code := cjobs.ExitCode(0)
+	errors := Executor{
+		On: ids,
+		Serial: func(on Locator) jobs.Job {
+			return job
+		},
+		Output: os.Stdout,
+		OnSuccess: func(r *CliJobResponse, w io.Writer, job interface{}) {
+			if c, ok := r.Pending[cjobs.PendingExitCodeName].(cjobs.ExitCode); ok {
+				code = c
+			}
+		},
+		LocalInit: needsSystemd,
+		Transport: defaultTransport.Get(),
+	}.Stream()
+
+	if terminal != nil {
+		utils.RestoreTerminal(os.Stdin.Fd(), terminal)
+	}
+	if len(errors) > 0 {
+		for i := range errors {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", errors[i])
+		}
+		os.Exit(1)
+	}
+	os.Exit(int(code))
+}
+
+func copyFiles(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		Fail(1, "Valid arguments: <name>:<path> <dir> | <path> <name>:<path>")
+	}
+
+	if name, path, ok := splitContainerPath(args[0]); ok {
+		ids, err := NewContainerLocators(defaultTransport.Get(), name)
+		if err != nil {
+			Fail(1, "You must pass one valid service name: %s", err.Error())
+		}
+		// the archive is extracted as it is read instead of buffered
+		archive, download := io.Pipe()
+		downloadErr := make(chan error, 1)
+		go func() {
+			err := Executor{
+				On: ids,
+				Serial: func(on Locator) jobs.Job {
+					return &cjobs.GetContainerFilesRequest{
+						Id:           AsIdentifier(on),
+						Path:         path,
+						DockerSocket: conf.Docker.Socket,
+					}
+				},
+				LocalInit: needsData,
+				Transport: defaultTransport.Get(),
+			}.StreamTo(download)
+			downloadErr <- err
+			download.CloseWithError(err)
+		}()
+		errx := containers.ExtractArchive(archive, args[1], "/", nil)
+		if errx == nil {
+			// read the padding after the end of the archive
+			_, errx = io.Copy(ioutil.Discard, archive)
+		}
+		if errx != nil {
+			select {
+			case err := <-downloadErr:
+				if err != nil {
+					Fail(1, "Error: %s", err.Error())
+				}
+			default:
+			}
+			Fail(1, "Unable to extract files to %s: %s", args[1], errx.Error())
+		}
+		if err := <-downloadErr; err != nil {
+			Fail(1, "Error: %s", err.Error())
+		}
+		os.Exit(0)
+	}
+
+	name, path, ok := splitContainerPath(args[1])
+	if !ok {
+		Fail(1, "One of the arguments must be a container path of the form <name>:<path>")
+	}
+	ids, err := NewContainerLocators(defaultTransport.Get(), name)
+	if err != nil {
+		Fail(1, "You must pass one valid service name: %s", err.Error())
+	}
+	source, err := filepath.EvalSymlinks(args[0])
+	if err != nil {
+		Fail(1, "Unable to read %s: %s", args[0], err.Error())
+	}
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(containers.WriteArchive(writer, source, filepath.Base(source)))
+	}()
+
+	Executor{
+		On: ids,
+		Serial: func(on Locator) jobs.Job {
+			return &cjobs.PutContainerFilesRequest{
+				Id:           AsIdentifier(on),
+				Path:         path,
+				Archive:      reader,
+				DockerSocket: conf.Docker.Socket,
+			}
+		},
+		Output:    os.Stdout,
+		LocalInit: needsData,
+		Transport: defaultTransport.Get(),
+	}.StreamAndExit()
+}
+
+func moveContainer(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		Fail(1, "Valid arguments: <host>/<name> <host>")
+	}
+	t := defaultTransport.Get()
+	ids, err := NewContainerLocators(t, args[0])
+	if err != nil {
+		Fail(1, "You must pass one valid service name: %s", err.Error())
+	}
+	destination, err := t.LocatorFor(args[1])
+	if err != nil {
+		Fail(1, "Argument 2 must be a valid host name: %s", err.Error())
+	}
+	source := ids[0].(*ResourceLocator)
+	if source.At.String() == destination.String() {
+		Fail(1, "The container %s is already on %s", source.Id, args[1])
+	}
+	targets := Locators{&ResourceLocator{Type: ResourceTypeContainer, Id: source.Id, At: destination}}
+
+	// stopping the container clears whether it is started on boot, so
+	// the state is read first to carry it to the destination
+	started, err := containerStartsOnBoot(t, ids)
+	if err != nil {
+		Fail(1, "Unable to read the state of the container, it has not been moved: %s", err.Error())
+	}
+
+	// a container that does not move is returned to its state before
+	// the move
+	exitOnErrors := func(errors []error, message string) {
+		if len(errors) == 0 {
+			return
+		}
+		for i := range errors {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", errors[i])
+		}
+		if started {
+			if failures := (Executor{
+				On: ids,
+				Serial: func(on Locator) jobs.Job {
+					return &cjobs.StartedContainerStateRequest{
+						Id: AsIdentifier(on),
+					}
+				},
+				Output:    os.Stdout,
+				LocalInit: needsSystemd,
+				Transport: t,
+			}.Stream()); len(failures) > 0 {
+				for i := range failures {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", failures[i])
+				}
+				Fail(1, "%s, and could not be started again on %s", message, source.At.String())
+			}
+			Fail(1, "%s, it was started again on %s", message, source.At.String())
+		}
+		Fail(1, "%s, it remains stopped on %s", message, source.At.String())
+	}
+
+	// stop the container so that its data does not change during the copy
+	exitOnErrors(Executor{
+		On: ids,
+		Serial: func(on Locator) jobs.Job {
+			return &cjobs.StoppedContainerStateRequest{
+				Id: AsIdentifier(on),
+			}
+		},
+		Output:    os.Stdout,
+		LocalInit: needsSystemd,
+		Transport: t,
+	}.Stream(), "Unable to stop the container")
+
+	// the export is streamed into the import as it is read, so that the
+	// data of the container is never held in memory
+	archive, exported := io.Pipe()
+	exportErr := make(chan error, 1)
+	go func() {
+		err := Executor{
+			On: ids,
+			Serial: func(on Locator) jobs.Job {
+				return &cjobs.ExportContainerRequest{
+					Id:           AsIdentifier(on),
+					Started:      started,
+					DockerSocket: conf.Docker.Socket,
+				}
+			},
+			LocalInit: needsData,
+			Transport: t,
+		}.StreamTo(exported)
+		exportErr <- err
+		exported.CloseWithError(err)
+	}()
+
+	importErrors := Executor{
+		On: targets,
+		Serial: func(on Locator) jobs.Job {
+			return &cjobs.ImportContainerRequest{
+				RequestIdentifier: jobs.NewRequestIdentifier(),
+				Id:                AsIdentifier(on),
+				Archive:           archive,
+				DockerSocket:      conf.Docker.Socket,
+			}
+		},
+		Output: os.Stdout,
+		OnSuccess: func(r *CliJobResponse, w io.Writer, job interface{}) {
+			if pairs, ok := job.(*cjobs.ImportContainerRequest).PortMappingsFrom(r.Pending); ok {
+				for i := range pairs {
+					fmt.Fprintf(w, "==> Links to port %d of %s should use %s:%d\n", pairs[i].Internal, source.Id, destination.String(), pairs[i].External)
+				}
+			}
+		},
+		LocalInit: needsSystemdAndData,
+		Transport: t,
+	}.Stream()
+	if len(importErrors) > 0 {
+		// a failed export also fails the import
+		select {
+		case err := <-exportErr:
+			if err != nil {
+				exitOnErrors([]error{err}, "Unable to export the container")
+			}
+		default:
+		}
+		exitOnErrors(importErrors, "Unable to import the container")
+	}
+	io.Copy(ioutil.Discard, archive)
+	if err := <-exportErr; err != nil {
+		exitOnErrors([]error{err}, "Unable to export the container")
+	}
+
+	Executor{
+		On: ids,
+		Serial: func(on Locator) jobs.Job {
+			return &cjobs.DeleteContainerRequest{
+				Id:    AsIdentifier(on),
+				Label: on.Identity(),
+			}
+		},
+		Output: os.Stdout,
+		OnSuccess: func(r *CliJobResponse, w io.Writer, job interface{}) {
+			fmt.Fprintf(w, "Moved %s to %s\n", source.Id, destination.String())
+		},
+		LocalInit: needsSystemdAndData,
+		Transport: t,
+	}.StreamAndExit()
+}
+
+// Whether the container at each locator is started on boot.
+func containerStartsOnBoot(t transport.Transport, on Locators) (bool, error) {
+	data, failures := Executor{
+		On: on,
+		Group: func(on ...Locator) jobs.Job {
+			return &cjobs.ListContainersRequest{Label: on[0].TransportLocator().String()}
+		},
+		LocalInit: needsSystemd,
+		Transport: t,
+	}.Gather()
+	if len(failures) > 0 {
+		return false, failures[0]
+	}
+	for i := range data {
+		var list *cjobs.ListContainersResponse
+		if r, ok := data[i].(*http.ListContainersResponse); ok {
+			list = &r.ListContainersResponse
+		} else if j, ok := data[i].(*cjobs.ListContainersResponse); ok {
+			list = j
+		} else {
+			continue
+		}
+		for _, c := range list.Containers {
+			for _, locator := range on {
+				if c.Id == string(AsIdentifier(locator)) && c.StartOnBoot {
+					return true, nil
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+// Split an argument of the form <name>:<absolute path>.
+func splitContainerPath(s string) (string, string, bool) {
+	i := strings.Index(s, ":/")
+	if i < 1 {
+		return "", "", false
+	}
+	return s[:i], s[i+1:], true
+}
+
+func createToken(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		Fail(1, "Valid arguments: <type> <content_id>")
+	}
+
+	if keyPath == "" {
+		Fail(1, "You must specify --key-path to create a token")
+	}
+	config, err := encrypted.NewTokenConfiguration(filepath.Join(keyPath, "client"), filepath.Join(keyPath, "server.pub"))
+	if err != nil {
+		Fail(1, "Unable to load token configuration: %s", err.Error())
+	}
+
+	job := &cjobs.ContentRequest{Locator: args[1], Type: args[0]}
+	value, err := config.Sign(job, "key", expiresAt)
+	if err != nil {
+		Fail(1, "Unable to sign this request: %s", err.Error())
+	}
+	fmt.Printf("%s", value)
+	os.Exit(0)
+}
+
+func initGear(cmd *cobra.Command, args []string) {
+	if len(args) != 2 || !(pre || post) || (pre && post) {
+		Fail(1, "Valid arguments: <id> <image_name> (--pre|--post)")
+	}
+	containerId, err := containers.NewIdentifier(args[0])
+	if err != nil {
+		Fail(1, "Argument 1 must be a valid gear identifier: %s", err.Error())
+	}
+
+	switch {
+	case pre:
+		if err := InitPreStart(conf.Docker.Socket, containerId, args[1]); err != nil {
+			Fail(2, "Unable to initialize container %s", err.Error())
+		}
+	case post:
+		if err := InitPostStart(conf.Docker.Socket, containerId); err != nil {
+			Fail(2, "Unable to initialize container %s", err.Error())
+		}
+	}
+}
+
+func runHook(cmd *cobra.Command, args []string) {
+	if len(args) < 2 {
+		Fail(1, "Valid arguments: <name> <stage> [-- <command> <arg>...]")
+	}
+	id, err := containers.NewIdentifier(args[0])
+	if err != nil {
+		Fail(1, "Argument 1 must be a valid gear identifier: %s", err.Error())
+	}
+
+	hook := &containers.LifecycleHook{
+		Stage:   containers.HookStage(args[1]),
+		Timeout: hookTimeout,
+	}
+	if hookURL != "" {
+		hook.Type, hook.Method, hook.Url = containers.HookHTTP, hookMethod, hookURL
+	} else {
+		hook.Type, hook.Command = containers.HookCommand, args[2:]
+	}
+	if err := hook.Check(); err != nil {
+		Fail(1, err.Error())
+	}
+
+	if err := RunHook(conf.Docker.Socket, id, hook); err != nil {
+		Fail(2, "The %s hook of %s failed: %s", hook.Stage, id, err.Error())
+	}
+}
+
+func runJob(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		Fail(1, "Valid arguments: <name>")
+	}
+
+	exitCode, err := RunJob(conf.Docker.Socket, containers.JobIdentifier(args[0]))
+	if err != nil {
+		log.Printf("Unable to record the run of %s: %s", args[0], err.Error())
+	}
+	os.Exit(exitCode)
+}
+
+func recordRun(cmd *cobra.Command, args []string) {
+	if len(args) != 1 || !(pre || post) || (pre && post) {
+		Fail(1, "Valid arguments: <name> (--pre|--post)")
+	}
+	id, err := containers.NewScheduleIdentifier(args[0])
+	if err != nil {
+		Fail(1, "Argument 1 must be a valid schedule name: %s", err.Error())
+	}
+
+	switch {
+	case pre:
+		cursor, err := systemd.CurrentCursor()
+		if err != nil {
+			log.Printf("Unable to read the journal cursor: %v", err)
+		}
+		if err := id.StartRun(cursor); err != nil {
+			Fail(2, "Unable to record the start of %s: %s", id, err.Error())
+		}
+	case post:
+		// systemd describes the outcome of the service to ExecStopPost
+		if err := id.FinishRun(os.Getenv("SERVICE_RESULT"), os.Getenv("EXIT_STATUS")); err != nil {
+			Fail(2, "Unable to record the end of %s: %s", id, err.Error())
 		}
 	}
 }
@@ -853,5 +2531,5 @@ func clean(cmd *cobra.Command, args []string) {
 	logInfo := log.New(os.Stdout, "INFO: ", log.Ldate|log.Ltime)
 	logError := log.New(os.Stderr, "ERROR: ", log.Ldate|log.Ltime)
 
-	cleanup.Clean(&cleanup.CleanerContext{DryRun: dryRun, Repair: repair, LogInfo: logInfo, LogError: logError})
+	cleanup.Clean(&cleanup.CleanerContext{DryRun: dryRun, Repair: repair, ImageRetention: imageRetention, LogInfo: logInfo, LogError: logError})
 }
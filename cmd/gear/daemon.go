@@ -2,8 +2,11 @@ package main
 
 import (
 	. "github.com/openshift/geard/cmd"
+	"github.com/openshift/geard/config"
 	"github.com/openshift/geard/containers"
+	cjobs "github.com/openshift/geard/containers/jobs"
 	"github.com/openshift/geard/encrypted"
+	"github.com/openshift/geard/health"
 	"github.com/openshift/geard/systemd"
 
 	"github.com/spf13/cobra"
@@ -30,11 +33,18 @@ func daemon(cmd *cobra.Command, args []string) {
 	if err := containers.InitializeData(); err != nil {
 		log.Fatal(err)
 	}
+	if profile := containers.ProfileIdentifier(config.DefaultSecurityProfile); profile != "" {
+		if _, err := containers.ReadSecurityProfile(profile); err != nil {
+			log.Fatalf("Unable to read the default security profile %s: %v", profile, err)
+		}
+	}
 	if err := Initialize(ForDaemon); err != nil {
 		log.Fatal(err)
 	}
 
 	conf.Dispatcher.Start()
+	health.NewMonitor(conf.Docker.Socket).Start()
+	cjobs.StartEnvironmentRefresh()
 
 	log.Printf("Listening (HTTP) on %s ...", listenAddr)
 	log.Fatal(nethttp.ListenAndServe(listenAddr, nil))
@@ -3,24 +3,28 @@
 package main
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	dc "github.com/fsouza/go-dockerclient"
 	"io"
 	"log"
 	"net"
+	"net/http"
 	"os"
 	"os/exec"
 	"os/user"
 	"path"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/openshift/geard/containers"
 	"github.com/openshift/geard/docker"
 	"github.com/openshift/geard/selinux"
 	"github.com/openshift/geard/ssh"
+	"github.com/openshift/geard/systemd"
 	"github.com/openshift/geard/utils"
 )
 
@@ -33,17 +37,36 @@ func InitPreStart(dockerSocket string, id containers.Identifier, imageName strin
 		d       *docker.DockerClient
 	)
 
-	_, socketActivationType, err := containers.GetSocketActivation(id)
+	if err := containers.WriteContainerEnvironment(id, hostFacts()); err != nil {
+		fmt.Printf("init_pre_start: Unable to resolve the environment: %v\n", err)
+		return err
+	}
+
+	socketActivated, socketActivationType, err := containers.GetSocketActivation(id)
+	if err != nil {
+		fmt.Printf("init_pre_start: Error while parsing unit file: %v\n", err)
+		return err
+	}
+	isolated, err := containers.IsIsolatedContainer(id)
 	if err != nil {
 		fmt.Printf("init_pre_start: Error while parsing unit file: %v\n", err)
 		return err
 	}
+	if !isolated && !socketActivated {
+		return nil
+	}
+
+	userns, err := containers.UserNamespaceFor(id)
+	if err != nil {
+		fmt.Printf("init_pre_start: Unable to read the user namespace: %v\n", err)
+		return err
+	}
 
 	if _, err = user.Lookup(id.LoginFor()); err != nil {
 		if _, ok := err.(user.UnknownUserError); !ok {
 			return err
 		}
-		if err = createUser(id); err != nil {
+		if err = createUser(id, userns); err != nil {
 			return err
 		}
 	}
@@ -60,6 +83,10 @@ func InitPreStart(dockerSocket string, id containers.Identifier, imageName strin
 		return err
 	}
 
+	if userns != nil {
+		return shiftVolumes(d, id, userns)
+	}
+
 	u, _ := user.Lookup(id.LoginFor())
 	volumes := make([]string, 0, 10)
 	for volPath := range imgInfo.Config.Volumes {
@@ -77,6 +104,11 @@ func InitPreStart(dockerSocket string, id containers.Identifier, imageName strin
 		return err
 	}
 
+	if err := chownVolumes(id, u); err != nil {
+		fmt.Printf("container init pre-start: Unable to set ownership of volumes: %v\n", err)
+		return err
+	}
+
 	containerData := containers.ContainerInitScript{
 		imgInfo.Config.User == "",
 		user,
@@ -122,8 +154,99 @@ func InitPreStart(dockerSocket string, id containers.Identifier, imageName strin
 	return nil
 }
 
-func createUser(id containers.Identifier) error {
-	cmd := exec.Command("/usr/sbin/useradd", id.LoginFor(), "-m", "-d", id.HomePath(), "-c", "Container user")
+// Facts about the host that container environments may reference.
+func hostFacts() map[string]string {
+	facts := make(map[string]string)
+	if name, err := os.Hostname(); err == nil {
+		facts["HOST_NAME"] = name
+	}
+	if ip, err := resolver.ResolveIP("localhost"); err == nil {
+		facts["HOST_IP"] = ip.String()
+	}
+	return facts
+}
+
+// Give the writable named volumes that no container owns yet to the user
+// of an isolated container.
+func chownVolumes(id containers.Identifier, u *user.User) error {
+	mounts, err := containers.GetContainerVolumes(id)
+	if err != nil || len(mounts) == 0 {
+		return err
+	}
+	uid, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		return err
+	}
+	gid, err := strconv.Atoi(u.Gid)
+	if err != nil {
+		return err
+	}
+	for i := range mounts {
+		if mounts[i].ReadOnly {
+			continue
+		}
+		owner, err := mounts[i].Name.Owner()
+		if err != nil {
+			return err
+		}
+		if owner != 0 {
+			if owner != uid {
+				fmt.Printf("container init pre-start: Volume %s is owned by another container, its ownership is unchanged\n", mounts[i].Name)
+			}
+			continue
+		}
+		if err := mounts[i].Name.Chown(uid, gid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Map the ownership of the data volumes and writable named volumes of a
+// container in a user namespace into its range, skipping named volumes
+// that belong to another container.
+func shiftVolumes(d *docker.DockerClient, id containers.Identifier, userns *containers.UserNamespace) error {
+	paths := []string{}
+	if data, err := d.GetContainer(id.ContainerFor()+"-data", false); err == nil {
+		for _, path := range data.Volumes {
+			paths = append(paths, path)
+		}
+	}
+	mounts, err := containers.GetContainerVolumes(id)
+	if err != nil {
+		return err
+	}
+	for i := range mounts {
+		if mounts[i].ReadOnly {
+			continue
+		}
+		owner, err := mounts[i].Name.Owner()
+		if err != nil {
+			return err
+		}
+		if owner != 0 && !userns.Contains(owner) {
+			fmt.Printf("container init pre-start: Volume %s is owned by another container, its ownership is unchanged\n", mounts[i].Name)
+			continue
+		}
+		paths = append(paths, mounts[i].Name.PathFor())
+	}
+	for _, path := range paths {
+		if err := userns.ShiftOwnership(path); err != nil {
+			fmt.Printf("container init pre-start: Unable to set ownership of %s: %v\n", path, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// The user of a container in a user namespace has the host id of the
+// root user in the container.
+func createUser(id containers.Identifier, userns *containers.UserNamespace) error {
+	args := []string{id.LoginFor(), "-m", "-d", id.HomePath(), "-c", "Container user"}
+	if userns != nil {
+		args = append(args, "-u", strconv.Itoa(userns.Start))
+	}
+	cmd := exec.Command("/usr/sbin/useradd", args...)
 	if out, err := cmd.CombinedOutput(); err != nil {
 		log.Println(out)
 		return err
@@ -134,10 +257,9 @@ func createUser(id containers.Identifier) error {
 
 func InitPostStart(dockerSocket string, id containers.Identifier) error {
 	var (
-		u         *user.User
-		container *dc.Container
-		err       error
-		d         *docker.DockerClient
+		u   *user.User
+		err error
+		d   *docker.DockerClient
 	)
 
 	if u, err = user.Lookup(id.LoginFor()); err == nil {
@@ -154,35 +276,219 @@ func InitPostStart(dockerSocket string, id containers.Identifier) error {
 
 	if file, err := os.Open(id.NetworkLinksPathFor()); err == nil {
 		defer file.Close()
+		if err := initNetworkLinks(d, id.ContainerFor(), file); err != nil {
+			return err
+		}
+	}
 
-		const ContainerInterval = time.Second / 3
-		const ContainerWait = time.Second * 12
-		for i := 0; i < int(ContainerWait/ContainerInterval); i++ {
-			if container, err = d.GetContainer(id.ContainerFor(), true); err != nil {
-				return err
+	return nil
+}
+
+// Wait for the named container to run and apply the network links to
+// its namespace.
+func initNetworkLinks(d *docker.DockerClient, name string, links io.Reader) error {
+	container, err := waitForContainer(d, name)
+	if err != nil {
+		return err
+	}
+
+	pid, err := d.ChildProcessForContainer(container)
+	if err != nil {
+		return err
+	}
+	if pid < 2 {
+		return errors.New("support: child PID is not correct")
+	}
+	log.Printf("Updating network namespaces for %d", pid)
+	return updateNamespaceNetworkLinks(pid, links)
+}
+
+// Wait a short time for the named container to run.
+func waitForContainer(d *docker.DockerClient, name string) (*dc.Container, error) {
+	var (
+		container *dc.Container
+		err       error
+	)
+
+	const ContainerInterval = time.Second / 3
+	const ContainerWait = time.Second * 12
+	for i := 0; i < int(ContainerWait/ContainerInterval); i++ {
+		if container, err = d.GetContainer(name, true); err != nil {
+			return nil, err
+		}
+		if container.State.Running {
+			break
+		} else {
+			log.Printf("Waiting for container to run.")
+			time.Sleep(ContainerInterval)
+		}
+	}
+	return container, nil
+}
+
+// Run a one-off container in the foreground and record its result.
+// Returns the exit code of the container.
+func RunJob(dockerSocket string, id containers.JobIdentifier) (int, error) {
+	run, err := containers.ReadContainerRun(id)
+	if err != nil {
+		return 1, err
+	}
+
+	cursor, err := systemd.CurrentCursor()
+	if err != nil {
+		log.Printf("run_job: Unable to read the journal cursor: %v", err)
+	}
+	result := &containers.ContainerRunResult{Result: containers.RunRunning, Started: time.Now(), Cursor: cursor}
+	if err := result.Write(id); err != nil {
+		return 1, err
+	}
+
+	if err := run.WriteEnvironment(); err != nil {
+		finished := time.Now()
+		result.Result, result.ExitCode, result.Finished = containers.RunFailed, 1, &finished
+		result.Write(id)
+		return 1, err
+	}
+	defer os.Remove(id.EnvironmentPathFor())
+
+	command := run.DockerCommand()
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		finished := time.Now()
+		result.Result, result.ExitCode, result.Finished = containers.RunFailed, 1, &finished
+		result.Write(id)
+		return 1, err
+	}
+
+	if len(run.NetworkLinks) > 0 {
+		go func() {
+			d, err := docker.GetConnection(dockerSocket)
+			if err == nil {
+				buf := &bytes.Buffer{}
+				run.NetworkLinks.WriteTo(buf)
+				err = initNetworkLinks(d, id.ContainerFor(), buf)
 			}
-			if container.State.Running {
-				break
-			} else {
-				log.Printf("Waiting for container to run.")
-				time.Sleep(ContainerInterval)
+			if err != nil {
+				log.Printf("run_job: Unable to set network links: %v", err)
 			}
+		}()
+	}
+
+	timedOut := make(chan bool, 1)
+	if run.Timeout > 0 {
+		timer := time.AfterFunc(time.Duration(run.Timeout)*time.Second, func() {
+			log.Printf("run_job: Stopping %s after %d seconds", id.ContainerFor(), run.Timeout)
+			timedOut <- true
+			if err := exec.Command("/usr/bin/docker", "stop", id.ContainerFor()).Run(); err != nil {
+				log.Printf("run_job: Unable to stop %s: %v", id.ContainerFor(), err)
+			}
+		})
+		defer timer.Stop()
+	}
+
+	exitCode := 0
+	if err := cmd.Wait(); err != nil {
+		exitCode = 1
+		if exit, ok := err.(*exec.ExitError); ok {
+			if status, ok := exit.Sys().(syscall.WaitStatus); ok {
+				exitCode = status.ExitStatus()
+			}
+		}
+	}
+
+	finished := time.Now()
+	result.ExitCode, result.Finished = exitCode, &finished
+	select {
+	case <-timedOut:
+		result.Result = containers.RunTimeout
+	default:
+		if exitCode == 0 {
+			result.Result = containers.RunSuccess
+		} else {
+			result.Result = containers.RunExitCode
 		}
+	}
+	if err := result.Write(id); err != nil {
+		return exitCode, err
+	}
+	return exitCode, nil
+}
+
+// Run a lifecycle hook of the container, stopping it once its timeout
+// elapses.  HTTP hooks succeed on any 2xx or 3xx response.
+func RunHook(dockerSocket string, id containers.Identifier, hook *containers.LifecycleHook) error {
+	timeout := hook.TimeoutDuration()
 
-		pid, err := d.ChildProcessForContainer(container)
+	if hook.Type == containers.HookHTTP {
+		req, err := http.NewRequest(hook.Method, hook.Url, nil)
 		if err != nil {
 			return err
 		}
-		if pid < 2 {
-			return errors.New("support: child PID is not correct")
+		client := &http.Client{Timeout: timeout}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+			return errors.New(fmt.Sprintf("%s %s returned %s", hook.Method, hook.Url, resp.Status))
+		}
+		return nil
+	}
+
+	var (
+		command []string
+		err     error
+	)
+	switch hook.Stage {
+	case containers.HookPostStart, containers.HookPreStop:
+		if hook.Stage == containers.HookPostStart {
+			d, err := docker.GetConnection(dockerSocket)
+			if err != nil {
+				return err
+			}
+			if _, err := waitForContainer(d, id.ContainerFor()); err != nil {
+				return err
+			}
 		}
-		log.Printf("Updating network namespaces for %d", pid)
-		if err := updateNamespaceNetworkLinks(pid, file); err != nil {
+		command = append([]string{"/usr/bin/switchns", "--container=" + id.ContainerFor(), "--"}, hook.Command...)
+	default:
+		if command, err = hook.DockerCommand(id); err != nil {
 			return err
 		}
+		// a hook container left behind by an interrupted hook
+		exec.Command("/usr/bin/docker", "rm", "-f", id.HookContainerFor()).Run()
 	}
 
-	return nil
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	timedOut := make(chan bool, 1)
+	timer := time.AfterFunc(timeout, func() {
+		log.Printf("hook: Stopping %s hook of %s after %d seconds", hook.Stage, id, hook.Timeout)
+		timedOut <- true
+		cmd.Process.Kill()
+		if hook.Stage == containers.HookPreStart || hook.Stage == containers.HookPostStop {
+			if err := exec.Command("/usr/bin/docker", "kill", id.HookContainerFor()).Run(); err != nil {
+				log.Printf("hook: Unable to stop %s: %v", id.HookContainerFor(), err)
+			}
+		}
+	})
+	defer timer.Stop()
+
+	err = cmd.Wait()
+	select {
+	case <-timedOut:
+		return errors.New(fmt.Sprintf("The %s hook did not complete within %d seconds", hook.Stage, hook.Timeout))
+	default:
+	}
+	return err
 }
 
 func getHostIPFromNamespace(name string) (*net.IPAddr, error) {
@@ -294,20 +600,13 @@ func updateNamespaceNetworkLinks(pid int, ports io.Reader) error {
 		return err
 	}
 
+	links, err := containers.ReadNetworkLinksFrom(ports)
+	if err != nil {
+		log.Printf("gear: Could not read from network links file: %v", err)
+	}
+
 	fmt.Fprintf(stdin, "*nat\n")
-	for {
-		link := containers.NetworkLink{}
-		if _, err := fmt.Fscanf(ports, "%s\t%v\t%v\t%s\n", &link.FromHost, &link.FromPort, &link.ToPort, &link.ToHost); err != nil {
-			if err == io.EOF {
-				break
-			}
-			log.Printf("gear: Could not read from network links file: %v", err)
-			continue
-		}
-		if err := link.Check(); err != nil {
-			log.Printf("gear: Link in file is not valid: %v", err)
-			continue
-		}
+	for _, link := range links {
 		if link.Complete() {
 			srcIP, err := net.ResolveIPAddr("ip", link.FromHost)
 			if err != nil {
@@ -57,6 +57,18 @@ type ResourceType string
 // A container resource
 const ResourceTypeContainer ResourceType = "ctr"
 
+// A slice resource
+const ResourceTypeSlice ResourceType = "slice"
+
+// A volume resource
+const ResourceTypeVolume ResourceType = "volume"
+
+// A scheduled job resource
+const ResourceTypeSchedule ResourceType = "schedule"
+
+// A security profile resource
+const ResourceTypeProfile ResourceType = "profile"
+
 type ResourceValidator interface {
 	Type() ResourceType
 }
@@ -90,6 +102,26 @@ func AsIdentifier(locator Locator) containers.Identifier {
 	return id
 }
 
+func AsSliceIdentifier(locator Locator) containers.SliceIdentifier {
+	id, _ := containers.NewSliceIdentifier(locator.(*ResourceLocator).Id)
+	return id
+}
+
+func AsVolumeIdentifier(locator Locator) containers.VolumeIdentifier {
+	id, _ := containers.NewVolumeIdentifier(locator.(*ResourceLocator).Id)
+	return id
+}
+
+func AsScheduleIdentifier(locator Locator) containers.ScheduleIdentifier {
+	id, _ := containers.NewScheduleIdentifier(locator.(*ResourceLocator).Id)
+	return id
+}
+
+func AsProfileIdentifier(locator Locator) containers.ProfileIdentifier {
+	id, _ := containers.NewProfileIdentifier(locator.(*ResourceLocator).Id)
+	return id
+}
+
 func NewResourceLocators(t transport.Transport, defaultType ResourceType, values ...string) (Locators, error) {
 	out := make(Locators, 0, len(values))
 	for i := range values {
@@ -130,6 +162,62 @@ func NewContainerLocators(t transport.Transport, values ...string) (Locators, er
 	return locators, nil
 }
 
+func NewSliceLocators(t transport.Transport, values ...string) (Locators, error) {
+	locators, err := NewResourceLocators(t, ResourceTypeSlice, values...)
+	if err != nil {
+		return Locators{}, err
+	}
+	for i := range locators {
+		_, err := containers.NewSliceIdentifier(locators[i].(*ResourceLocator).Id)
+		if err != nil {
+			return Locators{}, err
+		}
+	}
+	return locators, nil
+}
+
+func NewVolumeLocators(t transport.Transport, values ...string) (Locators, error) {
+	locators, err := NewResourceLocators(t, ResourceTypeVolume, values...)
+	if err != nil {
+		return Locators{}, err
+	}
+	for i := range locators {
+		_, err := containers.NewVolumeIdentifier(locators[i].(*ResourceLocator).Id)
+		if err != nil {
+			return Locators{}, err
+		}
+	}
+	return locators, nil
+}
+
+func NewScheduleLocators(t transport.Transport, values ...string) (Locators, error) {
+	locators, err := NewResourceLocators(t, ResourceTypeSchedule, values...)
+	if err != nil {
+		return Locators{}, err
+	}
+	for i := range locators {
+		_, err := containers.NewScheduleIdentifier(locators[i].(*ResourceLocator).Id)
+		if err != nil {
+			return Locators{}, err
+		}
+	}
+	return locators, nil
+}
+
+func NewProfileLocators(t transport.Transport, values ...string) (Locators, error) {
+	locators, err := NewResourceLocators(t, ResourceTypeProfile, values...)
+	if err != nil {
+		return Locators{}, err
+	}
+	for i := range locators {
+		_, err := containers.NewProfileIdentifier(locators[i].(*ResourceLocator).Id)
+		if err != nil {
+			return Locators{}, err
+		}
+	}
+	return locators, nil
+}
+
 // Given a command line string representing a resource, break it into type, host identity, and suffix
 func SplitTypeHostSuffix(value string) (res ResourceType, host string, suffix string, err error) {
 	if value == "" {
@@ -20,6 +20,13 @@ type DockerConfiguration struct {
 type DockerFeatures struct {
 	EnvironmentFile bool
 	ForegroundRun   bool
+	UserNamespaces  bool
 }
 
 var SystemDockerFeatures = DockerFeatures{}
+
+// The security profile of containers installed without one
+var DefaultSecurityProfile = ""
+
+// File environment sources must be inside this directory
+var EnvironmentSourcePath = "/etc/geard/env"
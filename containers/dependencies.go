@@ -0,0 +1,78 @@
+package containers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Another container on the same host that must be started before this
+// one.  A required dependency also stops this container when it stops
+// or fails to start.
+type ContainerDependency struct {
+	Id       Identifier
+	Required bool `json:"Required,omitempty"`
+}
+
+type ContainerDependencies []ContainerDependency
+
+// Parse a dependency of the form <name>[:required]
+func NewContainerDependencyFromString(s string) (*ContainerDependency, error) {
+	value := strings.Split(s, ":")
+	if len(value) > 2 {
+		return nil, errors.New(fmt.Sprintf("The dependency '%s' must be of the form <name>[:required]", s))
+	}
+	dep := &ContainerDependency{Id: Identifier(value[0])}
+	if len(value) == 2 {
+		switch value[1] {
+		case "required":
+			dep.Required = true
+		case "wanted":
+		default:
+			return nil, errors.New(fmt.Sprintf("The dependency mode '%s' must be 'required' or 'wanted'", value[1]))
+		}
+	}
+	if err := dep.Check(); err != nil {
+		return nil, err
+	}
+	return dep, nil
+}
+
+func (d *ContainerDependency) Check() error {
+	if _, err := NewIdentifier(string(d.Id)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *ContainerDependency) String() string {
+	if d.Required {
+		return string(d.Id) + ":required"
+	}
+	return string(d.Id)
+}
+
+func (d ContainerDependencies) Check() error {
+	ids := make(map[Identifier]bool)
+	for i := range d {
+		if err := d[i].Check(); err != nil {
+			return err
+		}
+		if ids[d[i].Id] {
+			return errors.New(fmt.Sprintf("The dependency on %s may only be listed once", d[i].Id))
+		}
+		ids[d[i].Id] = true
+	}
+	return nil
+}
+
+// Add a dependency, a required dependency replaces a wanted one.
+func (d *ContainerDependencies) Add(id Identifier, required bool) {
+	for i := range *d {
+		if (*d)[i].Id == id {
+			(*d)[i].Required = (*d)[i].Required || required
+			return
+		}
+	}
+	*d = append(*d, ContainerDependency{id, required})
+}
@@ -0,0 +1,27 @@
+package containers
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDependenciesInUnitFile(t *testing.T) {
+	unit := ContainerUnit{
+		Id:           Identifier("web"),
+		Image:        "busybox",
+		Dependencies: ContainerDependencies{{Id: "db", Required: true}, {Id: "cache"}},
+	}
+	buf := &bytes.Buffer{}
+	if err := ContainerUnitTemplate.ExecuteTemplate(buf, "SIMPLE", unit); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"After=ctr-db.service\nRequires=ctr-db.service\n", "After=ctr-cache.service\nWants=ctr-cache.service\n"} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("Expected %q in unit: %s", line, buf.String())
+		}
+	}
+	if _, err := NewContainerDependencyFromString("db:needed"); err == nil {
+		t.Errorf("Expected an unknown dependency mode to be invalid")
+	}
+}
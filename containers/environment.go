@@ -2,13 +2,15 @@ package containers
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
+	"github.com/openshift/geard/utils"
 	"io"
 	"log"
-	"net/http"
-	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 )
@@ -16,6 +18,9 @@ import (
 type Environment struct {
 	Name  string
 	Value string
+	// Encrypted at rest and only readable with a content token of type
+	// secrets
+	Secret bool `json:"Secret,omitempty"`
 }
 
 func (e *Environment) Check() error {
@@ -76,8 +81,16 @@ func ExtractEnvironmentVariablesFrom(existing *[]string) (EnvironmentVariables,
 
 type EnvironmentDescription struct {
 	Variables []Environment
-	Source    string
-	Id        Identifier // Used on creation only
+	// An http, https, file or extension URL the variables are loaded
+	// from
+	Source        string
+	SourceOptions *EnvironmentSourceOptions `json:"SourceOptions,omitempty"`
+	Id            Identifier                // Used on creation only
+
+	// The digest of the fetched source
+	digest string
+	// The names of the fetched variables that were not set directly
+	fetched []string
 }
 
 func (d *EnvironmentDescription) Empty() bool {
@@ -98,47 +111,157 @@ func (d *EnvironmentDescription) Check() error {
 		}
 	}
 	if d.Source != "" {
-		_, erru := url.Parse(d.Source)
-		if erru != nil {
-			return erru
+		if err := checkEnvironmentSource(d.Source, d.SourceOptions); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
+// Load the variables of the source, which are overridden by the
+// variables and secrets set directly on the description.
 // TODO: Return JobErrors that callers can react to
 func (j *EnvironmentDescription) Fetch(upto int64) error {
 	if j.Source == "" {
 		return nil
 	}
 
-	var client http.Client
-	resp, err := client.Get(j.Source)
+	data, err := readEnvironmentSource(j.Source, j.SourceOptions, upto)
 	if err != nil {
-		log.Print("job_environment: Unable to load the environment file from", j.Source, ":", err)
+		log.Print("job_environment: Unable to load the environment file from ", j.Source, ": ", err)
+		return err
+	}
+	explicit := EnvironmentVariables(j.Variables)
+	if err := j.ReadFrom(bytes.NewReader(data)); err != nil {
 		return err
 	}
-	defer resp.Body.Close()
+	fetched := overrideEnvironment(j.Variables, explicit)
+	j.fetched = fetched.names()
+	j.Variables = append(fetched, explicit...)
+	j.digest = contentDigest(data)
+	return nil
+}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		log.Printf("job_environment: fetch status code is %d from %s", resp.StatusCode, j.Source)
-		return errors.New("Unable to retrieve environment file from remote server.")
+func (e EnvironmentVariables) names() []string {
+	names := make([]string, 0, len(e))
+	for i := range e {
+		names = append(names, e[i].Name)
 	}
+	return names
+}
+
+// The variables of base whose names are not set in overrides.
+func overrideEnvironment(base, overrides EnvironmentVariables) EnvironmentVariables {
+	set := make(map[string]bool)
+	for i := range overrides {
+		set[overrides[i].Name] = true
+	}
+	kept := make(EnvironmentVariables, 0, len(base))
+	for i := range base {
+		if !set[base[i].Name] {
+			kept = append(kept, base[i])
+		}
+	}
+	return kept
+}
+
+// The SHA-256 digest of the content last fetched from the source.
+func (j *EnvironmentDescription) SourceDigest() string {
+	return j.digest
+}
+
+// Lock the environment against other writers until the returned file is
+// closed, or return utils.ErrLockTaken if another writer holds it.  The
+// lock is taken on a file beside the environment, since a rollback
+// replaces the environment file.
+func (i Identifier) LockEnvironment() (*os.File, error) {
+	file, _, err := utils.OpenFileExclusive(i.EnvironmentPathFor()+".lock", 0660)
+	return file, err
+}
 
-	var r io.Reader = resp.Body
-	if upto > 0 {
-		r = &io.LimitedReader{r, upto}
+// Write the provided enviroment data to an appropriate location and
+// record the result as a new version
+func (j *EnvironmentDescription) Write(appends bool) error {
+	lock, err := j.Id.LockEnvironment()
+	if err != nil {
+		return err
 	}
-	if err := j.ReadFrom(r); err != nil {
+	defer lock.Close()
+
+	if err := j.writeVariables(j.Id.EnvironmentPathFor(), appends); err != nil {
 		return err
 	}
+	if errs := j.writeSecrets(appends); errs != nil {
+		log.Print("job_environment: Unable to write secret environment file: ", errs)
+		return errs
+	}
+	if !appends {
+		if errs := j.writeSource(); errs != nil {
+			log.Print("job_environment: Unable to record the source of the environment: ", errs)
+			return errs
+		}
+	}
+	if _, errv := saveEnvironmentVersion(j.Id); errv != nil {
+		log.Print("job_environment: Unable to record a version of the environment: ", errv)
+		return errv
+	}
 	return nil
 }
 
-// Write the provided enviroment data to an appropriate location
-func (j *EnvironmentDescription) Write(appends bool) error {
-	envPath := j.Id.EnvironmentPathFor()
+// Replace the variables previously loaded from the source with the ones
+// just fetched, keeping the variables that were set directly and the
+// secrets of the environment, and record the result as a new version.
+func (j *EnvironmentDescription) WriteRefreshed(previous []string) error {
+	lock, err := j.Id.LockEnvironment()
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
 
+	if err := j.refreshVariables(j.Id.EnvironmentPathFor(), previous); err != nil {
+		return err
+	}
+	if errs := j.writeSource(); errs != nil {
+		log.Print("job_environment: Unable to record the source of the environment: ", errs)
+		return errs
+	}
+	if _, errv := saveEnvironmentVersion(j.Id); errv != nil {
+		log.Print("job_environment: Unable to record a version of the environment: ", errv)
+		return errv
+	}
+	return nil
+}
+
+func (j *EnvironmentDescription) refreshVariables(envPath string, previous []string) error {
+	// sources recorded without the fetched names replace every fetched name
+	if previous == nil {
+		previous = j.fetched
+	}
+	replaced := make(EnvironmentVariables, 0, len(previous))
+	for i := range previous {
+		replaced = append(replaced, Environment{Name: previous[i]})
+	}
+
+	current := &EnvironmentDescription{}
+	file, err := os.Open(envPath)
+	if err == nil {
+		err = current.ReadFrom(file)
+		file.Close()
+	}
+	if err != nil && !os.IsNotExist(err) {
+		log.Print("job_environment: Unable to read environment file: ", err)
+		return err
+	}
+
+	kept := overrideEnvironment(current.Variables, replaced)
+	fetched := overrideEnvironment(j.Variables, kept)
+	j.fetched = fetched.names()
+	j.Variables = append(fetched, kept...)
+	return j.writeVariables(envPath, false)
+}
+
+// Write the variables that are not secret to the environment file.
+func (j *EnvironmentDescription) writeVariables(envPath string, appends bool) error {
 	var file *os.File
 	var err error
 
@@ -158,14 +281,17 @@ func (j *EnvironmentDescription) Write(appends bool) error {
 
 	env := j.Variables
 	for i := range env {
+		if env[i].Secret {
+			continue
+		}
 		if _, errw := fmt.Fprintf(file, "%s=%s\n", env[i].Name, strconv.Quote(env[i].Value)); errw != nil {
-			log.Print("job_environment: Unable to write to environment file: ", err)
-			return err
+			log.Print("job_environment: Unable to write to environment file: ", errw)
+			return errw
 		}
 	}
 	if errc := file.Close(); errc != nil {
 		log.Print("job_environment: Unable to close environment file: ", errc)
-		return err
+		return errc
 	}
 	return nil
 }
@@ -193,7 +319,7 @@ func (j *EnvironmentDescription) ReadFrom(r io.Reader) error {
 
 	env := make(EnvironmentVariables, 0, len(all))
 	for name := range all {
-		e := Environment{name, all[name]}
+		e := Environment{Name: name, Value: all[name]}
 		env = append(env, e)
 	}
 	j.Variables = env
@@ -210,3 +336,146 @@ func (j *EnvironmentDescription) Map() map[string]string {
 
 	return env
 }
+
+var variableReference = regexp.MustCompile("\\$\\$|\\$\\{([a-zA-Z_][a-zA-Z0-9_]*)\\}")
+
+// Resolve references of the form ${NAME} in the values of the variables
+// to other variables, or to the provided facts about the host and the
+// links of a container.  Unknown names and references that form a cycle
+// resolve to an empty value, and $$ is a literal $.  Secret values are
+// never interpolated but may be referenced.
+func (e EnvironmentVariables) Interpolate(facts map[string]string) EnvironmentVariables {
+	in := &interpolation{
+		variables: make(map[string]*Environment),
+		facts:     facts,
+		resolved:  make(map[string]string),
+		resolving: make(map[string]bool),
+	}
+	for i := range e {
+		in.variables[e[i].Name] = &e[i]
+	}
+
+	resolved := make(EnvironmentVariables, len(e))
+	for i := range e {
+		resolved[i] = e[i]
+		resolved[i].Value = in.lookup(e[i].Name)
+	}
+	return resolved
+}
+
+type interpolation struct {
+	variables map[string]*Environment
+	facts     map[string]string
+	resolved  map[string]string
+	resolving map[string]bool
+}
+
+func (in *interpolation) lookup(name string) string {
+	if value, ok := in.resolved[name]; ok {
+		return value
+	}
+	v, ok := in.variables[name]
+	if !ok {
+		if value, ok := in.facts[name]; ok {
+			return value
+		}
+		log.Printf("job_environment: The variable %s is not defined", name)
+		return ""
+	}
+	if v.Secret {
+		return v.Value
+	}
+	if in.resolving[name] {
+		log.Printf("job_environment: The variable %s refers to itself", name)
+		return ""
+	}
+	in.resolving[name] = true
+	value := variableReference.ReplaceAllStringFunc(v.Value, func(ref string) string {
+		if ref == "$$" {
+			return "$"
+		}
+		return in.lookup(ref[2 : len(ref)-1])
+	})
+	delete(in.resolving, name)
+	in.resolved[name] = value
+	return value
+}
+
+// Resolve the environment of the container into the file passed to
+// docker.  The stored variables and secrets are interpolated with the
+// links of the container and the provided facts about the host, and each
+// named link defines <NAME>_HOST and <NAME>_PORT unless the environment
+// does.  A file with secrets must be on a tmpfs so that their values are
+// never written to disk in plain text.
+func WriteContainerEnvironment(id Identifier, facts map[string]string) error {
+	path := id.EnvironmentRunPathFor()
+	env := &EnvironmentDescription{}
+	hasSecrets := false
+	environmentPath, err := readEnvironmentPath(id)
+	if err != nil {
+		return err
+	}
+	if environmentPath != "" {
+		file, err := os.Open(environmentPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		if err := env.ReadFrom(file); err != nil {
+			return err
+		}
+		secrets, err := ReadSecrets(Identifier(filepath.Base(environmentPath)))
+		if err != nil {
+			return err
+		}
+		env.Variables = append(env.Variables, secrets...)
+		hasSecrets = len(secrets) > 0
+	}
+
+	links := NetworkLinks{}
+	if file, err := os.Open(id.NetworkLinksPathFor()); err == nil {
+		defer file.Close()
+		if links, err = ReadNetworkLinksFrom(file); err != nil {
+			return err
+		}
+	}
+	if hasSecrets {
+		if err := checkTmpfs(filepath.Dir(path)); err != nil {
+			return err
+		}
+	}
+
+	values := map[string]string{"CONTAINER_ID": string(id)}
+	for name, value := range facts {
+		values[name] = value
+	}
+	for name, value := range links.Variables() {
+		values[name] = value
+	}
+	defined := env.Map()
+	for i := range links {
+		if links[i].Name == "" {
+			continue
+		}
+		for _, suffix := range []string{"_HOST", "_PORT"} {
+			name := links[i].VariablePrefix() + suffix
+			if _, found := defined[name]; !found {
+				defined[name] = ""
+				env.Variables = append(env.Variables, Environment{Name: name, Value: "${LINK_" + name + "}"})
+			}
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	resolved := EnvironmentVariables(env.Variables).Interpolate(values)
+	for i := range resolved {
+		if _, err := fmt.Fprintf(file, "%s=%s\n", resolved[i].Name, strconv.Quote(resolved[i].Value)); err != nil {
+			return err
+		}
+	}
+	return file.Close()
+}
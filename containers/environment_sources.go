@@ -0,0 +1,312 @@
+package containers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/openshift/geard/config"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const environmentSourceTimeout = 30 * time.Second
+
+var allowedChecksum = regexp.MustCompile("\\A[a-fA-F0-9]{64}\\z")
+
+// How the Source of an environment is fetched and verified.
+type EnvironmentSourceOptions struct {
+	// The path on the host of a PEM bundle of the CAs trusted for an
+	// https source, defaults to the system roots
+	CAPath string `json:"CAPath,omitempty"`
+	// Credentials sent to an https source, either a bearer token or a
+	// user name and password.  They are encrypted when stored for
+	// refresh.
+	BearerToken string `json:"BearerToken,omitempty"`
+	Username    string `json:"Username,omitempty"`
+	Password    string `json:"Password,omitempty"`
+	// The hex SHA-256 digest the content must match
+	Checksum string `json:"Checksum,omitempty"`
+	// Seconds between fetches of the source by the daemon, 0 fetches it
+	// only when the environment is written
+	RefreshInterval int `json:"RefreshInterval,omitempty"`
+	// Restart the running containers that use the environment when a
+	// refresh changes it
+	RestartOnChange bool `json:"RestartOnChange,omitempty"`
+}
+
+func (o *EnvironmentSourceOptions) Check(u *url.URL) error {
+	if o.CAPath != "" && !filepath.IsAbs(o.CAPath) {
+		return errors.New("The CA bundle must be an absolute path on the host")
+	}
+	if o.BearerToken != "" && (o.Username != "" || o.Password != "") {
+		return errors.New("An environment source may use a bearer token or a user name and password, but not both")
+	}
+	if (o.CAPath != "" || o.BearerToken != "" || o.Username != "" || o.Password != "") && u.Scheme != "https" {
+		return errors.New("A CA bundle and credentials may only be used with an https environment source")
+	}
+	if o.Checksum != "" && !allowedChecksum.MatchString(o.Checksum) {
+		return errors.New("The checksum must be a hex encoded SHA-256 digest")
+	}
+	if o.RefreshInterval < 0 {
+		return errors.New("The refresh interval may not be negative")
+	}
+	return nil
+}
+
+// Opens the content of an environment source with a scheme other than
+// http, https or file.
+type EnvironmentSourceFetcher func(u *url.URL) (io.ReadCloser, error)
+
+var environmentSourceFetchers = make(map[string]EnvironmentSourceFetcher)
+
+// Allow an extension to load environments from sources of a scheme.
+func AddEnvironmentSourceFetcher(scheme string, fetcher EnvironmentSourceFetcher) {
+	environmentSourceFetchers[scheme] = fetcher
+}
+
+func checkEnvironmentSource(source string, options *EnvironmentSourceOptions) error {
+	u, err := url.Parse(source)
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "http", "https":
+		if u.Host == "" {
+			return errors.New("An http environment source must specify a host")
+		}
+	case "file":
+		if u.Host != "" || !filepath.IsAbs(u.Path) {
+			return errors.New("A file environment source must be of the form file:///<path>")
+		}
+	default:
+		if _, ok := environmentSourceFetchers[u.Scheme]; !ok {
+			return errors.New(fmt.Sprintf("Unrecognized environment source scheme '%s'", u.Scheme))
+		}
+	}
+	if options != nil {
+		return options.Check(u)
+	}
+	return nil
+}
+
+// Open the content of an environment source.
+func openEnvironmentSource(source string, options *EnvironmentSourceOptions) (io.ReadCloser, error) {
+	if options == nil {
+		options = &EnvironmentSourceOptions{}
+	}
+	u, err := url.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return openHttpEnvironmentSource(source, options)
+	case "file":
+		return openFileEnvironmentSource(u.Path)
+	}
+	if fetcher, ok := environmentSourceFetchers[u.Scheme]; ok {
+		return fetcher(u)
+	}
+	return nil, errors.New(fmt.Sprintf("Unrecognized environment source scheme '%s'", u.Scheme))
+}
+
+func openHttpEnvironmentSource(source string, options *EnvironmentSourceOptions) (io.ReadCloser, error) {
+	transport := &http.Transport{Proxy: http.ProxyFromEnvironment}
+	if options.CAPath != "" {
+		pem, err := ioutil.ReadFile(options.CAPath)
+		if err != nil {
+			return nil, err
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, errors.New("No certificates could be read from " + options.CAPath)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: roots}
+	}
+	client := &http.Client{Transport: transport, Timeout: environmentSourceTimeout}
+
+	req, err := http.NewRequest("GET", source, nil)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case options.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+options.BearerToken)
+	case options.Username != "":
+		req.SetBasicAuth(options.Username, options.Password)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, errors.New(fmt.Sprintf("Unable to retrieve environment file from remote server, status code %d", resp.StatusCode))
+	}
+	return resp.Body, nil
+}
+
+// File sources are limited to the environment source directory so that
+// the API cannot be used to read arbitrary files on the host.
+func openFileEnvironmentSource(path string) (io.ReadCloser, error) {
+	base, err := filepath.EvalSymlinks(config.EnvironmentSourcePath)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(resolved, base+string(filepath.Separator)) {
+		return nil, errors.New(fmt.Sprintf("A file environment source must be inside %s", config.EnvironmentSourcePath))
+	}
+	return os.Open(resolved)
+}
+
+// Read at most upto bytes of an environment source, verifying the
+// checksum of the content when one is set.
+func readEnvironmentSource(source string, options *EnvironmentSourceOptions, upto int64) ([]byte, error) {
+	r, err := openEnvironmentSource(source, options)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var reader io.Reader = r
+	if upto > 0 {
+		reader = &io.LimitedReader{R: r, N: upto + 1}
+	}
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if upto > 0 && int64(len(data)) > upto {
+		return nil, errors.New(fmt.Sprintf("The environment source is larger than %d bytes", upto))
+	}
+	if options != nil && options.Checksum != "" && !strings.EqualFold(contentDigest(data), options.Checksum) {
+		return nil, errors.New("The content of the environment source does not match the checksum")
+	}
+	return data, nil
+}
+
+func contentDigest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// The source of an environment the daemon fetches periodically.
+type EnvironmentSourceRecord struct {
+	Id      Identifier `json:"-"`
+	Source  string
+	Options EnvironmentSourceOptions
+	// The SHA-256 digest of the content last written to the environment
+	Digest string
+	// The names of the variables loaded from the source
+	Names []string `json:"Names,omitempty"`
+}
+
+// Store the source with its credentials encrypted by the key of the
+// host, or remove the record of an environment that is not refreshed.
+func (j *EnvironmentDescription) writeSource() error {
+	path := j.Id.EnvironmentSourcePathFor()
+	if j.Source == "" || j.SourceOptions == nil || j.SourceOptions.RefreshInterval == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	record := EnvironmentSourceRecord{Source: j.Source, Options: *j.SourceOptions, Digest: j.digest, Names: j.fetched}
+	if record.Options.BearerToken != "" || record.Options.Password != "" {
+		key, err := LoadSecretKey()
+		if err != nil {
+			return err
+		}
+		if record.Options.BearerToken, err = encryptOptional(key, record.Options.BearerToken); err != nil {
+			return err
+		}
+		if record.Options.Password, err = encryptOptional(key, record.Options.Password); err != nil {
+			return err
+		}
+	}
+	data, err := json.Marshal(&record)
+	if err != nil {
+		return err
+	}
+	return replaceFile(path, data, 0600)
+}
+
+func encryptOptional(key []byte, value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	return encryptSecret(key, value)
+}
+
+func decryptOptional(key []byte, value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	return decryptSecret(key, value)
+}
+
+// Read the stored source of an environment and decrypt its credentials.
+func ReadEnvironmentSource(id Identifier) (*EnvironmentSourceRecord, error) {
+	data, err := ioutil.ReadFile(id.EnvironmentSourcePathFor())
+	if err != nil {
+		return nil, err
+	}
+	record := &EnvironmentSourceRecord{}
+	if err := json.NewDecoder(bytes.NewReader(data)).Decode(record); err != nil {
+		return nil, err
+	}
+	record.Id = id
+	if record.Options.BearerToken != "" || record.Options.Password != "" {
+		key, err := LoadSecretKey()
+		if err != nil {
+			return nil, err
+		}
+		if record.Options.BearerToken, err = decryptOptional(key, record.Options.BearerToken); err != nil {
+			return nil, err
+		}
+		if record.Options.Password, err = decryptOptional(key, record.Options.Password); err != nil {
+			return nil, err
+		}
+	}
+	return record, nil
+}
+
+// The environments whose sources the daemon refreshes.
+func ListEnvironmentSources() ([]Identifier, error) {
+	ids := []Identifier{}
+	base := filepath.Join(config.ContainerBasePath(), "env", "sources")
+	err := filepath.Walk(base, func(path string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		if id, err := NewIdentifier(info.Name()); err == nil {
+			ids = append(ids, id)
+		}
+		return nil
+	})
+	return ids, err
+}
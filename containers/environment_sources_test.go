@@ -0,0 +1,156 @@
+package containers
+
+import (
+	"github.com/openshift/geard/config"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEnvironmentSourceCheck(t *testing.T) {
+	checksum := contentDigest([]byte("A=1\n"))
+	for _, valid := range []EnvironmentDescription{
+		{Source: "http://host/env"},
+		{Source: "https://host/env", SourceOptions: &EnvironmentSourceOptions{CAPath: "/etc/pki/ca.pem", BearerToken: "abc", Checksum: checksum, RefreshInterval: 60}},
+		{Source: "file:///etc/geard/env/app.env"},
+	} {
+		if err := valid.Check(); err != nil {
+			t.Errorf("Expected %s to be a valid source: %v", valid.Source, err)
+		}
+	}
+	for _, invalid := range []EnvironmentDescription{
+		{Source: "ftp://host/env"},
+		{Source: "file://host/env"},
+		{Source: "http://host/env", SourceOptions: &EnvironmentSourceOptions{BearerToken: "abc"}},
+		{Source: "https://host/env", SourceOptions: &EnvironmentSourceOptions{BearerToken: "abc", Username: "user"}},
+		{Source: "https://host/env", SourceOptions: &EnvironmentSourceOptions{Checksum: "abc"}},
+		{Source: "https://host/env", SourceOptions: &EnvironmentSourceOptions{CAPath: "ca.pem"}},
+	} {
+		if err := invalid.Check(); err == nil {
+			t.Errorf("Expected %s %+v to be rejected", invalid.Source, invalid.SourceOptions)
+		}
+	}
+}
+
+func TestFetchEnvironmentSource(t *testing.T) {
+	content := []byte("A=1\nB=\"two\"\n")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	options := &EnvironmentSourceOptions{BearerToken: "secret", Checksum: contentDigest(content)}
+	env := &EnvironmentDescription{Source: server.URL, SourceOptions: options}
+	if err := env.Fetch(1024); err != nil {
+		t.Fatal(err)
+	}
+	if values := env.Map(); values["A"] != "1" || values["B"] != "two" || env.SourceDigest() != options.Checksum {
+		t.Errorf("Unexpected environment %+v", env)
+	}
+
+	explicit := &EnvironmentDescription{Source: server.URL, SourceOptions: options, Variables: []Environment{{Name: "A", Value: "set"}, {Name: "S", Value: "secret", Secret: true}}}
+	if err := explicit.Fetch(1024); err != nil {
+		t.Fatal(err)
+	}
+	if values := explicit.Map(); len(values) != 3 || values["A"] != "set" || values["B"] != "two" || len(explicit.Secrets()) != 1 {
+		t.Errorf("Expected the variables set directly to override the source: %+v", explicit.Variables)
+	}
+	if len(explicit.fetched) != 1 || explicit.fetched[0] != "B" {
+		t.Errorf("Expected only B to be recorded as fetched: %v", explicit.fetched)
+	}
+
+	if err := (&EnvironmentDescription{Source: server.URL}).Fetch(1024); err == nil {
+		t.Errorf("Expected a request without credentials to fail")
+	}
+	options.Checksum = contentDigest([]byte("other"))
+	if err := env.Fetch(1024); err == nil {
+		t.Errorf("Expected content that does not match the checksum to be rejected")
+	}
+	options.Checksum = ""
+	if err := env.Fetch(4); err == nil {
+		t.Errorf("Expected content larger than the limit to be rejected")
+	}
+}
+
+func TestFileEnvironmentSourceIsRestricted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geard-env-source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	base := filepath.Join(dir, "sources")
+	if err := os.Mkdir(base, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(base, "app.env"), []byte("A=1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "other.env"), []byte("B=2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "other.env"), filepath.Join(base, "link.env")); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(path string) { config.EnvironmentSourcePath = path }(config.EnvironmentSourcePath)
+	config.EnvironmentSourcePath = base
+
+	env := &EnvironmentDescription{Source: "file://" + filepath.Join(base, "app.env")}
+	if err := env.Fetch(1024); err != nil || env.Map()["A"] != "1" {
+		t.Fatalf("Unable to read a file source: %v %+v", err, env)
+	}
+	for _, path := range []string{filepath.Join(dir, "other.env"), filepath.Join(base, "..", "other.env"), filepath.Join(base, "link.env")} {
+		if err := (&EnvironmentDescription{Source: "file://" + path}).Fetch(1024); err == nil {
+			t.Errorf("Expected %s to be outside of the source directory", path)
+		}
+	}
+}
+
+func TestRefreshKeepsSecretsAndVariablesSetDirectly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geard-env-refresh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	envPath := filepath.Join(dir, "contents")
+	secretsPath := filepath.Join(dir, "secrets")
+	if err := ioutil.WriteFile(envPath, []byte("A=\"1\"\nOLD=\"gone\"\nLOCAL=\"patched\"\n"), 0660); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(secretsPath, []byte("PASSWORD=encrypted\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	env := &EnvironmentDescription{Variables: []Environment{{Name: "A", Value: "2"}, {Name: "NEW", Value: "3"}}}
+	env.fetched = EnvironmentVariables(env.Variables).names()
+	if err := env.refreshVariables(envPath, []string{"A", "OLD"}); err != nil {
+		t.Fatal(err)
+	}
+
+	written := &EnvironmentDescription{}
+	file, err := os.Open(envPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err := written.ReadFrom(file); err != nil {
+		t.Fatal(err)
+	}
+	if values := written.Map(); len(values) != 3 || values["A"] != "2" || values["NEW"] != "3" || values["LOCAL"] != "patched" {
+		t.Errorf("Unexpected refreshed environment %v", values)
+	}
+	if strings.Join(env.fetched, ",") != "A,NEW" {
+		t.Errorf("Unexpected fetched names %v", env.fetched)
+	}
+	if data, err := ioutil.ReadFile(secretsPath); err != nil || string(data) != "PASSWORD=encrypted\n" {
+		t.Errorf("Expected the stored secret to be kept: %v %q", err, data)
+	}
+}
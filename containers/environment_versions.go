@@ -0,0 +1,341 @@
+package containers
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type EnvironmentChangeType string
+
+const (
+	EnvironmentAdded   EnvironmentChangeType = "added"
+	EnvironmentRemoved EnvironmentChangeType = "removed"
+	EnvironmentChanged EnvironmentChangeType = "changed"
+)
+
+// A variable that differs between two versions of an environment.
+// Secret values are redacted.
+type EnvironmentChange struct {
+	Name   string
+	Change EnvironmentChangeType
+	Old    string `json:"Old,omitempty"`
+	New    string `json:"New,omitempty"`
+	Secret bool   `json:"Secret,omitempty"`
+}
+
+func (c *EnvironmentChange) String() string {
+	switch c.Change {
+	case EnvironmentAdded:
+		return "+" + c.Name
+	case EnvironmentRemoved:
+		return "-" + c.Name
+	}
+	return "~" + c.Name
+}
+
+type EnvironmentChanges []EnvironmentChange
+
+func (c EnvironmentChanges) String() string {
+	names := make([]string, len(c))
+	for i := range c {
+		names[i] = c[i].String()
+	}
+	return strings.Join(names, " ")
+}
+
+// The changes that turn one set of variables into another, sorted by
+// name.  When a name is set more than once the last value is used.
+func DiffEnvironment(from, to EnvironmentVariables) EnvironmentChanges {
+	before := environmentByName(from)
+	after := environmentByName(to)
+
+	names := make([]string, 0, len(before)+len(after))
+	for name := range before {
+		names = append(names, name)
+	}
+	for name := range after {
+		if _, found := before[name]; !found {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	changes := EnvironmentChanges{}
+	for _, name := range names {
+		old, hadOld := before[name]
+		value, hasNew := after[name]
+		switch {
+		case !hadOld:
+			changes = append(changes, EnvironmentChange{Name: name, Change: EnvironmentAdded, New: redacted(value), Secret: value.Secret})
+		case !hasNew:
+			changes = append(changes, EnvironmentChange{Name: name, Change: EnvironmentRemoved, Old: redacted(old), Secret: old.Secret})
+		case old.Value != value.Value || old.Secret != value.Secret:
+			changes = append(changes, EnvironmentChange{Name: name, Change: EnvironmentChanged, Old: redacted(old), New: redacted(value), Secret: old.Secret || value.Secret})
+		}
+	}
+	return changes
+}
+
+func environmentByName(env EnvironmentVariables) map[string]Environment {
+	all := make(map[string]Environment, len(env))
+	for i := range env {
+		all[env[i].Name] = env[i]
+	}
+	return all
+}
+
+func redacted(e Environment) string {
+	if e.Secret {
+		return RedactedSecretValue
+	}
+	return e.Value
+}
+
+// An immutable copy of an environment recorded each time it is written.
+type EnvironmentVersion struct {
+	Version int
+	Created time.Time
+	// The changes from the previous version
+	Changes EnvironmentChanges `json:"Changes,omitempty"`
+}
+
+// Sorted from newest to oldest, the newest version is the current
+// content of the environment.
+type EnvironmentVersions []EnvironmentVersion
+
+func (v EnvironmentVersions) Less(a, b int) bool {
+	return v[a].Version > v[b].Version
+}
+func (v EnvironmentVersions) Len() int {
+	return len(v)
+}
+func (v EnvironmentVersions) Swap(a, b int) {
+	v[a], v[b] = v[b], v[a]
+}
+
+func (v EnvironmentVersions) Find(version int) (*EnvironmentVersion, bool) {
+	for i := range v {
+		if v[i].Version == version {
+			return &v[i], true
+		}
+	}
+	return nil, false
+}
+
+// Return the version written before the current one.
+func (v EnvironmentVersions) Previous() (*EnvironmentVersion, bool) {
+	if len(v) < 2 {
+		return nil, false
+	}
+	return &v[1], true
+}
+
+// List the versions of an environment with the changes made by each.
+func ListEnvironmentVersions(id Identifier) (EnvironmentVersions, error) {
+	infos, err := ioutil.ReadDir(id.EnvironmentVersionsPathFor())
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make(EnvironmentVersions, 0, len(infos))
+	hasSecrets := false
+	for _, info := range infos {
+		if strings.HasSuffix(info.Name(), ".secrets") {
+			hasSecrets = true
+			continue
+		}
+		n, err := strconv.Atoi(info.Name())
+		if err != nil || n < 1 || info.IsDir() {
+			continue
+		}
+		versions = append(versions, EnvironmentVersion{Version: n, Created: info.ModTime()})
+	}
+	sort.Sort(versions)
+
+	var key []byte
+	if hasSecrets {
+		if key, err = LoadSecretKey(); err != nil {
+			return nil, err
+		}
+	}
+	var next EnvironmentVariables
+	for i := len(versions) - 1; i >= 0; i-- {
+		env, err := readEnvironmentVersion(id, versions[i].Version, key)
+		if err != nil {
+			return nil, err
+		}
+		versions[i].Changes = DiffEnvironment(next, env)
+		next = env
+	}
+	return versions, nil
+}
+
+func readEnvironmentVersion(id Identifier, version int, key []byte) (EnvironmentVariables, error) {
+	path := id.EnvironmentVersionPathFor(strconv.Itoa(version))
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	env := &EnvironmentDescription{}
+	if err := env.ReadFrom(file); err != nil {
+		return nil, err
+	}
+
+	secrets, err := os.Open(path + ".secrets")
+	if os.IsNotExist(err) {
+		return env.Variables, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer secrets.Close()
+	values, err := readSecretsFrom(secrets, key)
+	if err != nil {
+		return nil, err
+	}
+	return append(env.Variables, values...), nil
+}
+
+// Record the current content of an environment and its secrets as a new
+// version, returning its number.  The caller holds the lock of the
+// environment, so the content recorded is the content it just wrote.
+func saveEnvironmentVersion(id Identifier) (int, error) {
+	data, err := ioutil.ReadFile(id.EnvironmentPathFor())
+	if err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	secrets, err := ioutil.ReadFile(id.SecretsPathFor())
+	if err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	return writeEnvironmentVersion(id.EnvironmentVersionsPathFor(), data, secrets)
+}
+
+// Write the content of an environment to the next version in dir, unless
+// it is the same as the newest version, whose number is returned instead.
+// Secrets are nil when the environment has no secrets file.
+func writeEnvironmentVersion(dir string, data, secrets []byte) (int, error) {
+	latest := 0
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	for _, info := range infos {
+		if n, err := strconv.Atoi(info.Name()); err == nil && n > latest {
+			latest = n
+		}
+	}
+	if latest > 0 {
+		path := filepath.Join(dir, strconv.Itoa(latest))
+		previous, err := ioutil.ReadFile(path)
+		if err != nil {
+			return 0, err
+		}
+		previousSecrets, err := ioutil.ReadFile(path + ".secrets")
+		if err != nil && !os.IsNotExist(err) {
+			return 0, err
+		}
+		if bytes.Equal(previous, data) && bytes.Equal(previousSecrets, secrets) && (previousSecrets == nil) == (secrets == nil) {
+			return latest, nil
+		}
+	}
+
+	// the version file is created exclusively to claim its number from
+	// concurrent writers
+	version := latest + 1
+	var file *os.File
+	for {
+		file, err = os.OpenFile(filepath.Join(dir, strconv.Itoa(version)), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0440)
+		if !os.IsExist(err) {
+			break
+		}
+		version++
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	path := file.Name()
+
+	if secrets != nil {
+		if err := ioutil.WriteFile(path+".secrets", secrets, 0400); err != nil {
+			os.Remove(path)
+			return 0, err
+		}
+	}
+	if _, err := file.Write(data); err != nil {
+		os.Remove(path)
+		os.Remove(path + ".secrets")
+		return 0, err
+	}
+	if err := file.Close(); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// Replace the content of an environment and its secrets with an earlier
+// version, which is recorded as a new version.
+func RestoreEnvironmentVersion(id Identifier, version int) (int, error) {
+	lock, err := id.LockEnvironment()
+	if err != nil {
+		return 0, err
+	}
+	defer lock.Close()
+
+	path := id.EnvironmentVersionPathFor(strconv.Itoa(version))
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	secrets, err := ioutil.ReadFile(path + ".secrets")
+	if err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+
+	if err := replaceFile(id.EnvironmentPathFor(), data, 0660); err != nil {
+		return 0, err
+	}
+	if secrets != nil {
+		if err := replaceFile(id.SecretsPathFor(), secrets, 0600); err != nil {
+			return 0, err
+		}
+	} else if err := os.Remove(id.SecretsPathFor()); err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	return saveEnvironmentVersion(id)
+}
+
+func replaceFile(path string, data []byte, mode os.FileMode) error {
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, mode); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// The containers on this host whose unit reads the environment.
+func ContainersUsingEnvironment(id Identifier) ([]Identifier, error) {
+	path := id.EnvironmentPathFor()
+	found := []Identifier{}
+	err := WalkContainerUnits(func(container Identifier) {
+		if environmentPath, err := readEnvironmentPath(container); err == nil && environmentPath == path {
+			found = append(found, container)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return found, nil
+}
@@ -0,0 +1,87 @@
+package containers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestDiffEnvironment(t *testing.T) {
+	changes := DiffEnvironment(
+		EnvironmentVariables{
+			{Name: "A", Value: "1"},
+			{Name: "B", Value: "2"},
+			{Name: "B", Value: "3"},
+			{Name: "TOKEN", Value: "old", Secret: true},
+			{Name: "GONE", Value: "x"},
+		},
+		EnvironmentVariables{
+			{Name: "A", Value: "1"},
+			{Name: "B", Value: "4"},
+			{Name: "TOKEN", Value: "new", Secret: true},
+			{Name: "NEW", Value: "y"},
+		},
+	)
+	if s := changes.String(); s != "~B -GONE +NEW ~TOKEN" {
+		t.Fatalf("Unexpected changes %q: %+v", s, changes)
+	}
+	if changes[0].Old != "3" || changes[0].New != "4" {
+		t.Errorf("Expected the last value of a name to be compared: %+v", changes[0])
+	}
+	if token := changes[3]; token.Old != RedactedSecretValue || token.New != RedactedSecretValue || !token.Secret {
+		t.Errorf("Expected secret values to be redacted: %+v", token)
+	}
+	if len(DiffEnvironment(nil, nil)) != 0 {
+		t.Errorf("Expected no changes between empty environments")
+	}
+}
+
+func TestEnvironmentVersionsPrevious(t *testing.T) {
+	versions := EnvironmentVersions{{Version: 2}, {Version: 10}, {Version: 1}}
+	sort.Sort(versions)
+	if versions[0].Version != 10 || versions[2].Version != 1 {
+		t.Fatalf("Versions not sorted newest first: %+v", versions)
+	}
+	if previous, ok := versions.Previous(); !ok || previous.Version != 2 {
+		t.Errorf("Unexpected previous version %+v", previous)
+	}
+	if _, ok := versions.Find(3); ok {
+		t.Errorf("Expected version 3 to be missing")
+	}
+	if _, ok := versions[2:].Previous(); ok {
+		t.Errorf("A single version should have no previous version")
+	}
+}
+
+func TestWriteEnvironmentVersionSkipsUnchangedContent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geard-env-versions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for i, step := range []struct {
+		data, secrets []byte
+		version       int
+	}{
+		{[]byte("A=\"1\"\n"), nil, 1},
+		{[]byte("A=\"1\"\n"), nil, 1},
+		{[]byte("A=\"2\"\n"), nil, 2},
+		{[]byte("A=\"2\"\n"), []byte("TOKEN=abc\n"), 3},
+		{[]byte("A=\"2\"\n"), []byte("TOKEN=abc\n"), 3},
+		{[]byte("A=\"2\"\n"), nil, 4},
+	} {
+		version, err := writeEnvironmentVersion(dir, step.data, step.secrets)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version != step.version {
+			t.Errorf("Expected write %d to be version %d, got %d", i, step.version, version)
+		}
+	}
+	if data, err := ioutil.ReadFile(filepath.Join(dir, "3.secrets")); err != nil || string(data) != "TOKEN=abc\n" {
+		t.Errorf("Expected the secrets to be recorded with version 3: %v %q", err, data)
+	}
+}
@@ -1,8 +1,12 @@
 package containers
 
 import (
+	"bufio"
+	"encoding/json"
 	"fmt"
+	"github.com/openshift/geard/systemd"
 	"github.com/openshift/go-systemd/dbus"
+	"io"
 	"os"
 	"strings"
 )
@@ -10,6 +14,7 @@ import (
 type EventListener struct {
 	conn      *dbus.Conn
 	exitChan  chan bool
+	health    io.ReadCloser
 	lastEvent map[Identifier]EventType
 }
 
@@ -22,6 +27,12 @@ const (
 	Stopped
 	Deleted
 	Errored
+	// Health events are read from the journal entries written by the
+	// health monitor of the gear daemon.
+	Healthy
+	Unhealthy
+	// systemd has given up restarting the container
+	CrashLooping
 )
 
 type ContainerEvent struct {
@@ -43,6 +54,12 @@ func (e ContainerEvent) String() string {
 		return string(e.Id) + " (deleted)"
 	case e.Type == Errored:
 		return string(e.Id) + " (error)"
+	case e.Type == Healthy:
+		return string(e.Id) + " (healthy)"
+	case e.Type == Unhealthy:
+		return string(e.Id) + " (unhealthy)"
+	case e.Type == CrashLooping:
+		return string(e.Id) + " (crash loop)"
 	}
 	return string(e.Id) + " (unknown)"
 }
@@ -111,7 +128,11 @@ func (e *EventListener) runner(errorChan chan error, eventChan chan *ContainerEv
 							event = ContainerEvent{id, Idled}
 						} else {
 							if update.ActiveState == "failed" {
-								event = ContainerEvent{id, Errored}
+								if result, _ := systemd.GetServiceResult(unit); systemd.IsStartLimitResult(result) {
+									event = ContainerEvent{id, CrashLooping}
+								} else {
+									event = ContainerEvent{id, Errored}
+								}
 							} else {
 								event = ContainerEvent{id, Unknown}
 							}
@@ -139,16 +160,48 @@ func (e *EventListener) runner(errorChan chan error, eventChan chan *ContainerEv
 	fmt.Println("Event listener exited")
 }
 
+// Follow the health transitions published to the journal.  The stream
+// ends when the listener is stopped.
+func (e *EventListener) healthRunner(r io.Reader, errorChan chan error, eventChan chan *ContainerEvent) {
+	scan := bufio.NewScanner(r)
+	scan.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scan.Scan() {
+		fields := make(map[string]interface{})
+		if err := json.Unmarshal(scan.Bytes(), &fields); err != nil {
+			continue
+		}
+		if event, ok := healthEventFrom(fields); ok {
+			eventChan <- event
+		}
+	}
+	if err := scan.Err(); err != nil {
+		select {
+		case errorChan <- err:
+		default:
+		}
+	}
+}
+
 func (e *EventListener) Run() (<-chan *ContainerEvent, <-chan error) {
 	errorChan := make(chan error, 10)
 	e.exitChan = make(chan bool)
 	eventChan := make(chan *ContainerEvent, 100)
 
+	health, err := systemd.FollowJournalMatches(healthEventMatch)
+	if err != nil {
+		errorChan <- err
+	} else {
+		e.health = health
+		go e.healthRunner(health, errorChan, eventChan)
+	}
 	go e.runner(errorChan, eventChan)
 	return eventChan, errorChan
 }
 
 func (e *EventListener) Stop() {
+	if e.health != nil {
+		e.health.Close()
+	}
 	e.exitChan <- true
 }
 
@@ -0,0 +1,375 @@
+package containers
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/openshift/geard/port"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	exportDescriptionName = "container.json"
+	exportDataPrefix      = "data"
+)
+
+// Secrets are encrypted with the key of the host, which the destination
+// of an export does not have, so a container whose environment has
+// secrets is not exported rather than moved without them.
+var ErrExportSecrets = errors.New("The environment of the container has secret variables, which cannot be exported")
+
+// The definition of a container and the files that describe it,
+// packaged to install the container on another host.
+type ContainerExport struct {
+	Id    Identifier
+	Image string
+	// The active unit definition, whose port mappings are replaced
+	// when the container is imported
+	Unit            string
+	Ports           port.PortPairs `json:"Ports,omitempty"`
+	SocketActivated bool           `json:"SocketActivated,omitempty"`
+	// Whether the container is started on boot
+	Started bool `json:"Started,omitempty"`
+	// The host ids the users of the container are mapped to, which
+	// are replaced when the container is imported
+	UserNamespace *UserNamespace `json:"UserNamespace,omitempty"`
+
+	EnvironmentId Identifier `json:"EnvironmentId,omitempty"`
+	Environment   string     `json:"Environment,omitempty"`
+	NetworkLinks  string     `json:"NetworkLinks,omitempty"`
+	HealthChecks  string     `json:"HealthChecks,omitempty"`
+
+	// Public keys in the authorized_keys format that may access the
+	// container over SSH
+	AccessKeys []string `json:"AccessKeys,omitempty"`
+
+	// The environment replaced by WriteFiles, nil if it did not exist
+	replacedEnvironment []byte
+}
+
+// Read the definition of an installed container.
+func NewContainerExport(id Identifier) (*ContainerExport, error) {
+	unit, err := ioutil.ReadFile(id.UnitPathFor())
+	if err != nil {
+		return nil, err
+	}
+	version, err := readVersion(id.UnitPathFor())
+	if err != nil {
+		return nil, err
+	}
+	started, err := id.UnitStartOnBoot()
+	if err != nil {
+		return nil, err
+	}
+	userns, err := UserNamespaceFor(id)
+	if err != nil {
+		return nil, err
+	}
+	e := &ContainerExport{
+		Id:              id,
+		Image:           version.Image,
+		Unit:            string(unit),
+		Ports:           version.Ports,
+		SocketActivated: version.SocketActivated(),
+		Started:         started,
+		UserNamespace:   userns,
+	}
+
+	scan := bufio.NewScanner(bytes.NewReader(unit))
+	for scan.Scan() {
+		if line := scan.Text(); strings.HasPrefix(line, "EnvironmentFile=") {
+			path := strings.TrimPrefix(line, "EnvironmentFile=")
+			envId, err := NewIdentifier(filepath.Base(path))
+			if err != nil || envId.EnvironmentPathFor() != path {
+				return nil, errors.New("The environment file " + path + " is not a stored environment")
+			}
+			if err := checkExportSecrets(envId.SecretsPathFor()); err != nil {
+				return nil, err
+			}
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			e.EnvironmentId, e.Environment = envId, string(data)
+		}
+	}
+
+	for path, value := range map[string]*string{
+		id.NetworkLinksPathFor(): &e.NetworkLinks,
+		id.HealthChecksPathFor(): &e.HealthChecks,
+	} {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		*value = string(data)
+	}
+	return e, nil
+}
+
+func checkExportSecrets(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+	secrets, err := readSecretsFrom(file, nil)
+	if err != nil {
+		return err
+	}
+	if len(secrets) > 0 {
+		return ErrExportSecrets
+	}
+	return nil
+}
+
+func (e *ContainerExport) Check() error {
+	if e.Image == "" {
+		return errors.New("The export does not name the image of the container")
+	}
+	if !strings.Contains(e.Unit, "\nX-ContainerId="+string(e.Id)+"\n") {
+		return errors.New("The unit definition of the export does not belong to " + string(e.Id))
+	}
+	if e.EnvironmentId != "" {
+		if _, err := NewIdentifier(string(e.EnvironmentId)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var exportSeccompPath = regexp.MustCompile("seccomp=(/[^\\s\"]+)")
+
+// Verify that the slice, named volumes, security profile and seccomp
+// filter the unit of the export refers to exist on this host, since the
+// unit cannot start without them.
+func (e *ContainerExport) CheckHost() error {
+	scan := bufio.NewScanner(strings.NewReader(e.Unit))
+	for scan.Scan() {
+		line := scan.Text()
+		switch {
+		case strings.HasPrefix(line, "Slice="):
+			slice, err := NewSliceIdentifier(strings.TrimPrefix(line, "Slice="))
+			if err != nil {
+				return err
+			}
+			if _, err := os.Stat(slice.UnitPathFor()); err != nil {
+				return errors.New(fmt.Sprintf("The slice %s of the container does not exist on this host", slice))
+			}
+		case strings.HasPrefix(line, "X-SecurityProfile="):
+			profile, err := NewProfileIdentifier(strings.TrimPrefix(line, "X-SecurityProfile="))
+			if err != nil {
+				return err
+			}
+			if _, err := os.Stat(profile.DescriptionPathFor()); err != nil {
+				return errors.New(fmt.Sprintf("The security profile %s of the container does not exist on this host", profile))
+			}
+		}
+		for _, match := range exportSeccompPath.FindAllStringSubmatch(line, -1) {
+			if _, err := os.Stat(match[1]); err != nil {
+				return errors.New(fmt.Sprintf("The seccomp filter %s of the container does not exist on this host", match[1]))
+			}
+		}
+	}
+	if err := scan.Err(); err != nil {
+		return err
+	}
+	mounts, err := readVolumesFromUnitFile(strings.NewReader(e.Unit))
+	if err != nil {
+		return err
+	}
+	for i := range mounts {
+		if _, err := os.Stat(mounts[i].Name.PathFor()); err != nil {
+			return errors.New(fmt.Sprintf("The volume %s of the container does not exist on this host", mounts[i].Name))
+		}
+	}
+	return nil
+}
+
+// Return the unit definition with the external ports of the exported
+// mappings replaced by those in reserved, and the user namespace
+// replaced by userns.
+func (e *ContainerExport) UnitFor(reserved port.PortPairs, userns *UserNamespace) string {
+	pairs := make([]string, 0, len(e.Ports)*4+6)
+	for i := range e.Ports {
+		from := e.Ports[i]
+		to, found := reserved.Find(from.Internal)
+		if !found {
+			continue
+		}
+		pairs = append(pairs,
+			fmt.Sprintf("X-PortMapping=%d:%d\n", from.Internal, from.External), fmt.Sprintf("X-PortMapping=%d:%d\n", to.Internal, to.External),
+			fmt.Sprintf("-p %d:%d ", from.External, from.Internal), fmt.Sprintf("-p %d:%d ", to.External, to.Internal),
+		)
+	}
+	if from := e.UserNamespace; from != nil && userns != nil {
+		pairs = append(pairs,
+			from.DockerSpec(), userns.DockerSpec(),
+			"X-UserNamespace="+from.String()+"\n", "X-UserNamespace="+userns.String()+"\n",
+			fmt.Sprintf("X-ContainerUserId=%d\n", from.Start), fmt.Sprintf("X-ContainerUserId=%d\n", userns.Start),
+		)
+	}
+	return strings.NewReplacer(pairs...).Replace(e.Unit)
+}
+
+// Restore the environment, network links and health checks of the
+// container.
+func (e *ContainerExport) WriteFiles() error {
+	if e.EnvironmentId != "" {
+		previous, err := ioutil.ReadFile(e.EnvironmentId.EnvironmentPathFor())
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		e.replacedEnvironment = previous
+		if err := writeFileAtomic(e.EnvironmentId.EnvironmentPathFor(), []byte(e.Environment), 0660); err != nil {
+			return err
+		}
+	}
+	if e.NetworkLinks != "" {
+		if err := writeFileAtomic(e.Id.NetworkLinksPathFor(), []byte(e.NetworkLinks), 0660); err != nil {
+			return err
+		}
+	}
+	if e.HealthChecks != "" {
+		if err := writeFileAtomic(e.Id.HealthChecksPathFor(), []byte(e.HealthChecks), 0660); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Undo WriteFiles after a failed import, restoring an environment that
+// was replaced.
+func (e *ContainerExport) RemoveFiles() error {
+	var err error
+	if e.EnvironmentId != "" {
+		path := e.EnvironmentId.EnvironmentPathFor()
+		if e.replacedEnvironment != nil {
+			err = writeFileAtomic(path, e.replacedEnvironment, 0660)
+		} else if errr := os.Remove(path); errr != nil && !os.IsNotExist(errr) {
+			err = errr
+		}
+	}
+	for _, path := range []string{e.Id.NetworkLinksPathFor(), e.Id.HealthChecksPathFor()} {
+		if errr := os.Remove(path); errr != nil && !os.IsNotExist(errr) {
+			err = errr
+		}
+	}
+	return err
+}
+
+// Write the export to w as a tar stream, followed by the contents of
+// the data volumes, which map a path in the container to the directory
+// on the host.
+func WriteContainerExport(w io.Writer, e *ContainerExport, volumes map[string]string) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	buf := bufio.NewWriter(w)
+	tw := tar.NewWriter(buf)
+	header := &tar.Header{
+		Name:     exportDescriptionName,
+		Mode:     0600,
+		Size:     int64(len(data)),
+		ModTime:  time.Now(),
+		Typeflag: tar.TypeReg,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return err
+	}
+
+	paths := make([]string, 0, len(volumes))
+	for path := range volumes {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if err := writeArchiveEntries(tw, volumes[path], filepath.Join(exportDataPrefix, path)); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return buf.Flush()
+}
+
+// Read the export at the start of a tar stream, returning a reader
+// positioned at the data volume entries that follow it.
+func ReadContainerExport(r io.Reader) (*ContainerExport, *tar.Reader, error) {
+	tr := tar.NewReader(r)
+	header, err := tr.Next()
+	if err != nil {
+		return nil, nil, err
+	}
+	if header.Name != exportDescriptionName {
+		return nil, nil, errors.New("The archive must begin with " + exportDescriptionName)
+	}
+	e := &ContainerExport{}
+	if err := json.NewDecoder(io.LimitReader(tr, 1024*1024)).Decode(e); err != nil {
+		return nil, nil, err
+	}
+	return e, tr, nil
+}
+
+// Extract the data volume entries of an export into the host
+// directories of the volumes with the same path in the container.
+// Entries that are not within one of the volumes are skipped.
+func ExtractContainerData(tr *tar.Reader, volumes map[string]string) error {
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := filepath.Clean(header.Name)
+		if !strings.HasPrefix(name, exportDataPrefix+"/") {
+			continue
+		}
+		path := strings.TrimPrefix(name, exportDataPrefix)
+
+		var volume string
+		for dir := range volumes {
+			if (path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, "/")+"/")) && len(dir) > len(volume) {
+				volume = dir
+			}
+		}
+		if volume == "" {
+			continue
+		}
+
+		rel, err := filepath.Rel(volume, path)
+		if err != nil {
+			return err
+		}
+		header.Name = rel
+		if err := extractArchiveEntry(tr, header, volumes[volume], "/", nil); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package containers
+
+import (
+	"bytes"
+	"github.com/openshift/geard/port"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestContainerExportUnitFor(t *testing.T) {
+	e := &ContainerExport{
+		Id:    Identifier("abc"),
+		Image: "busybox",
+		Unit:  "ExecStart=/usr/bin/docker run -p 4000:8080 -p 4001:22  \"busybox\"\n\nX-ContainerId=abc\nX-PortMapping=8080:4000\nX-PortMapping=22:4001\n",
+		Ports: port.PortPairs{{Internal: 8080, External: 4000}, {Internal: 22, External: 4001}},
+	}
+	if err := e.Check(); err != nil {
+		t.Fatal(err)
+	}
+
+	unit := e.UnitFor(port.PortPairs{{Internal: 8080, External: 4001}, {Internal: 22, External: 5000}}, nil)
+	for _, part := range []string{"-p 4001:8080 -p 5000:22 ", "X-PortMapping=8080:4001\nX-PortMapping=22:5000\n"} {
+		if !strings.Contains(unit, part) {
+			t.Errorf("Expected %q in unit: %s", part, unit)
+		}
+	}
+
+	e.Id = Identifier("other")
+	if err := e.Check(); err == nil {
+		t.Errorf("Expected a unit of another container to be rejected")
+	}
+}
+
+func TestContainerExportUnitForUserNamespace(t *testing.T) {
+	from := &UserNamespace{UserNamespaceBase, UserNamespaceSize}
+	to := &UserNamespace{UserNamespaceBase + 2*UserNamespaceSize, UserNamespaceSize}
+	e := &ContainerExport{
+		Id:            Identifier("abc"),
+		Image:         "busybox",
+		Unit:          "ExecStart=/usr/bin/docker run " + from.DockerSpec() + " \"busybox\"\n\nX-ContainerId=abc\nX-ContainerUserId=1048576\nX-ContainerType=userns\nX-UserNamespace=1048576:65536\n",
+		UserNamespace: from,
+	}
+
+	unit := e.UnitFor(port.PortPairs{}, to)
+	for _, part := range []string{to.DockerSpec(), "X-ContainerUserId=1179648\n", "X-UserNamespace=1179648:65536\n"} {
+		if !strings.Contains(unit, part) {
+			t.Errorf("Expected %q in unit: %s", part, unit)
+		}
+	}
+}
+
+func TestContainerExportCheckHost(t *testing.T) {
+	seccomp, err := ioutil.TempFile("", "geard-seccomp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	seccomp.Close()
+	defer os.Remove(seccomp.Name())
+
+	unit := "ExecStart=/usr/bin/docker run --security-opt seccomp=" + seccomp.Name() + " \"busybox\"\n\nX-ContainerId=abc\n"
+	if err := (&ContainerExport{Id: Identifier("abc"), Unit: unit}).CheckHost(); err != nil {
+		t.Errorf("Expected a unit whose dependencies exist to be accepted: %v", err)
+	}
+	for _, missing := range []string{
+		"Slice=container-missing.slice\n",
+		"X-SecurityProfile=missing\n",
+		"X-Volume=missing:/data\n",
+		"ExecStart=/usr/bin/docker run --security-opt seccomp=" + seccomp.Name() + ".missing \"busybox\"\n",
+	} {
+		if err := (&ContainerExport{Id: Identifier("abc"), Unit: missing + unit}).CheckHost(); err == nil {
+			t.Errorf("Expected a unit with a missing dependency to be rejected: %s", missing)
+		}
+	}
+}
+
+func TestExportRefusesSecrets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geard-export-secrets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "secrets")
+
+	if err := checkExportSecrets(path); err != nil {
+		t.Errorf("Expected an environment without secrets to be exported: %v", err)
+	}
+	if err := ioutil.WriteFile(path, []byte{}, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkExportSecrets(path); err != nil {
+		t.Errorf("Expected an empty secrets file to be exported: %v", err)
+	}
+	if err := ioutil.WriteFile(path, []byte("PASSWORD=c2VjcmV0\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkExportSecrets(path); err != ErrExportSecrets {
+		t.Errorf("Expected an environment with secrets to be refused, got %v", err)
+	}
+}
+
+func TestContainerExportRoundTrip(t *testing.T) {
+	source, err := ioutil.TempDir("", "geard-export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(source)
+	dest, err := ioutil.TempDir("", "geard-export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+
+	os.MkdirAll(filepath.Join(source, "mysql", "db"), 0755)
+	ioutil.WriteFile(filepath.Join(source, "mysql", "db", "data.frm"), []byte("rows"), 0640)
+
+	e := &ContainerExport{Id: Identifier("abc"), Image: "mysql", AccessKeys: []string{"ssh-rsa AAAA"}}
+	buf := &bytes.Buffer{}
+	if err := WriteContainerExport(buf, e, map[string]string{"/var/lib/mysql": filepath.Join(source, "mysql")}); err != nil {
+		t.Fatal(err)
+	}
+
+	read, data, err := ReadContainerExport(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.Id != e.Id || read.Image != e.Image || len(read.AccessKeys) != 1 {
+		t.Errorf("Unexpected export %+v", read)
+	}
+	if err := ExtractContainerData(data, map[string]string{"/var/lib/mysql": dest, "/var/log": filepath.Join(dest, "log")}); err != nil {
+		t.Fatal(err)
+	}
+	contents, err := ioutil.ReadFile(filepath.Join(dest, "db", "data.frm"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(contents) != "rows" {
+		t.Errorf("Unexpected file contents %q", contents)
+	}
+}
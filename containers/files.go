@@ -0,0 +1,281 @@
+package containers
+
+import (
+	"archive/tar"
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const maxSymlinkDepth = 40
+
+// Resolve a path inside of root as a process chrooted to root would,
+// following symbolic links without allowing them to escape root.  Path
+// components that do not exist are appended unresolved.
+func ResolvePathInRoot(root, path string) (string, error) {
+	resolved := "/"
+	remaining := strings.Split(filepath.Clean("/"+path), "/")
+	links := 0
+	for len(remaining) > 0 {
+		part := remaining[0]
+		remaining = remaining[1:]
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			resolved = filepath.Dir(resolved)
+			continue
+		}
+
+		next := filepath.Join(resolved, part)
+		info, err := os.Lstat(filepath.Join(root, next))
+		if err != nil {
+			if os.IsNotExist(err) {
+				resolved = next
+				continue
+			}
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+
+		links++
+		if links > maxSymlinkDepth {
+			return "", errors.New("Too many symbolic links in " + path)
+		}
+		target, err := os.Readlink(filepath.Join(root, next))
+		if err != nil {
+			return "", err
+		}
+		if filepath.IsAbs(target) {
+			resolved = "/"
+		}
+		remaining = append(strings.Split(target, "/"), remaining...)
+	}
+	return filepath.Join(root, resolved), nil
+}
+
+// Write the file or directory at source to w as a tar stream, with
+// entries named beneath base.  Symbolic links are archived as links.
+func WriteArchive(w io.Writer, source, base string) error {
+	if base == "/" {
+		base = "."
+	}
+	buf := bufio.NewWriter(w)
+	tw := tar.NewWriter(buf)
+	if err := writeArchiveEntries(tw, source, base); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return buf.Flush()
+}
+
+func writeArchiveEntries(tw *tar.Writer, source, base string) error {
+	return filepath.Walk(source, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(source, p)
+		if err != nil {
+			return err
+		}
+
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			// sockets and other special files are skipped
+			return nil
+		}
+		header.Name = filepath.Join(base, rel)
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+}
+
+// The owner applied to extracted files, or nil to keep the owners
+// recorded in the archive.
+type FileOwner struct {
+	Uid int
+	Gid int
+}
+
+// Extract a tar stream into the directory at path inside of root.
+// Entries may not refer to locations outside of the directory.
+func ExtractArchive(r io.Reader, root, path string, owner *FileOwner) error {
+	dir := filepath.Clean("/" + path)
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := extractArchiveEntry(tr, header, root, dir, owner); err != nil {
+			return err
+		}
+	}
+}
+
+// Extract the current entry of tr into the directory dir inside of root.
+func extractArchiveEntry(tr *tar.Reader, header *tar.Header, root, dir string, owner *FileOwner) error {
+	name := filepath.Clean(header.Name)
+	if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+		return errors.New("The archive entry " + header.Name + " is outside of the destination")
+	}
+	parent, err := ResolvePathInRoot(root, filepath.Join(dir, filepath.Dir(name)))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return err
+	}
+	target := filepath.Join(parent, filepath.Base(name))
+	if name == "." {
+		target = parent
+	}
+
+	// never write through an existing link
+	if info, err := os.Lstat(target); err == nil && (info.Mode()&os.ModeSymlink != 0 || (info.IsDir() && header.Typeflag != tar.TypeDir)) {
+		if err := os.RemoveAll(target); err != nil {
+			return err
+		}
+	}
+
+	mode := os.FileMode(header.Mode).Perm()
+	switch header.Typeflag {
+	case tar.TypeDir:
+		if err := os.Mkdir(target, mode); err != nil && !os.IsExist(err) {
+			return err
+		}
+	case tar.TypeReg, tar.TypeRegA:
+		f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(f, tr)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	case tar.TypeSymlink:
+		os.Remove(target)
+		if err := os.Symlink(header.Linkname, target); err != nil {
+			return err
+		}
+	case tar.TypeLink:
+		source, err := ResolvePathInRoot(root, filepath.Join(dir, filepath.Clean("/"+header.Linkname)))
+		if err != nil {
+			return err
+		}
+		os.Remove(target)
+		if err := os.Link(source, target); err != nil {
+			return err
+		}
+	default:
+		return nil
+	}
+
+	uid, gid := header.Uid, header.Gid
+	if owner != nil {
+		uid, gid = owner.Uid, owner.Gid
+	}
+	if err := os.Lchown(target, uid, gid); err != nil && !os.IsPermission(err) {
+		return err
+	}
+	if header.Typeflag != tar.TypeSymlink {
+		if err := os.Chmod(target, mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Whether the container runs as its own user or in a user namespace.
+func IsIsolatedContainer(id Identifier) (bool, error) {
+	isolated, _, err := getContainerType(id)
+	return isolated, err
+}
+
+func getContainerType(id Identifier) (isolated, userns bool, err error) {
+	f, err := os.Open(id.UnitPathFor())
+	if err != nil {
+		return false, false, err
+	}
+	defer f.Close()
+
+	scan := bufio.NewScanner(f)
+	for scan.Scan() {
+		if line := scan.Text(); line == "X-ContainerType=isolated" || line == "X-ContainerType=userns" {
+			return true, line == "X-ContainerType=userns", nil
+		}
+	}
+	return false, false, scan.Err()
+}
+
+// Return the host user that owns the files of an isolated container, or
+// nil if the container is not isolated.  Files of a container in a user
+// namespace are owned by its root user.
+func GetContainerFileOwner(id Identifier) (*FileOwner, error) {
+	isolated, userns, err := getContainerType(id)
+	if err != nil {
+		return nil, err
+	}
+	if !isolated {
+		return nil, nil
+	}
+	// the root user of a container in a user namespace
+	if userns {
+		r, err := UserNamespaceFor(id)
+		if err != nil {
+			return nil, err
+		}
+		if r != nil {
+			return &FileOwner{r.Start, r.Start}, nil
+		}
+	}
+
+	u, err := user.Lookup(id.LoginFor())
+	if err != nil {
+		return nil, err
+	}
+	uid, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		return nil, err
+	}
+	gid, err := strconv.Atoi(u.Gid)
+	if err != nil {
+		return nil, err
+	}
+	return &FileOwner{uid, gid}, nil
+}
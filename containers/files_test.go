@@ -0,0 +1,91 @@
+package containers
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolvePathInRoot(t *testing.T) {
+	root, err := ioutil.TempDir("", "geard-files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	os.MkdirAll(filepath.Join(root, "var/lib"), 0755)
+	os.Symlink("/etc", filepath.Join(root, "var/escape"))
+	os.Symlink("../../..", filepath.Join(root, "var/lib/up"))
+	os.Symlink("lib", filepath.Join(root, "var/data"))
+
+	for path, expected := range map[string]string{
+		"/var/escape/passwd": "/etc/passwd",
+		"/var/lib/up/etc":    "/etc",
+		"/var/data/app":      "/var/lib/app",
+		"/../../tmp":         "/tmp",
+	} {
+		resolved, err := ResolvePathInRoot(root, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resolved != filepath.Join(root, expected) {
+			t.Errorf("Expected %s to resolve to %s, got %s", path, expected, resolved)
+		}
+	}
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	source, err := ioutil.TempDir("", "geard-files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(source)
+	dest, err := ioutil.TempDir("", "geard-files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+
+	os.MkdirAll(filepath.Join(source, "config/conf.d"), 0755)
+	ioutil.WriteFile(filepath.Join(source, "config/conf.d/app.conf"), []byte("port=8080\n"), 0640)
+	os.Symlink("conf.d/app.conf", filepath.Join(source, "config/app.conf"))
+
+	buf := &bytes.Buffer{}
+	if err := WriteArchive(buf, filepath.Join(source, "config"), "config"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExtractArchive(buf, dest, "/etc", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dest, "etc/config/app.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "port=8080\n" {
+		t.Errorf("Unexpected file contents %q", data)
+	}
+	if info, err := os.Stat(filepath.Join(dest, "etc/config/conf.d/app.conf")); err != nil || info.Mode().Perm() != 0640 {
+		t.Errorf("Unexpected file mode %v: %v", info, err)
+	}
+}
+
+func TestExtractArchiveOutsideDestination(t *testing.T) {
+	dest, err := ioutil.TempDir("", "geard-files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	tw.WriteHeader(&tar.Header{Name: "../escape", Mode: 0644, Typeflag: tar.TypeReg})
+	tw.Close()
+
+	if err := ExtractArchive(buf, dest, "/", nil); err == nil {
+		t.Errorf("Expected an entry outside of the destination to fail")
+	}
+}
@@ -0,0 +1,293 @@
+package containers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/openshift/geard/config"
+	"github.com/openshift/geard/port"
+	"github.com/openshift/geard/utils"
+	"github.com/openshift/go-systemd/journal"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type HealthCheckType string
+
+const (
+	// Open a TCP connection to an internal port
+	HealthCheckTCP HealthCheckType = "tcp"
+	// Perform an HTTP GET against an internal port, any 2xx or 3xx is healthy
+	HealthCheckHTTP HealthCheckType = "http"
+	// Run a command inside the container namespace with switchns, exit 0 is healthy
+	HealthCheckCommand HealthCheckType = "command"
+)
+
+const (
+	DefaultHealthInterval  = 30
+	DefaultHealthTimeout   = 5
+	DefaultHealthThreshold = 3
+)
+
+// A check performed periodically against a running container.
+type HealthCheck struct {
+	Type HealthCheckType
+
+	Port    port.Port `json:"Port,omitempty"`
+	Path    string    `json:"Path,omitempty"`
+	Command []string  `json:"Command,omitempty"`
+
+	// Seconds between checks
+	Interval int `json:"Interval,omitempty"`
+	// Seconds before an individual check is considered failed
+	Timeout int `json:"Timeout,omitempty"`
+	// Consecutive results required before the health of the
+	// container changes
+	Threshold int `json:"Threshold,omitempty"`
+}
+
+type HealthChecks []HealthCheck
+
+func (h *HealthCheck) Check() error {
+	switch h.Type {
+	case HealthCheckTCP, HealthCheckHTTP:
+		if err := h.Port.Check(); err != nil {
+			return errors.New("A health check port must be a positive integer less than 65536")
+		}
+		if h.Type == HealthCheckHTTP && h.Path != "" && !strings.HasPrefix(h.Path, "/") {
+			return errors.New("A health check path must begin with '/'")
+		}
+	case HealthCheckCommand:
+		if len(h.Command) == 0 {
+			return errors.New("A command health check must specify the command to run")
+		}
+	default:
+		return errors.New(fmt.Sprintf("Unrecognized health check type '%s', must be one of tcp, http, or command", h.Type))
+	}
+	if h.Interval < 0 || h.Timeout < 0 || h.Threshold < 0 {
+		return errors.New("Health check interval, timeout, and threshold may not be negative")
+	}
+	if h.Interval == 0 {
+		h.Interval = DefaultHealthInterval
+	}
+	if h.Timeout == 0 {
+		h.Timeout = DefaultHealthTimeout
+	}
+	if h.Threshold == 0 {
+		h.Threshold = DefaultHealthThreshold
+	}
+	if h.Timeout > h.Interval {
+		return errors.New("A health check timeout may not be longer than its interval")
+	}
+	return nil
+}
+
+func (h *HealthCheck) IntervalDuration() time.Duration {
+	return time.Duration(h.Interval) * time.Second
+}
+
+func (h *HealthCheck) TimeoutDuration() time.Duration {
+	return time.Duration(h.Timeout) * time.Second
+}
+
+func (h *HealthCheck) String() string {
+	switch h.Type {
+	case HealthCheckTCP:
+		return fmt.Sprintf("tcp:%d", h.Port)
+	case HealthCheckHTTP:
+		return fmt.Sprintf("http:%d%s", h.Port, h.Path)
+	case HealthCheckCommand:
+		return "cmd:" + strings.Join(h.Command, " ")
+	}
+	return string(h.Type)
+}
+
+func (h HealthChecks) Check() error {
+	for i := range h {
+		if err := h[i].Check(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Parse a health check of the form tcp:<port>, http:<port>[/<path>], or
+// cmd:<command> [<arg>...]
+func NewHealthCheckFromString(s string) (*HealthCheck, error) {
+	value := strings.SplitN(s, ":", 2)
+	if len(value) != 2 || value[1] == "" {
+		return nil, errors.New(fmt.Sprintf("The health check '%s' must be of the form tcp:<port>, http:<port>[/<path>], or cmd:<command>", s))
+	}
+	check := &HealthCheck{}
+	switch value[0] {
+	case "tcp":
+		check.Type = HealthCheckTCP
+	case "http":
+		check.Type = HealthCheckHTTP
+		if i := strings.Index(value[1], "/"); i != -1 {
+			check.Path = value[1][i:]
+			value[1] = value[1][:i]
+		}
+	case "cmd", "command":
+		check.Type = HealthCheckCommand
+		check.Command = strings.Fields(value[1])
+		return check, nil
+	default:
+		return nil, errors.New(fmt.Sprintf("Unrecognized health check type '%s', must be one of tcp, http, or cmd", value[0]))
+	}
+	p, err := strconv.Atoi(value[1])
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("The health check port '%s' must be a number", value[1]))
+	}
+	check.Port = port.Port(p)
+	return check, nil
+}
+
+func (i Identifier) HealthChecksPathFor() string {
+	return utils.IsolateContentPath(filepath.Join(config.ContainerBasePath(), "health"), string(i), "")
+}
+
+func (i Identifier) HealthStatePathFor() string {
+	return filepath.Join(i.RunPathFor(), "health")
+}
+
+// Persist the health checks for a container, removing any existing
+// checks when none are provided.
+func (h HealthChecks) Write(id Identifier) error {
+	path := id.HealthChecksPathFor()
+	if len(h) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0660)
+}
+
+func ReadHealthChecks(id Identifier) (HealthChecks, error) {
+	data, err := ioutil.ReadFile(id.HealthChecksPathFor())
+	if err != nil {
+		return nil, err
+	}
+	checks := HealthChecks{}
+	if err := json.Unmarshal(data, &checks); err != nil {
+		return nil, err
+	}
+	return checks, nil
+}
+
+type Health string
+
+const (
+	HealthNone      Health = ""
+	HealthStarting  Health = "starting"
+	HealthHealthy   Health = "healthy"
+	HealthUnhealthy Health = "unhealthy"
+)
+
+// The last reported health of a running container.
+type HealthState struct {
+	Health  Health
+	Message string    `json:"Message,omitempty"`
+	Updated time.Time `json:"Updated"`
+}
+
+func ReadHealthState(id Identifier) (*HealthState, error) {
+	data, err := ioutil.ReadFile(id.HealthStatePathFor())
+	if err != nil {
+		return nil, err
+	}
+	state := &HealthState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// Return the health of a container, or HealthNone if the container
+// has no health checks or is not running.
+func GetHealth(id Identifier) Health {
+	state, err := ReadHealthState(id)
+	if err != nil {
+		return HealthNone
+	}
+	return state.Health
+}
+
+// The journal match of the entries recording health transitions, which
+// event listeners in any process follow.
+const healthEventMatch = "GEARD_EVENT=health"
+
+// Record the health of a container and publish a structured journal
+// entry when it becomes healthy or unhealthy.  HealthNone clears the
+// state.
+func SetHealth(id Identifier, health Health, message string) error {
+	path := id.HealthStatePathFor()
+	previous := GetHealth(id)
+	if health == HealthNone {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	} else {
+		data, err := json.Marshal(&HealthState{health, message, time.Now()})
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(path, data, 0664); err != nil {
+			return err
+		}
+	}
+	if previous == health || (health != HealthHealthy && health != HealthUnhealthy) {
+		return nil
+	}
+
+	priority := journal.PriInfo
+	if health == HealthUnhealthy {
+		priority = journal.PriWarning
+	}
+	text := fmt.Sprintf("Container %s is %s", id, health)
+	if message != "" {
+		text += ": " + message
+	}
+	return journal.Send(text, priority, map[string]string{
+		"GEARD_EVENT":     "health",
+		"GEARD_CONTAINER": string(id),
+		"GEARD_HEALTH":    string(health),
+	})
+}
+
+// Convert a journal entry written by SetHealth to an event.
+func healthEventFrom(fields map[string]interface{}) (*ContainerEvent, bool) {
+	value, _ := fields["GEARD_CONTAINER"].(string)
+	id, err := NewIdentifier(value)
+	if err != nil {
+		return nil, false
+	}
+	switch health, _ := fields["GEARD_HEALTH"].(string); Health(health) {
+	case HealthHealthy:
+		return &ContainerEvent{id, Healthy}, true
+	case HealthUnhealthy:
+		return &ContainerEvent{id, Unhealthy}, true
+	}
+	return nil, false
+}
+
+func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, mode); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
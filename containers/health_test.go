@@ -0,0 +1,59 @@
+package containers
+
+import (
+	"testing"
+)
+
+func TestNewHealthCheckFromString(t *testing.T) {
+	check, err := NewHealthCheckFromString("http:8080/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if check.Type != HealthCheckHTTP || check.Port != 8080 || check.Path != "/healthz" {
+		t.Errorf("Unexpected http check %+v", check)
+	}
+	check, err = NewHealthCheckFromString("cmd:/bin/check --quiet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if check.Type != HealthCheckCommand || len(check.Command) != 2 {
+		t.Errorf("Unexpected command check %+v", check)
+	}
+	for _, s := range []string{"tcp", "tcp:", "tcp:abc", "udp:53"} {
+		if _, err := NewHealthCheckFromString(s); err == nil {
+			t.Errorf("Expected %s to be an invalid health check", s)
+		}
+	}
+}
+
+func TestHealthCheckDefaults(t *testing.T) {
+	check := &HealthCheck{Type: HealthCheckTCP, Port: 80}
+	if err := check.Check(); err != nil {
+		t.Fatal(err)
+	}
+	if check.Interval != DefaultHealthInterval || check.Timeout != DefaultHealthTimeout || check.Threshold != DefaultHealthThreshold {
+		t.Errorf("Defaults were not applied %+v", check)
+	}
+	if err := (&HealthCheck{Type: HealthCheckTCP, Port: 80, Interval: 1, Timeout: 5}).Check(); err == nil {
+		t.Error("A timeout longer than the interval should be rejected")
+	}
+	if err := (&HealthCheck{Type: HealthCheckHTTP}).Check(); err == nil {
+		t.Error("An http check without a port should be rejected")
+	}
+}
+
+func TestHealthEventFromJournal(t *testing.T) {
+	event, ok := healthEventFrom(map[string]interface{}{"GEARD_EVENT": "health", "GEARD_CONTAINER": "web-1", "GEARD_HEALTH": "unhealthy"})
+	if !ok || event.Id != Identifier("web-1") || event.Type != Unhealthy {
+		t.Errorf("Unexpected event %+v", event)
+	}
+	for _, fields := range []map[string]interface{}{
+		{"GEARD_CONTAINER": "web-1", "GEARD_HEALTH": "starting"},
+		{"GEARD_CONTAINER": "web 1", "GEARD_HEALTH": "healthy"},
+		{"GEARD_HEALTH": "healthy"},
+	} {
+		if _, ok := healthEventFrom(fields); ok {
+			t.Errorf("Expected %v not to be a health event", fields)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+package containers
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type HookStage string
+
+const (
+	// Before the container starts, e.g. to run migrations
+	HookPreStart HookStage = "pre-start"
+	// Once the container is running
+	HookPostStart HookStage = "post-start"
+	// Before the container is stopped, e.g. to deregister it from
+	// service discovery
+	HookPreStop HookStage = "pre-stop"
+	// After the container has stopped
+	HookPostStop HookStage = "post-stop"
+)
+
+type HookType string
+
+const (
+	// Run a command inside the container namespace with switchns.
+	// Before start and after stop the command is run in a new
+	// container from the image with the same volumes and environment.
+	HookCommand HookType = "command"
+	// Send an HTTP request, any 2xx or 3xx response succeeds
+	HookHTTP HookType = "http"
+)
+
+type HookFailurePolicy string
+
+const (
+	// A failed start hook prevents the container from starting
+	HookFailureAbort HookFailurePolicy = "fail"
+	// Failures are logged and the container continues to start or stop
+	HookFailureIgnore HookFailurePolicy = "ignore"
+)
+
+const (
+	DefaultHookTimeout    = 30
+	DefaultHookHTTPMethod = "POST"
+	// The systemd start and stop timeouts of a container without hooks
+	defaultStartTimeout = 300
+	defaultStopTimeout  = 90
+)
+
+// An action run by systemd at a stage of the container lifecycle.
+type LifecycleHook struct {
+	Stage HookStage
+	Type  HookType
+
+	Command []string `json:"Command,omitempty"`
+	Method  string   `json:"Method,omitempty"`
+	Url     string   `json:"Url,omitempty"`
+
+	// Seconds before the hook is stopped and considered failed
+	Timeout   int               `json:"Timeout,omitempty"`
+	OnFailure HookFailurePolicy `json:"OnFailure,omitempty"`
+}
+
+type LifecycleHooks []LifecycleHook
+
+func (h *LifecycleHook) Check() error {
+	switch h.Stage {
+	case HookPreStart, HookPostStart, HookPreStop, HookPostStop:
+	default:
+		return errors.New(fmt.Sprintf("Unrecognized hook stage '%s', must be one of pre-start, post-start, pre-stop, or post-stop", h.Stage))
+	}
+	switch h.Type {
+	case HookCommand:
+		if len(h.Command) == 0 {
+			return errors.New("A command hook must specify the command to run")
+		}
+		for i := range h.Command {
+			if err := checkUnitValue("hook command", h.Command[i]); err != nil {
+				return err
+			}
+		}
+	case HookHTTP:
+		u, err := url.Parse(h.Url)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("An HTTP hook must specify an http or https URL")
+		}
+		if err := checkUnitValue("hook URL", h.Url); err != nil {
+			return err
+		}
+		if h.Method == "" {
+			h.Method = DefaultHookHTTPMethod
+		}
+		h.Method = strings.ToUpper(h.Method)
+		if strings.IndexFunc(h.Method, func(r rune) bool { return r < 'A' || r > 'Z' }) != -1 {
+			return errors.New(fmt.Sprintf("The HTTP method '%s' is not valid", h.Method))
+		}
+	default:
+		return errors.New(fmt.Sprintf("Unrecognized hook type '%s', must be one of command or http", h.Type))
+	}
+	if h.Timeout < 0 {
+		return errors.New("A hook timeout may not be negative")
+	}
+	if h.Timeout == 0 {
+		h.Timeout = DefaultHookTimeout
+	}
+	switch h.OnFailure {
+	case "":
+		h.OnFailure = HookFailureAbort
+	case HookFailureAbort, HookFailureIgnore:
+	default:
+		return errors.New(fmt.Sprintf("Unrecognized hook failure policy '%s', must be one of fail or ignore", h.OnFailure))
+	}
+	return nil
+}
+
+func (h *LifecycleHook) TimeoutDuration() time.Duration {
+	return time.Duration(h.Timeout) * time.Second
+}
+
+// Whether systemd should ignore the exit code of the hook.
+func (h *LifecycleHook) Ignored() bool {
+	return h.OnFailure == HookFailureIgnore
+}
+
+// The arguments to 'gear hook' that run the hook, quoted for a unit file.
+func (h *LifecycleHook) HookSpec() string {
+	parts := []string{string(h.Stage), fmt.Sprintf("--timeout=%d", h.Timeout)}
+	switch h.Type {
+	case HookHTTP:
+		parts = append(parts, "--method="+h.Method, "--url", unitQuote(h.Url))
+	case HookCommand:
+		parts = append(parts, "--")
+		for i := range h.Command {
+			parts = append(parts, unitQuote(h.Command[i]))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func (h *LifecycleHook) String() string {
+	switch h.Type {
+	case HookHTTP:
+		return fmt.Sprintf("%s:http:%s %s", h.Stage, h.Method, h.Url)
+	case HookCommand:
+		return fmt.Sprintf("%s:cmd:%s", h.Stage, joinCommandLine(h.Command))
+	}
+	return string(h.Stage)
+}
+
+// The name of the container a command hook runs in before the
+// container starts or after it stops.
+func (i Identifier) HookContainerFor() string {
+	return i.ContainerFor() + "-hook"
+}
+
+// The command that runs a command hook in a new container from the
+// image of the installed container, with the same data and named
+// volumes, environment and user namespace.
+func (h *LifecycleHook) DockerCommand(id Identifier) ([]string, error) {
+	version, err := readVersion(id.UnitPathFor())
+	if err != nil {
+		return nil, err
+	}
+	mounts, err := GetContainerVolumes(id)
+	if err != nil {
+		return nil, err
+	}
+	environmentPath, err := readEnvironmentPath(id)
+	if err != nil {
+		return nil, err
+	}
+	userns, err := UserNamespaceFor(id)
+	if err != nil {
+		return nil, err
+	}
+
+	command := []string{
+		"/usr/bin/docker", "run",
+		"--rm", "--name", id.HookContainerFor(),
+		"--volumes-from", id.ContainerFor() + "-data",
+		"-a", "stdout", "-a", "stderr",
+	}
+	if _, err := os.Stat(id.EnvironmentRunPathFor()); err == nil {
+		command = append(command, "--env-file", id.EnvironmentRunPathFor())
+	} else if environmentPath != "" {
+		command = append(command, "--env-file", environmentPath)
+	}
+	for i := range mounts {
+		command = append(command, "-v", mounts[i].BindSpec())
+	}
+	if userns != nil {
+		command = append(command, strings.Fields(userns.DockerSpec())...)
+	}
+	command = append(command, "--entrypoint", h.Command[0], version.Image)
+	return append(command, h.Command[1:]...), nil
+}
+
+func readEnvironmentPath(id Identifier) (string, error) {
+	f, err := os.Open(id.UnitPathFor())
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scan := bufio.NewScanner(f)
+	for scan.Scan() {
+		if line := strings.TrimSpace(scan.Text()); strings.HasPrefix(line, "EnvironmentFile=") {
+			return strings.TrimPrefix(line, "EnvironmentFile="), nil
+		}
+	}
+	return "", scan.Err()
+}
+
+func (h LifecycleHooks) Check() error {
+	for i := range h {
+		if err := h[i].Check(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The hooks run at a stage, in the order they were declared.
+func (h LifecycleHooks) For(stage HookStage) LifecycleHooks {
+	hooks := LifecycleHooks{}
+	for i := range h {
+		if h[i].Stage == stage {
+			hooks = append(hooks, h[i])
+		}
+	}
+	return hooks
+}
+
+// The seconds systemd waits for the container to start, which
+// includes the timeouts of the start hooks.
+func (h LifecycleHooks) StartTimeout() int {
+	timeout := defaultStartTimeout
+	for i := range h {
+		if h[i].Stage == HookPreStart || h[i].Stage == HookPostStart {
+			timeout += h[i].Timeout
+		}
+	}
+	return timeout
+}
+
+// The seconds systemd waits for the container to stop, which includes
+// the timeouts of the stop hooks.
+func (h LifecycleHooks) StopTimeout() int {
+	timeout := defaultStopTimeout
+	for i := range h {
+		if h[i].Stage == HookPreStop || h[i].Stage == HookPostStop {
+			timeout += h[i].Timeout
+		}
+	}
+	return timeout
+}
+
+// Parse a hook of the form <stage>:cmd:<command> [<arg>...] or
+// <stage>:http:[<method> ]<url>.  A stage prefixed with '-' ignores
+// failures of the hook.  The words of a command are split like a shell
+// would, so an argument containing spaces may be single or double
+// quoted, or the spaces escaped with '\'.
+func NewLifecycleHookFromString(s string) (*LifecycleHook, error) {
+	value := strings.SplitN(s, ":", 3)
+	if len(value) != 3 || value[2] == "" {
+		return nil, errors.New(fmt.Sprintf("The hook '%s' must be of the form <stage>:cmd:<command> or <stage>:http:[<method> ]<url>", s))
+	}
+	hook := &LifecycleHook{Stage: HookStage(value[0])}
+	if strings.HasPrefix(value[0], "-") {
+		hook.Stage = HookStage(value[0][1:])
+		hook.OnFailure = HookFailureIgnore
+	}
+	switch value[1] {
+	case "cmd", "command":
+		hook.Type = HookCommand
+		command, err := splitCommandLine(value[2])
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("The command of the hook '%s' is invalid: %s", s, err.Error()))
+		}
+		hook.Command = command
+	case "http":
+		hook.Type = HookHTTP
+		fields := strings.Fields(value[2])
+		switch len(fields) {
+		case 1:
+			hook.Url = fields[0]
+		case 2:
+			hook.Method, hook.Url = fields[0], fields[1]
+		default:
+			return nil, errors.New(fmt.Sprintf("The HTTP hook '%s' must be of the form <stage>:http:[<method> ]<url>", s))
+		}
+	default:
+		return nil, errors.New(fmt.Sprintf("Unrecognized hook type '%s', must be one of cmd or http", value[1]))
+	}
+	return hook, nil
+}
+
+// Split a command line into words on unquoted whitespace.  Single quotes
+// preserve every character up to the closing quote, inside double quotes
+// a backslash escapes only '"' and '\\', and outside of quotes a
+// backslash escapes any character.  Variables and globs are not
+// expanded.
+func splitCommandLine(s string) ([]string, error) {
+	words := []string{}
+	var word []rune
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, c := range s {
+		switch {
+		case escaped:
+			if quote == '"' && c != '"' && c != '\\' {
+				word = append(word, '\\')
+			}
+			word = append(word, c)
+			escaped = false
+		case c == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				word = append(word, c)
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			inWord = true
+		case unicode.IsSpace(c):
+			if inWord {
+				words = append(words, string(word))
+				word, inWord = nil, false
+			}
+		default:
+			word = append(word, c)
+			inWord = true
+		}
+	}
+	if escaped {
+		return nil, errors.New("the command ends with an unfinished escape")
+	}
+	if quote != 0 {
+		return nil, errors.New(fmt.Sprintf("the command has an unterminated %c quote", quote))
+	}
+	if inWord {
+		words = append(words, string(word))
+	}
+	return words, nil
+}
+
+// Join words so that splitCommandLine returns them unchanged.
+func joinCommandLine(words []string) string {
+	parts := make([]string, len(words))
+	for i, word := range words {
+		if word != "" && !strings.ContainsAny(word, " \t\n'\"\\") {
+			parts[i] = word
+			continue
+		}
+		parts[i] = "'" + strings.Replace(word, "'", `'\''`, -1) + "'"
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,84 @@
+package containers
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestNewLifecycleHookFromString(t *testing.T) {
+	hook, err := NewLifecycleHookFromString("pre-start:cmd:/app/migrate --all")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := hook.Check(); err != nil {
+		t.Fatal(err)
+	}
+	if hook.Stage != HookPreStart || hook.Type != HookCommand || len(hook.Command) != 2 || hook.Timeout != DefaultHookTimeout || hook.Ignored() {
+		t.Errorf("Unexpected command hook %+v", hook)
+	}
+	if spec := hook.HookSpec(); spec != `pre-start --timeout=30 -- "/app/migrate" "--all"` {
+		t.Errorf("Unexpected hook spec %q", spec)
+	}
+
+	hook, err = NewLifecycleHookFromString("-pre-stop:http:delete http://registry:8500/v1/agent/service/deregister/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := hook.Check(); err != nil {
+		t.Fatal(err)
+	}
+	if hook.Stage != HookPreStop || hook.Method != "DELETE" || !hook.Ignored() {
+		t.Errorf("Unexpected http hook %+v", hook)
+	}
+
+	hook, err = NewLifecycleHookFromString(`post-start:cmd:/bin/sh -c 'echo "started" > /tmp/log' a\ b "c \"d\""`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(hook.Command, "|") != `/bin/sh|-c|echo "started" > /tmp/log|a b|c "d"` {
+		t.Errorf("Unexpected quoted command %q", hook.Command)
+	}
+	if parsed, err := NewLifecycleHookFromString(hook.String()); err != nil || strings.Join(parsed.Command, "|") != strings.Join(hook.Command, "|") {
+		t.Errorf("Expected %s to parse to the same command: %v %q", hook, err, parsed)
+	}
+
+	for _, s := range []string{"pre-start", "pre-start:cmd:", `pre-start:cmd:/bin/echo "a`, `pre-start:cmd:/bin/echo a\`, "start:cmd:/bin/true", "post-stop:exec:/bin/true", "post-start:http:ftp://host/", "post-start:http:GET PUT http://host/"} {
+		if hook, err := NewLifecycleHookFromString(s); err == nil && hook.Check() == nil {
+			t.Errorf("Expected %s to be an invalid hook", s)
+		}
+	}
+}
+
+func TestHooksInUnitFile(t *testing.T) {
+	unit := ContainerUnit{
+		Id:             Identifier("test"),
+		Image:          "busybox",
+		ExecutablePath: "/usr/bin/gear",
+		Hooks: LifecycleHooks{
+			{Stage: HookPreStart, Type: HookCommand, Command: []string{"/app/migrate"}},
+			{Stage: HookPreStop, Type: HookHTTP, Url: "http://registry/app", Method: "DELETE", OnFailure: HookFailureIgnore, Timeout: 10},
+		},
+	}
+	if err := unit.Hooks.Check(); err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if err := ContainerUnitTemplate.ExecuteTemplate(buf, "SIMPLE", unit); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	for _, line := range []string{
+		"TimeoutStartSec=330\n",
+		"TimeoutStopSec=100\n",
+		`ExecStartPre=/usr/bin/gear hook "test" pre-start --timeout=30 -- "/app/migrate"` + "\n",
+		`ExecStop=-/usr/bin/gear hook "test" pre-stop --timeout=10 --method=DELETE --url "http://registry/app"` + "\n",
+	} {
+		if !strings.Contains(s, line) {
+			t.Errorf("Expected %q in unit: %s", line, s)
+		}
+	}
+	if strings.Index(s, "pre-stop") > strings.Index(s, `ExecStop=-/usr/bin/docker stop`) {
+		t.Errorf("Expected the pre-stop hook to run before the container is stopped: %s", s)
+	}
+}
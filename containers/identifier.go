@@ -93,6 +93,22 @@ func (i Identifier) EnvironmentPathFor() string {
 	return utils.IsolateContentPath(filepath.Join(config.ContainerBasePath(), "env", "contents"), string(i), "")
 }
 
+func (i Identifier) SecretsPathFor() string {
+	return utils.IsolateContentPath(filepath.Join(config.ContainerBasePath(), "env", "secrets"), string(i), "")
+}
+
+func (i Identifier) EnvironmentSourcePathFor() string {
+	return utils.IsolateContentPath(filepath.Join(config.ContainerBasePath(), "env", "sources"), string(i), "")
+}
+
+func (i Identifier) EnvironmentVersionsPathFor() string {
+	return i.EnvironmentVersionPathFor("")
+}
+
+func (i Identifier) EnvironmentVersionPathFor(suffix string) string {
+	return utils.IsolateContentPath(filepath.Join(config.ContainerBasePath(), "env", "versions"), string(i), suffix)
+}
+
 func (i Identifier) NetworkLinksPathFor() string {
 	return utils.IsolateContentPath(filepath.Join(config.ContainerBasePath(), "ports", "links"), string(i), "")
 }
@@ -109,6 +125,12 @@ func (i Identifier) RunPathFor() string {
 	return utils.IsolateContentPathWithPerm(config.ContainerRunPath(), string(i), "/", 0775)
 }
 
+// The resolved environment of the container, including its secrets, on
+// the run tmpfs
+func (i Identifier) EnvironmentRunPathFor() string {
+	return filepath.Join(i.RunPathFor(), "env")
+}
+
 func (i Identifier) AuthKeysPathFor() string {
 	return filepath.Join(i.HomePath(), ".ssh", "authorized_keys")
 }
@@ -45,6 +45,7 @@ func verifyDataPaths() error {
 		filepath.Join(config.ContainerBasePath(), "home"),
 		filepath.Join(config.ContainerBasePath(), "git"),
 		filepath.Join(config.ContainerBasePath(), "units"),
+		filepath.Join(config.ContainerBasePath(), "volumes"),
 		filepath.Join(config.ContainerBasePath(), "access", "git"),
 		filepath.Join(config.ContainerBasePath(), "access", "containers", "ssh"),
 		filepath.Join(config.ContainerBasePath(), "keys", "public"),
@@ -59,7 +60,15 @@ func verifyDataPaths() error {
 	for _, path := range []string{
 		filepath.Join(config.ContainerBasePath(), "targets"),
 		filepath.Join(config.ContainerBasePath(), "slices"),
+		filepath.Join(config.ContainerBasePath(), "schedules"),
+		filepath.Join(config.ContainerBasePath(), "jobs"),
+		filepath.Join(config.ContainerBasePath(), "health"),
+		filepath.Join(config.ContainerBasePath(), "userns"),
+		filepath.Join(config.ContainerBasePath(), "profiles"),
 		filepath.Join(config.ContainerBasePath(), "env", "contents"),
+		filepath.Join(config.ContainerBasePath(), "env", "secrets"),
+		filepath.Join(config.ContainerBasePath(), "env", "versions"),
+		filepath.Join(config.ContainerBasePath(), "env", "sources"),
 		filepath.Join(config.ContainerBasePath(), "ports", "descriptions"),
 		filepath.Join(config.ContainerBasePath(), "ports", "interfaces"),
 	} {
@@ -90,15 +99,15 @@ func initializeTargets() error {
 
 func initializeSlices() error {
 	for _, name := range []string{
-		"container",
-		"container-small",
+		string(RootSlice),
+		string(DefaultSlice),
 	} {
-		parent := "container"
-		if name == "container" {
+		parent := string(RootSlice)
+		if name == string(RootSlice) {
 			parent = ""
 		}
 
-		if err := systemd.InitializeSystemdFile(systemd.SliceType, name, SliceUnitTemplate, SliceUnit{name, parent}, false); err != nil {
+		if err := systemd.InitializeSystemdFile(systemd.SliceType, name, SliceUnitTemplate, SliceUnit{Name: name, Parent: parent, MemoryLimit: "512M"}, false); err != nil {
 			return err
 		}
 	}
@@ -122,7 +131,7 @@ func checkPath(path string, mode os.FileMode, dir bool) error {
 
 func isSystemdFile(filePath string) bool {
 	extention := filepath.Ext(filePath)
-	systemdExts := []string{".slice", ".service", ".socket", ".target"}
+	systemdExts := []string{".slice", ".service", ".socket", ".target", ".timer"}
 	for _, e := range systemdExts {
 		if e == extention {
 			return true
@@ -136,6 +145,7 @@ func disableAllUnits() {
 
 	for _, path := range []string{
 		filepath.Join(config.ContainerBasePath(), "units"),
+		filepath.Join(config.ContainerBasePath(), "schedules"),
 		filepath.Join(config.ContainerBasePath(), "slices"),
 		filepath.Join(config.ContainerBasePath(), "targets"),
 	} {
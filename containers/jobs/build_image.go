@@ -9,6 +9,7 @@ import (
 	"github.com/openshift/geard/utils"
 	"github.com/openshift/go-systemd/dbus"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"reflect"
@@ -27,12 +28,22 @@ type ExtendedBuildImageData struct {
 	RuntimeImage string
 	Clean        bool
 	Verbose      bool
+
+	// Resume the output of an earlier build with the same name after
+	// the entry with this journal cursor, instead of starting a build
+	Cursor string `json:"Cursor,omitempty"`
+	// Write the output as JSON journal entries that carry their cursors,
+	// omitting the progress messages of the build
+	JsonLog bool `json:"JsonLog,omitempty"`
 }
 
 func (e *ExtendedBuildImageData) Check() error {
 	if e.Name == "" {
 		return errors.New("An identifier must be specified for this build")
 	}
+	if e.Cursor != "" {
+		return (&systemd.LogOptions{Cursor: e.Cursor}).Check()
+	}
 	if e.BaseImage == "" {
 		return errors.New("A base image is required to start a build")
 	}
@@ -49,30 +60,46 @@ const (
 
 func (j *BuildImageRequest) Execute(resp jobs.Response) {
 	w := resp.SuccessWithWrite(jobs.ResponseAccepted, true, false)
+	status := w
+	if j.JsonLog {
+		status = ioutil.Discard
+	}
 
-	fmt.Fprintf(w, "Processing build-image request:\n")
+	fmt.Fprintf(status, "Processing build-image request:\n")
 	// TODO: download source, add bind-mount
 
 	unitName := containers.JobIdentifier(j.Name).UnitNameForBuild()
 	unitDescription := fmt.Sprintf("Builder for %s", j.Tag)
 
-	stdout, err := systemd.ProcessLogsForUnit(unitName)
+	logs := &systemd.LogOptions{Since: "now", Follow: true, Json: j.JsonLog}
+	if j.Cursor != "" {
+		// the remaining output of a finished build is written without waiting
+		running, _ := systemd.IsUnitProperty(systemd.Connection(), unitName, func(p map[string]interface{}) bool {
+			return p["SubState"] == "running"
+		})
+		logs = &systemd.LogOptions{Cursor: j.Cursor, Follow: running, Json: j.JsonLog}
+	}
+	stdout, err := systemd.OpenLogsForUnit(unitName, logs)
 	if err != nil {
 		stdout = utils.EmptyReader
 		log.Printf("job_build_image: Unable to fetch build logs: %s, %+v", err.Error(), err)
 	}
 	defer stdout.Close()
+	if !logs.Follow {
+		io.Copy(w, stdout)
+		return
+	}
 
 	conn, errc := systemd.NewConnection()
 	if errc != nil {
 		log.Print("job_build_image:", errc)
-		fmt.Fprintf(w, "Unable to watch start status", errc)
+		fmt.Fprintf(status, "Unable to watch start status", errc)
 		return
 	}
 
 	if err := conn.Subscribe(); err != nil {
 		log.Print("job_build_image:", err)
-		fmt.Fprintf(w, "Unable to watch start status", errc)
+		fmt.Fprintf(status, "Unable to watch start status", errc)
 		return
 	}
 	defer conn.Unsubscribe()
@@ -86,6 +113,36 @@ func (j *BuildImageRequest) Execute(resp jobs.Response) {
 			return unit != unitName
 		})
 
+	if j.Cursor != "" {
+		log.Printf("build_image: Resuming output of build %s", unitName)
+	} else if !j.startBuild(status, unitName, unitDescription) {
+		return
+	}
+
+	go io.Copy(w, stdout)
+
+wait:
+	for {
+		select {
+		case c := <-changes:
+			if changed, ok := c[unitName]; ok {
+				if changed.SubState != "running" {
+					fmt.Fprintf(status, "Build completed\n")
+					break wait
+				}
+			}
+		case err := <-errch:
+			fmt.Fprintf(status, "Error %+v\n", err)
+		case <-time.After(25 * time.Second):
+			log.Print("job_build_image:", "timeout")
+			break wait
+		}
+	}
+
+	stdout.Close()
+}
+
+func (j *BuildImageRequest) startBuild(w io.Writer, unitName, unitDescription string) bool {
 	fmt.Fprintf(w, "Running sti build unit: %s\n", unitName)
 	log.Printf("build_image: Running build %s", unitName)
 
@@ -133,32 +190,11 @@ func (j *BuildImageRequest) Execute(resp jobs.Response) {
 	if err != nil {
 		errType := reflect.TypeOf(err)
 		fmt.Fprintf(w, "Unable to start build container for this image due to (%s): %s\n", errType, err.Error())
-		return
+		return false
 	} else if status != "done" {
 		fmt.Fprintf(w, "Build did not complete successfully: %s\n", status)
 	} else {
 		fmt.Fprintf(w, "Sti build is running\n")
 	}
-
-	go io.Copy(w, stdout)
-
-wait:
-	for {
-		select {
-		case c := <-changes:
-			if changed, ok := c[unitName]; ok {
-				if changed.SubState != "running" {
-					fmt.Fprintf(w, "Build completed\n")
-					break wait
-				}
-			}
-		case err := <-errch:
-			fmt.Fprintf(w, "Error %+v\n", err)
-		case <-time.After(25 * time.Second):
-			log.Print("job_build_image:", "timeout")
-			break wait
-		}
-	}
-
-	stdout.Close()
+	return true
 }
@@ -0,0 +1,180 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/docker"
+	"github.com/openshift/geard/jobs"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Return a tar stream of the file or directory at Path inside the
+// container.
+type GetContainerFilesRequest struct {
+	Id           containers.Identifier
+	Path         string
+	DockerSocket string `json:"-"`
+}
+
+func (j *GetContainerFilesRequest) Check() error {
+	return checkContainerFilePath(j.Path)
+}
+
+func (j *GetContainerFilesRequest) Execute(resp jobs.Response) {
+	if _, err := os.Stat(j.Id.UnitPathFor()); err != nil {
+		resp.Failure(ErrContainerNotFound)
+		return
+	}
+
+	root, path, _, err := containerFilesRoot(j.DockerSocket, j.Id, j.Path)
+	if err != nil {
+		resp.Failure(err)
+		return
+	}
+	source, err := containers.ResolvePathInRoot(root, path)
+	if err != nil {
+		log.Printf("container_files: Unable to resolve %s in %s: %v", j.Path, j.Id, err)
+		resp.Failure(ErrContainerFilesFailed)
+		return
+	}
+	if _, err := os.Lstat(source); err != nil {
+		resp.Failure(ErrContainerPathNotFound)
+		return
+	}
+
+	w := resp.SuccessWithWrite(jobs.ResponseOk, false, false)
+	if err := containers.WriteArchive(w, source, filepath.Base(filepath.Clean(j.Path))); err != nil {
+		log.Printf("container_files: Unable to archive %s in %s: %v", j.Path, j.Id, err)
+	}
+}
+
+// Extract a tar stream into the directory at Path inside the container.
+// Files written to an isolated container are owned by the container
+// user, except in a named volume owned by another container, where they
+// are owned by the owner of the volume.  Volumes mounted read only are
+// not written.
+type PutContainerFilesRequest struct {
+	Id           containers.Identifier
+	Path         string
+	Archive      io.Reader `json:"-"`
+	DockerSocket string    `json:"-"`
+}
+
+func (j *PutContainerFilesRequest) Check() error {
+	if j.Archive == nil {
+		return errors.New("An archive of the files to copy must be provided")
+	}
+	return checkContainerFilePath(j.Path)
+}
+
+func (j *PutContainerFilesRequest) Execute(resp jobs.Response) {
+	if _, err := os.Stat(j.Id.UnitPathFor()); err != nil {
+		resp.Failure(ErrContainerNotFound)
+		return
+	}
+
+	owner, err := containers.GetContainerFileOwner(j.Id)
+	if err != nil {
+		log.Printf("container_files: Unable to determine the owner of files in %s: %v", j.Id, err)
+		resp.Failure(ErrContainerFilesFailed)
+		return
+	}
+	root, path, mount, err := containerFilesRoot(j.DockerSocket, j.Id, j.Path)
+	if err != nil {
+		resp.Failure(err)
+		return
+	}
+	if mount != nil {
+		if mount.ReadOnly {
+			resp.Failure(ErrContainerFilesReadOnly)
+			return
+		}
+		// files in a volume given to another container keep the owner
+		// of the volume
+		volumeOwner, err := mount.Name.FileOwner()
+		if err != nil {
+			log.Printf("container_files: Unable to determine the owner of volume %s: %v", mount.Name, err)
+			resp.Failure(ErrContainerFilesFailed)
+			return
+		}
+		if volumeOwner.Uid != 0 && (owner == nil || owner.Uid != volumeOwner.Uid) {
+			owner = volumeOwner
+		}
+	}
+	if err := containers.ExtractArchive(j.Archive, root, path, owner); err != nil {
+		log.Printf("container_files: Unable to extract files to %s in %s: %v", j.Path, j.Id, err)
+		resp.Failure(ErrContainerFilesFailed)
+		return
+	}
+	resp.Success(jobs.ResponseOk)
+}
+
+func checkContainerFilePath(path string) error {
+	if !filepath.IsAbs(path) {
+		return errors.New(fmt.Sprintf("The path '%s' must be absolute", path))
+	}
+	return nil
+}
+
+// Find the host directory that holds path and the location of path
+// within it, and the named volume mount that holds it if any.  Named
+// volumes and the volumes of the data container are reachable while the
+// container is stopped, the rest of the container filesystem only while
+// it is running.
+func containerFilesRoot(dockerSocket string, id containers.Identifier, path string) (string, string, *containers.VolumeMount, error) {
+	path = filepath.Clean(path)
+
+	mounts, err := containers.GetContainerVolumes(id)
+	if err != nil {
+		log.Printf("container_files: Unable to read the volumes of %s: %v", id, err)
+		return "", "", nil, ErrContainerFilesFailed
+	}
+	for i := range mounts {
+		if rel, ok := pathWithin(mounts[i].Path, path); ok {
+			return mounts[i].Name.PathFor(), rel, &mounts[i], nil
+		}
+	}
+
+	client, err := docker.GetConnection(dockerSocket)
+	if err != nil {
+		log.Printf("container_files: Unable to connect to docker: %v", err)
+		return "", "", nil, ErrContainerFilesFailed
+	}
+
+	if container, err := client.GetContainer(id.ContainerFor(), false); err == nil && container.State.Running {
+		pid, err := client.ChildProcessForContainer(container)
+		if err != nil {
+			log.Printf("container_files: Unable to find the process of %s: %v", id, err)
+			return "", "", nil, ErrContainerFilesFailed
+		}
+		return filepath.Join("/proc", strconv.Itoa(pid), "root"), path, nil, nil
+	}
+
+	if data, err := client.GetContainer(id.ContainerFor()+"-data", false); err == nil {
+		for volume, hostPath := range data.Volumes {
+			if rel, ok := pathWithin(volume, path); ok {
+				return hostPath, rel, nil, nil
+			}
+		}
+	}
+	return "", "", nil, ErrContainerFilesNotAvailable
+}
+
+// Return the location of path relative to dir if path is dir or
+// is inside of it.
+func pathWithin(dir, path string) (string, bool) {
+	dir = filepath.Clean(dir)
+	if path == dir {
+		return "/", true
+	}
+	if strings.HasPrefix(path, strings.TrimSuffix(dir, "/")+"/") {
+		return "/" + strings.TrimPrefix(path, strings.TrimSuffix(dir, "/")+"/"), true
+	}
+	return "", false
+}
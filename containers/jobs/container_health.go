@@ -0,0 +1,68 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/jobs"
+	"os"
+	"time"
+)
+
+const (
+	// The default number of seconds to wait for a health result
+	DefaultHealthWait = 120
+	maxHealthWait     = 3600
+	healthWaitPoll    = time.Second
+)
+
+// Wait until the health checks of a container report a result recorded
+// after the request began.
+type WaitForHealthRequest struct {
+	Id containers.Identifier
+	// Seconds to wait before giving up, defaults to DefaultHealthWait
+	Timeout int `json:"Timeout,omitempty"`
+}
+
+func (j *WaitForHealthRequest) Check() error {
+	if j.Timeout < 0 || j.Timeout > maxHealthWait {
+		return errors.New(fmt.Sprintf("The health wait timeout must be between 0 and %d seconds", maxHealthWait))
+	}
+	return nil
+}
+
+func (j *WaitForHealthRequest) Execute(resp jobs.Response) {
+	if _, err := os.Stat(j.Id.UnitPathFor()); err != nil {
+		resp.Failure(ErrContainerNotFound)
+		return
+	}
+	if _, err := os.Stat(j.Id.HealthChecksPathFor()); err != nil {
+		resp.Failure(ErrNoHealthChecks)
+		return
+	}
+
+	timeout := j.Timeout
+	if timeout == 0 {
+		timeout = DefaultHealthWait
+	}
+	started := time.Now()
+	deadline := started.Add(time.Duration(timeout) * time.Second)
+	for {
+		if state, err := containers.ReadHealthState(j.Id); err == nil && !state.Updated.Before(started) {
+			switch state.Health {
+			case containers.HealthHealthy:
+				w := resp.SuccessWithWrite(jobs.ResponseOk, false, false)
+				fmt.Fprintf(w, "Container %s is healthy\n", j.Id)
+				return
+			case containers.HealthUnhealthy:
+				resp.Failure(ErrContainerUnhealthy)
+				return
+			}
+		}
+		if time.Now().After(deadline) {
+			resp.Failure(ErrHealthWaitTimeout)
+			return
+		}
+		time.Sleep(healthWaitPoll)
+	}
+}
@@ -1,6 +1,8 @@
 package jobs
 
 import (
+	"errors"
+	"fmt"
 	"github.com/openshift/geard/containers"
 	"github.com/openshift/geard/jobs"
 	"github.com/openshift/geard/systemd"
@@ -9,8 +11,33 @@ import (
 	"time"
 )
 
+// The seconds of recent entries written and then followed by a log
+// request that does not select entries itself.
+const DefaultLogSeconds = 30
+
 type ContainerLogRequest struct {
 	Id containers.Identifier
+	systemd.LogOptions
+	// The number of seconds to follow the log for, 0 to follow until
+	// the caller disconnects
+	Timeout int `json:"Timeout,omitempty"`
+	// Closed when the caller disconnects
+	Disconnected <-chan struct{} `json:"-"`
+}
+
+// Write the entries of the last DefaultLogSeconds and follow new
+// entries for as long.
+func (j *ContainerLogRequest) FollowRecent() {
+	j.Since = fmt.Sprintf("-%d", DefaultLogSeconds)
+	j.Follow = true
+	j.Timeout = DefaultLogSeconds
+}
+
+func (j *ContainerLogRequest) Check() error {
+	if j.Timeout < 0 {
+		return errors.New("The timeout must be zero or a positive number of seconds")
+	}
+	return j.LogOptions.Check()
 }
 
 func (j *ContainerLogRequest) Execute(resp jobs.Response) {
@@ -19,8 +46,28 @@ func (j *ContainerLogRequest) Execute(resp jobs.Response) {
 		return
 	}
 
+	var until chan time.Time
+	finished := make(chan struct{})
+	defer close(finished)
+	if j.Follow {
+		until = make(chan time.Time, 1)
+		var timeout <-chan time.Time
+		if j.Timeout > 0 {
+			timeout = time.After(time.Duration(j.Timeout) * time.Second)
+		}
+		go func() {
+			select {
+			case t := <-timeout:
+				until <- t
+			case <-j.Disconnected:
+				until <- time.Now()
+			case <-finished:
+			}
+		}()
+	}
+
 	w := resp.SuccessWithWrite(jobs.ResponseOk, true, false)
-	err := systemd.WriteLogsTo(w, j.Id.UnitNameFor(), 30, time.After(30*time.Second))
+	err := systemd.WriteLogsWithOptionsTo(w, j.Id.UnitNameFor(), &j.LogOptions, until)
 	if err != nil {
 		log.Printf("job_container_log: Unable to fetch journal logs: %s\n", err.Error())
 	}
@@ -0,0 +1,196 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/jobs"
+	"github.com/openshift/geard/systemd"
+	"github.com/openshift/go-systemd/dbus"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// Report the resource usage of a running container.  If Interval is set
+// a sample is streamed as a JSON object every Interval seconds until the
+// caller disconnects.
+type ContainerStatsRequest struct {
+	Id       containers.Identifier
+	Interval int `json:"Interval,omitempty"`
+	Label    string
+}
+
+func (j *ContainerStatsRequest) JobLabel() string {
+	return j.Label
+}
+
+func (j *ContainerStatsRequest) Check() error {
+	return checkStatsInterval(j.Interval)
+}
+
+func (j *ContainerStatsRequest) Execute(resp jobs.Response) {
+	if _, err := os.Stat(j.Id.UnitPathFor()); err != nil {
+		resp.Failure(ErrContainerNotFound)
+		return
+	}
+	writeStats(resp, j.Interval, func() (*ContainerStatsResponse, error) {
+		stats, err := statsForUnit(j.Id)
+		if err != nil {
+			return nil, err
+		}
+		return &ContainerStatsResponse{ContainerStatsResults{{*stats, ""}}}, nil
+	})
+}
+
+// Report the resource usage of every running container on the host.
+type ListContainerStatsRequest struct {
+	Interval int `json:"Interval,omitempty"`
+	Label    string
+}
+
+func (j *ListContainerStatsRequest) JobLabel() string {
+	return j.Label
+}
+
+func (j *ListContainerStatsRequest) Check() error {
+	return checkStatsInterval(j.Interval)
+}
+
+func (j *ListContainerStatsRequest) Execute(resp jobs.Response) {
+	writeStats(resp, j.Interval, func() (*ContainerStatsResponse, error) {
+		r := &ContainerStatsResponse{make(ContainerStatsResults, 0)}
+		err := unitsMatching(reContainerUnits, func(name string, unit *dbus.UnitStatus) {
+			if unit.ActiveState != "active" {
+				return
+			}
+			stats, err := statsForUnit(containers.Identifier(name))
+			if err != nil {
+				log.Printf("container_stats: Unable to read the usage of %s: %v", name, err)
+				return
+			}
+			r.Containers = append(r.Containers, ContainerStatsResult{*stats, ""})
+		})
+		if err != nil {
+			return nil, err
+		}
+		r.Sort()
+		return r, nil
+	})
+}
+
+type ContainerStatsResult struct {
+	containers.ContainerStats
+	// Used by consumers
+	Server string `json:"Server,omitempty"`
+}
+type ContainerStatsResults []ContainerStatsResult
+
+func (c ContainerStatsResults) Less(a, b int) bool {
+	return c[a].Id < c[b].Id
+}
+func (c ContainerStatsResults) Len() int {
+	return len(c)
+}
+func (c ContainerStatsResults) Swap(a, b int) {
+	c[a], c[b] = c[b], c[a]
+}
+
+type ContainerStatsResponse struct {
+	Containers ContainerStatsResults
+}
+
+func (r *ContainerStatsResponse) Append(other *ContainerStatsResponse) {
+	r.Containers = append(r.Containers, other.Containers...)
+}
+func (r *ContainerStatsResponse) Sort() {
+	sort.Sort(r.Containers)
+}
+
+func (r *ContainerStatsResponse) WriteTableTo(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 8, 4, 1, ' ', tabwriter.DiscardEmptyColumns)
+	if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", "ID", "SERVER", "CPU", "MEMORY", "LIMIT", "TASKS", "READ", "WRITE"); err != nil {
+		return err
+	}
+	for i := range r.Containers {
+		s := &r.Containers[i]
+		limit := "-"
+		if s.MemoryLimit != 0 {
+			limit = formatBytes(s.MemoryLimit)
+		}
+		cpu := time.Duration(s.CPUTime) / time.Millisecond * time.Millisecond
+		if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\n", s.Id, s.Server, cpu, formatBytes(s.MemoryUsage), limit, s.Tasks, formatBytes(s.BlockRead), formatBytes(s.BlockWrite)); err != nil {
+			return err
+		}
+	}
+	tw.Flush()
+	return nil
+}
+
+func formatBytes(b uint64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	value := float64(b)
+	i := 0
+	for value >= 1024 && i < len(units)-1 {
+		value /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%d%s", b, units[0])
+	}
+	return fmt.Sprintf("%.1f%s", value, units[i])
+}
+
+func checkStatsInterval(interval int) error {
+	if interval < 0 {
+		return errors.New("The interval must be zero or a positive number of seconds")
+	}
+	return nil
+}
+
+func statsForUnit(id containers.Identifier) (*containers.ContainerStats, error) {
+	props, err := systemd.Connection().GetUnitProperties(id.UnitNameFor())
+	if err != nil {
+		return nil, err
+	}
+	cgroup, ok := props["ControlGroup"].(string)
+	if !ok || cgroup == "" {
+		return nil, ErrContainerNotRunning
+	}
+	return containers.ReadCgroupStats(id, cgroup)
+}
+
+// Write a single sample as data, or stream samples at an interval.
+func writeStats(resp jobs.Response, interval int, sample func() (*ContainerStatsResponse, error)) {
+	r, err := sample()
+	if err != nil {
+		if err == ErrContainerNotRunning {
+			resp.Failure(ErrContainerNotRunning)
+			return
+		}
+		log.Printf("container_stats: Unable to read container usage: %v", err)
+		resp.Failure(ErrContainerStatsFailed)
+		return
+	}
+	if interval == 0 {
+		resp.SuccessWithData(jobs.ResponseOk, r)
+		return
+	}
+
+	w := resp.SuccessWithWrite(jobs.ResponseAccepted, true, false)
+	encoder := json.NewEncoder(w)
+	for {
+		if err := encoder.Encode(r); err != nil {
+			return
+		}
+		time.Sleep(time.Duration(interval) * time.Second)
+		if r, err = sample(); err != nil {
+			log.Printf("container_stats: Unable to read container usage: %v", err)
+			return
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package jobs
 
 import (
+	"fmt"
 	"github.com/openshift/geard/containers"
 	"github.com/openshift/geard/jobs"
 	"github.com/openshift/geard/systemd"
@@ -24,4 +25,11 @@ func (j *ContainerStatusRequest) Execute(resp jobs.Response) {
 	if err != nil {
 		log.Printf("container_status: Unable to fetch container status logs: %s\n", err.Error())
 	}
+
+	if state, err := containers.ReadHealthState(j.Id); err == nil {
+		fmt.Fprintf(w, "\nHealth: %s (as of %s)\n", state.Health, state.Updated.Format("2006-01-02 15:04:05"))
+		if state.Message != "" {
+			fmt.Fprintf(w, "  %s\n", state.Message)
+		}
+	}
 }
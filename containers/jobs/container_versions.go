@@ -0,0 +1,165 @@
+package jobs
+
+import (
+	"fmt"
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/jobs"
+	"github.com/openshift/geard/port"
+	"github.com/openshift/geard/systemd"
+	"github.com/openshift/geard/utils"
+	"io"
+	"log"
+	"os"
+	"text/tabwriter"
+)
+
+type ContainerVersionsRequest struct {
+	Id    containers.Identifier
+	Label string
+}
+
+func (j *ContainerVersionsRequest) JobLabel() string {
+	return j.Label
+}
+
+type ContainerVersionsResponse struct {
+	Versions containers.ContainerVersions
+}
+
+func (r *ContainerVersionsResponse) WriteTableTo(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 8, 4, 1, ' ', tabwriter.DiscardEmptyColumns)
+	if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", "", "VERSION", "CREATED", "IMAGE", "PORTS"); err != nil {
+		return err
+	}
+	for i := range r.Versions {
+		version := &r.Versions[i]
+		current := ""
+		if version.Current {
+			current = "*"
+		}
+		if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", current, version.Id, version.Created.Format("2006-01-02 15:04:05"), version.Image, version.Ports.ToHeader()); err != nil {
+			return err
+		}
+	}
+	tw.Flush()
+	return nil
+}
+
+func (j *ContainerVersionsRequest) Execute(resp jobs.Response) {
+	versions, err := containers.ListVersions(j.Id)
+	if err != nil {
+		if os.IsNotExist(err) {
+			resp.Failure(ErrContainerNotFound)
+			return
+		}
+		log.Printf("container_versions: Unable to list versions of %s: %v", j.Id, err)
+		resp.Failure(ErrListVersionsFailed)
+		return
+	}
+	resp.SuccessWithData(jobs.ResponseOk, &ContainerVersionsResponse{versions})
+}
+
+// Re-activate a previously installed unit definition for a container.
+type RollbackContainerRequest struct {
+	Id containers.Identifier `json:"-"`
+	// The version to activate, defaults to the version installed
+	// before the current one
+	Version string `json:"Version,omitempty"`
+}
+
+func (j *RollbackContainerRequest) Execute(resp jobs.Response) {
+	id := j.Id
+	unitName := id.UnitNameFor()
+	unitPath := id.UnitPathFor()
+
+	// open and lock the base path (to prevent simultaneous updates)
+	state, exists, err := utils.OpenFileExclusive(unitPath, 0664)
+	if err != nil {
+		log.Print("rollback_container: Unable to lock unit file: ", err)
+		resp.Failure(ErrRollbackFailed)
+		return
+	}
+	defer state.Close()
+	if !exists {
+		os.Remove(unitPath)
+		resp.Failure(ErrContainerNotFound)
+		return
+	}
+
+	versions, err := containers.ListVersions(id)
+	if err != nil {
+		log.Printf("rollback_container: Unable to list versions of %s: %v", id, err)
+		resp.Failure(ErrRollbackFailed)
+		return
+	}
+
+	var target *containers.ContainerVersion
+	var found bool
+	if j.Version == "" {
+		target, found = versions.Previous()
+	} else {
+		target, found = versions.Find(j.Version)
+	}
+	if !found {
+		resp.Failure(ErrVersionNotFound)
+		return
+	}
+	if target.Current {
+		w := resp.SuccessWithWrite(jobs.ResponseOk, true, false)
+		fmt.Fprintf(w, "Version %s of %s is already active\n", target.Id, id)
+		return
+	}
+	targetPath := id.VersionedUnitPathFor(target.Id)
+
+	// reserve the ports of the old definition, releasing any the current one no longer needs
+	existingPorts, err := containers.GetExistingPorts(id)
+	if err != nil {
+		existingPorts = port.PortPairs{}
+	}
+	reserved, erra := port.AtomicReserveExternalPorts(targetPath, target.Ports, existingPorts)
+	if erra != nil {
+		log.Printf("rollback_container: Unable to reserve external ports: %+v", erra)
+		resp.Failure(ErrRollbackPortsReserved)
+		return
+	}
+	if len(reserved) > 0 {
+		resp.WritePendingSuccess(PendingPortMappingName, reserved)
+	}
+
+	if err := utils.AtomicReplaceLink(targetPath, unitPath); err != nil {
+		log.Printf("rollback_container: Failed to activate unit version %s: %+v", target.Id, err)
+		resp.Failure(ErrRollbackFailed)
+		return
+	}
+	state.Close()
+
+	paths := []string{unitPath}
+	if target.SocketActivated() {
+		socketUnitPath := id.SocketUnitPathFor()
+		if err := writeSocketUnit(socketUnitPath, &containers.ContainerUnit{Id: id, PortPairs: reserved}); err == nil {
+			paths = append(paths, socketUnitPath)
+		}
+	}
+
+	if err := systemd.EnableAndReloadUnit(systemd.Connection(), unitName, paths...); err != nil {
+		log.Printf("rollback_container: Could not enable container %s (%v): %v", unitName, paths, err)
+		resp.Failure(ErrRollbackFailed)
+		return
+	}
+
+	w := resp.SuccessWithWrite(jobs.ResponseAccepted, true, false)
+	fmt.Fprintf(w, "Container %s rolled back to version %s (%s)\n", id, target.Id, target.Image)
+
+	// only restart a container that is running, a stopped container picks up the change on start
+	active, _ := systemd.IsUnitProperty(systemd.Connection(), unitName, func(p map[string]interface{}) bool {
+		return p["ActiveState"] == "active" || p["ActiveState"] == "activating"
+	})
+	if active {
+		if err := systemd.Connection().RestartUnitJob(unitName, "replace"); err != nil {
+			log.Printf("rollback_container: Could not restart container %s: %v", unitName, err)
+			fmt.Fprintf(w, "The container could not be restarted, restart it to use this version\n")
+			return
+		}
+		fmt.Fprintf(w, "Container %s restart enqueued\n", id)
+	}
+}
@@ -3,18 +3,29 @@ package jobs
 import (
 	"fmt"
 	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/docker"
 	"github.com/openshift/geard/jobs"
 	"io"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 )
 
 const ContentTypeEnvironment = "env"
+const ContentTypeSecrets = "secrets"
+const ContentTypeImage = "image"
 
 type ContentRequest struct {
 	Type    string
 	Locator string
 	Subpath string
+
+	// The caller holds a content token for this type and locator,
+	// required to read secret values
+	Authorized bool `json:"-"`
+
+	DockerSocket string `json:"-"`
 }
 
 func (j *ContentRequest) Fast() bool {
@@ -35,11 +46,56 @@ func (j *ContentRequest) Execute(resp jobs.Response) {
 			return
 		}
 		defer file.Close()
+		names, errn := containers.ReadSecretNames(id)
+		if errn != nil {
+			log.Printf("job_content: Unable to read secret environment file: %+v", errn)
+		}
 		w := resp.SuccessWithWrite(jobs.ResponseOk, false, false)
 		if _, err := io.Copy(w, file); err != nil {
 			log.Printf("job_content: Unable to write environment file: %+v", err)
 			return
 		}
+		for i := range names {
+			fmt.Fprintf(w, "%s=%s\n", names[i], strconv.Quote(containers.RedactedSecretValue))
+		}
+	case ContentTypeSecrets:
+		if !j.Authorized {
+			resp.Failure(ErrSecretsNotAuthorized)
+			return
+		}
+		id, errr := containers.NewIdentifier(j.Locator)
+		if errr != nil {
+			resp.Failure(jobs.SimpleError{jobs.ResponseInvalidRequest, fmt.Sprintf("Invalid environment identifier: %s", errr.Error())})
+			return
+		}
+		if _, err := os.Stat(id.EnvironmentPathFor()); err != nil {
+			resp.Failure(ErrEnvironmentNotFound)
+			return
+		}
+		secrets, errs := containers.ReadSecrets(id)
+		if errs != nil {
+			log.Printf("job_content: Unable to decrypt secrets of %s: %+v", id, errs)
+			resp.Failure(ErrSecretsReadFailed)
+			return
+		}
+		w := resp.SuccessWithWrite(jobs.ResponseOk, false, false)
+		for i := range secrets {
+			fmt.Fprintf(w, "%s=%s\n", secrets[i].Name, strconv.Quote(secrets[i].Value))
+		}
+	case ContentTypeImage:
+		if j.Locator == "" || strings.HasPrefix(j.Locator, "-") {
+			resp.Failure(jobs.SimpleError{jobs.ResponseInvalidRequest, "Invalid image name"})
+			return
+		}
+		// a missing image fails before any of the archive is written
+		w := &deferredWriter{resp: resp, success: jobs.ResponseOk}
+		if err := docker.SaveImage(j.DockerSocket, j.Locator, w); err != nil {
+			log.Printf("job_content: Unable to export image %s: %v", j.Locator, err)
+			if w.w == nil {
+				resp.Failure(ErrImageExportFailed)
+			}
+			return
+		}
 	}
 }
 
@@ -28,6 +28,7 @@ func (j *DeleteContainerRequest) Execute(resp jobs.Response) {
 	homeDirPath := j.Id.BaseHomePath()
 	runDirPath := j.Id.RunPathFor()
 	networkLinksPath := j.Id.NetworkLinksPathFor()
+	healthChecksPath := j.Id.HealthChecksPathFor()
 
 	_, err := systemd.Connection().GetUnitProperties(unitName)
 	switch {
@@ -55,6 +56,10 @@ func (j *DeleteContainerRequest) Execute(resp jobs.Response) {
 		log.Printf("delete_container: Unable to release ports: %v", err)
 	}
 
+	if err := containers.ReleaseUserNamespace(j.Id); err != nil {
+		log.Printf("delete_container: Unable to release user namespace: %v", err)
+	}
+
 	if err := os.Remove(unitPath); err != nil && !os.IsNotExist(err) {
 		resp.Failure(ErrDeleteContainerFailed)
 		return
@@ -77,6 +82,10 @@ func (j *DeleteContainerRequest) Execute(resp jobs.Response) {
 		log.Printf("delete_container: Unable to remove network links file: %v", err)
 	}
 
+	if err := os.Remove(healthChecksPath); err != nil && !os.IsNotExist(err) {
+		log.Printf("delete_container: Unable to remove health checks file: %v", err)
+	}
+
 	if err := os.RemoveAll(unitDefinitionsPath); err != nil {
 		log.Printf("delete_container: Unable to remove definitions for container: %v", err)
 	}
@@ -1,35 +1,190 @@
 package jobs
 
 import (
+	"fmt"
 	"github.com/openshift/geard/containers"
 	"github.com/openshift/geard/jobs"
+	"github.com/openshift/geard/systemd"
+	"github.com/openshift/geard/utils"
+	"io"
+	"log"
+	"os"
+	"text/tabwriter"
 )
 
+// The largest environment file loaded from a source
+const environmentSourceLimit = 100 * 1024
+
 type PutEnvironmentRequest struct {
 	containers.EnvironmentDescription
+	// Restart the running containers that use the environment
+	Restart bool `json:"-"`
 }
 
 func (j *PutEnvironmentRequest) Execute(resp jobs.Response) {
-	if err := j.Fetch(100 * 1024); err != nil {
+	if err := j.Fetch(environmentSourceLimit); err != nil {
 		resp.Failure(ErrEnvironmentUpdateFailed)
 		return
 	}
 	if err := j.Write(false); err != nil {
+		if err == utils.ErrLockTaken {
+			resp.Failure(ErrEnvironmentLocked)
+			return
+		}
 		resp.Failure(ErrEnvironmentUpdateFailed)
 		return
 	}
+	if j.Restart {
+		restartContainersUsingEnvironment(j.Id, resp.SuccessWithWrite(jobs.ResponseAccepted, true, false))
+		return
+	}
 
 	resp.Success(jobs.ResponseOk)
 }
 
 type PatchEnvironmentRequest struct {
 	containers.EnvironmentDescription
+	// Restart the running containers that use the environment
+	Restart bool `json:"-"`
 }
 
 func (j *PatchEnvironmentRequest) Execute(resp jobs.Response) {
 	if err := j.Write(true); err != nil {
+		if err == utils.ErrLockTaken {
+			resp.Failure(ErrEnvironmentLocked)
+			return
+		}
 		resp.Failure(ErrEnvironmentUpdateFailed)
 		return
 	}
+	if j.Restart {
+		restartContainersUsingEnvironment(j.Id, resp.SuccessWithWrite(jobs.ResponseAccepted, true, false))
+		return
+	}
 	resp.Success(jobs.ResponseOk)
 }
+
+type EnvironmentVersionsRequest struct {
+	Id    containers.Identifier
+	Label string
+}
+
+func (j *EnvironmentVersionsRequest) JobLabel() string {
+	return j.Label
+}
+
+type EnvironmentVersionsResponse struct {
+	Versions containers.EnvironmentVersions
+}
+
+func (r *EnvironmentVersionsResponse) WriteTableTo(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 8, 4, 1, ' ', tabwriter.DiscardEmptyColumns)
+	if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", "", "VERSION", "CREATED", "CHANGES"); err != nil {
+		return err
+	}
+	for i := range r.Versions {
+		version := &r.Versions[i]
+		current := ""
+		if i == 0 {
+			current = "*"
+		}
+		if _, err := fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", current, version.Version, version.Created.Format("2006-01-02 15:04:05"), version.Changes.String()); err != nil {
+			return err
+		}
+	}
+	tw.Flush()
+	return nil
+}
+
+func (j *EnvironmentVersionsRequest) Execute(resp jobs.Response) {
+	versions, err := containers.ListEnvironmentVersions(j.Id)
+	if err != nil {
+		if os.IsNotExist(err) {
+			resp.Failure(ErrEnvironmentNotFound)
+			return
+		}
+		log.Printf("environment_versions: Unable to list versions of %s: %v", j.Id, err)
+		resp.Failure(ErrEnvironmentVersionsFailed)
+		return
+	}
+	resp.SuccessWithData(jobs.ResponseOk, &EnvironmentVersionsResponse{versions})
+}
+
+// Restore the variables and secrets of an earlier version of an
+// environment as its newest version.
+type RollbackEnvironmentRequest struct {
+	Id containers.Identifier `json:"-"`
+	// The version to restore, defaults to the version written before
+	// the current one
+	Version int `json:"Version,omitempty"`
+	// Restart the running containers that use the environment
+	Restart bool `json:"Restart,omitempty"`
+}
+
+func (j *RollbackEnvironmentRequest) Execute(resp jobs.Response) {
+	versions, err := containers.ListEnvironmentVersions(j.Id)
+	if err != nil {
+		if os.IsNotExist(err) {
+			resp.Failure(ErrEnvironmentNotFound)
+			return
+		}
+		log.Printf("rollback_environment: Unable to list versions of %s: %v", j.Id, err)
+		resp.Failure(ErrEnvironmentRollbackFailed)
+		return
+	}
+
+	var target *containers.EnvironmentVersion
+	var found bool
+	if j.Version == 0 {
+		target, found = versions.Previous()
+	} else {
+		target, found = versions.Find(j.Version)
+	}
+	if !found {
+		resp.Failure(ErrEnvironmentVersionNotFound)
+		return
+	}
+
+	version, err := containers.RestoreEnvironmentVersion(j.Id, target.Version)
+	if err == utils.ErrLockTaken {
+		resp.Failure(ErrEnvironmentLocked)
+		return
+	}
+	if err != nil {
+		log.Printf("rollback_environment: Unable to restore version %d of %s: %v", target.Version, j.Id, err)
+		resp.Failure(ErrEnvironmentRollbackFailed)
+		return
+	}
+
+	w := resp.SuccessWithWrite(jobs.ResponseAccepted, true, false)
+	fmt.Fprintf(w, "Environment %s rolled back to version %d as version %d\n", j.Id, target.Version, version)
+	if j.Restart {
+		restartContainersUsingEnvironment(j.Id, w)
+	}
+}
+
+// Only running containers are restarted, a stopped container reads the
+// environment when it starts.
+func restartContainersUsingEnvironment(id containers.Identifier, w io.Writer) {
+	ids, err := containers.ContainersUsingEnvironment(id)
+	if err != nil {
+		log.Printf("environment: Unable to find the containers using %s: %v", id, err)
+		fmt.Fprintf(w, "Unable to find the containers that use environment %s, restart them to use the change\n", id)
+		return
+	}
+	for i := range ids {
+		unitName := ids[i].UnitNameFor()
+		active, _ := systemd.IsUnitProperty(systemd.Connection(), unitName, func(p map[string]interface{}) bool {
+			return p["ActiveState"] == "active" || p["ActiveState"] == "activating"
+		})
+		if !active {
+			continue
+		}
+		if err := systemd.Connection().RestartUnitJob(unitName, "replace"); err != nil {
+			log.Printf("environment: Could not restart container %s: %v", unitName, err)
+			fmt.Fprintf(w, "Container %s could not be restarted\n", ids[i])
+			continue
+		}
+		fmt.Fprintf(w, "Container %s restart enqueued\n", ids[i])
+	}
+}
@@ -0,0 +1,78 @@
+package jobs
+
+import (
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/utils"
+	"io/ioutil"
+	"log"
+	"time"
+)
+
+// How often the stored environment sources are checked for a due refresh
+const environmentRefreshInterval = 10 * time.Second
+
+// Periodically fetch the sources of environments that have a refresh
+// interval, and write a new version of each environment whose source
+// content changed.
+func StartEnvironmentRefresh() {
+	go func() {
+		fetched := make(map[containers.Identifier]time.Time)
+		for {
+			refreshEnvironmentSources(fetched, time.Now())
+			time.Sleep(environmentRefreshInterval)
+		}
+	}()
+}
+
+func refreshEnvironmentSources(fetched map[containers.Identifier]time.Time, now time.Time) {
+	ids, err := containers.ListEnvironmentSources()
+	if err != nil {
+		log.Printf("environment_refresh: Unable to list environment sources: %v", err)
+		return
+	}
+
+	found := make(map[containers.Identifier]bool)
+	for _, id := range ids {
+		found[id] = true
+		record, err := containers.ReadEnvironmentSource(id)
+		if err != nil {
+			log.Printf("environment_refresh: Unable to read the source of %s: %v", id, err)
+			continue
+		}
+		interval := time.Duration(record.Options.RefreshInterval) * time.Second
+		if last, ok := fetched[id]; ok && now.Sub(last) < interval {
+			continue
+		}
+		fetched[id] = now
+		refreshEnvironment(record)
+	}
+	for id := range fetched {
+		if !found[id] {
+			delete(fetched, id)
+		}
+	}
+}
+
+func refreshEnvironment(record *containers.EnvironmentSourceRecord) {
+	options := record.Options
+	env := &containers.EnvironmentDescription{Id: record.Id, Source: record.Source, SourceOptions: &options}
+	if err := env.Fetch(environmentSourceLimit); err != nil {
+		log.Printf("environment_refresh: Unable to fetch the source of %s: %v", record.Id, err)
+		return
+	}
+	if env.SourceDigest() == record.Digest {
+		return
+	}
+	if err := env.WriteRefreshed(record.Names); err == utils.ErrLockTaken {
+		// the source is fetched again on the next interval
+		log.Printf("environment_refresh: %s is being updated, the refresh is skipped", record.Id)
+		return
+	} else if err != nil {
+		log.Printf("environment_refresh: Unable to update %s: %v", record.Id, err)
+		return
+	}
+	log.Printf("environment_refresh: The source of %s changed, a new version was recorded", record.Id)
+	if options.RestartOnChange {
+		restartContainersUsingEnvironment(record.Id, ioutil.Discard)
+	}
+}
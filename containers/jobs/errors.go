@@ -5,19 +5,73 @@ import (
 )
 
 var (
-	ErrContainerNotFound       = jobs.SimpleError{jobs.ResponseNotFound, "The specified container does not exist."}
-	ErrContainerAlreadyExists  = jobs.SimpleError{jobs.ResponseAlreadyExists, "A container with this identifier already exists."}
-	ErrContainerCreateFailed   = jobs.SimpleError{jobs.ResponseError, "Unable to create container."}
-	ErrContainerStartFailed    = jobs.SimpleError{jobs.ResponseError, "Unable to start this container."}
-	ErrContainerStopFailed     = jobs.SimpleError{jobs.ResponseError, "Unable to stop this container."}
-	ErrContainerRestartFailed  = jobs.SimpleError{jobs.ResponseError, "Unable to restart this container."}
-	ErrEnvironmentNotFound     = jobs.SimpleError{jobs.ResponseNotFound, "Unable to find the requested environment."}
-	ErrEnvironmentUpdateFailed = jobs.SimpleError{jobs.ResponseError, "Unable to update the specified environment."}
-	ErrListImagesFailed        = jobs.SimpleError{jobs.ResponseError, "Unable to list docker images."}
-	ErrListContainersFailed    = jobs.SimpleError{jobs.ResponseError, "Unable to list the installed containers."}
-	ErrStartRequestThrottled   = jobs.SimpleError{jobs.ResponseRateLimit, "It has been too soon since the last request to start."}
-	ErrStopRequestThrottled    = jobs.SimpleError{jobs.ResponseRateLimit, "It has been too soon since the last request to stop."}
-	ErrRestartRequestThrottled = jobs.SimpleError{jobs.ResponseRateLimit, "It has been too soon since the last request to restart or the state is currently changing."}
-	ErrLinkContainersFailed    = jobs.SimpleError{jobs.ResponseError, "Not all links could be set."}
-	ErrDeleteContainerFailed   = jobs.SimpleError{jobs.ResponseError, "Unable to delete the container."}
+	ErrContainerNotFound          = jobs.SimpleError{jobs.ResponseNotFound, "The specified container does not exist."}
+	ErrContainerAlreadyExists     = jobs.SimpleError{jobs.ResponseAlreadyExists, "A container with this identifier already exists."}
+	ErrContainerCreateFailed      = jobs.SimpleError{jobs.ResponseError, "Unable to create container."}
+	ErrContainerStartFailed       = jobs.SimpleError{jobs.ResponseError, "Unable to start this container."}
+	ErrContainerStopFailed        = jobs.SimpleError{jobs.ResponseError, "Unable to stop this container."}
+	ErrContainerRestartFailed     = jobs.SimpleError{jobs.ResponseError, "Unable to restart this container."}
+	ErrEnvironmentNotFound        = jobs.SimpleError{jobs.ResponseNotFound, "Unable to find the requested environment."}
+	ErrEnvironmentUpdateFailed    = jobs.SimpleError{jobs.ResponseError, "Unable to update the specified environment."}
+	ErrEnvironmentLocked          = jobs.SimpleError{jobs.ResponseRateLimit, "Another update of this environment is in progress, try again."}
+	ErrEnvironmentVersionNotFound = jobs.SimpleError{jobs.ResponseNotFound, "The requested version of this environment does not exist."}
+	ErrEnvironmentVersionsFailed  = jobs.SimpleError{jobs.ResponseError, "Unable to list the versions of this environment."}
+	ErrEnvironmentRollbackFailed  = jobs.SimpleError{jobs.ResponseError, "Unable to roll back this environment."}
+	ErrSecretsNotAuthorized       = jobs.SimpleError{jobs.ResponseNotAuthorized, "Reading secret environment values requires a content token of type 'secrets'."}
+	ErrSecretsReadFailed          = jobs.SimpleError{jobs.ResponseError, "Unable to decrypt the secrets of the specified environment."}
+	ErrListImagesFailed           = jobs.SimpleError{jobs.ResponseError, "Unable to list docker images."}
+	ErrImagePullFailed            = jobs.SimpleError{jobs.ResponseError, "Unable to pull the image."}
+	ErrImageExportFailed          = jobs.SimpleError{jobs.ResponseNotFound, "Unable to export the image, it may not exist on this host."}
+	ErrImageImportFailed          = jobs.SimpleError{jobs.ResponseError, "Unable to import the image."}
+	ErrListContainersFailed       = jobs.SimpleError{jobs.ResponseError, "Unable to list the installed containers."}
+	ErrStartRequestThrottled      = jobs.SimpleError{jobs.ResponseRateLimit, "It has been too soon since the last request to start."}
+	ErrStopRequestThrottled       = jobs.SimpleError{jobs.ResponseRateLimit, "It has been too soon since the last request to stop."}
+	ErrRestartRequestThrottled    = jobs.SimpleError{jobs.ResponseRateLimit, "It has been too soon since the last request to restart or the state is currently changing."}
+	ErrLinkContainersFailed       = jobs.SimpleError{jobs.ResponseError, "Not all links could be set."}
+	ErrDeleteContainerFailed      = jobs.SimpleError{jobs.ResponseError, "Unable to delete the container."}
+	ErrSliceNotFound              = jobs.SimpleError{jobs.ResponseNotFound, "The specified slice does not exist."}
+	ErrSliceParentNotFound        = jobs.SimpleError{jobs.ResponseInvalidRequest, "The parent of this slice does not exist."}
+	ErrSliceUpdateFailed          = jobs.SimpleError{jobs.ResponseError, "Unable to update the specified slice."}
+	ErrSliceDeleteFailed          = jobs.SimpleError{jobs.ResponseError, "Unable to delete the specified slice."}
+	ErrSliceBuiltin               = jobs.SimpleError{jobs.ResponseInvalidRequest, "The default slices may not be deleted."}
+	ErrSliceInUse                 = jobs.SimpleError{jobs.ResponseInvalidRequest, "The slice is still in use by containers or other slices."}
+	ErrListSlicesFailed           = jobs.SimpleError{jobs.ResponseError, "Unable to list the slices on this host."}
+	ErrContainerSliceFailed       = jobs.SimpleError{jobs.ResponseError, "Unable to change the slice of this container."}
+	ErrListVersionsFailed         = jobs.SimpleError{jobs.ResponseError, "Unable to list the versions of this container."}
+	ErrVersionNotFound            = jobs.SimpleError{jobs.ResponseNotFound, "The requested version of this container does not exist."}
+	ErrRollbackFailed             = jobs.SimpleError{jobs.ResponseError, "Unable to roll back this container."}
+	ErrRollbackPortsReserved      = jobs.SimpleError{jobs.ResponseError, "Unable to roll back this container: some ports could not be reserved."}
+	ErrVolumeNotFound             = jobs.SimpleError{jobs.ResponseNotFound, "The specified volume does not exist."}
+	ErrVolumeCreateFailed         = jobs.SimpleError{jobs.ResponseError, "Unable to create the specified volume."}
+	ErrVolumeDeleteFailed         = jobs.SimpleError{jobs.ResponseError, "Unable to delete the specified volume."}
+	ErrVolumeInUse                = jobs.SimpleError{jobs.ResponseInvalidRequest, "The volume is mounted by one or more containers."}
+	ErrListVolumesFailed          = jobs.SimpleError{jobs.ResponseError, "Unable to list the volumes on this host."}
+	ErrContainerNotRunning        = jobs.SimpleError{jobs.ResponseInvalidRequest, "The container must be running to execute a command in it."}
+	ErrExecFailed                 = jobs.SimpleError{jobs.ResponseError, "Unable to execute the command in this container."}
+	ErrContainerFilesFailed       = jobs.SimpleError{jobs.ResponseError, "Unable to copy files for this container."}
+	ErrContainerPathNotFound      = jobs.SimpleError{jobs.ResponseNotFound, "The requested path does not exist in the container."}
+	ErrNoHealthChecks             = jobs.SimpleError{jobs.ResponseInvalidRequest, "The container has no health checks."}
+	ErrContainerUnhealthy         = jobs.SimpleError{jobs.ResponseError, "The health checks of the container are failing."}
+	ErrHealthWaitTimeout          = jobs.SimpleError{jobs.ResponseError, "The health checks of the container did not report a result in time."}
+	ErrContainerStatsFailed       = jobs.SimpleError{jobs.ResponseError, "Unable to read the resource usage of the container."}
+	ErrContainerFilesNotAvailable = jobs.SimpleError{jobs.ResponseInvalidRequest, "The container must be running to access files outside of its volumes."}
+	ErrContainerFilesReadOnly     = jobs.SimpleError{jobs.ResponseInvalidRequest, "The path is in a volume the container mounts read only."}
+	ErrScheduleNotFound           = jobs.SimpleError{jobs.ResponseNotFound, "The specified scheduled job does not exist."}
+	ErrScheduleUpdateFailed       = jobs.SimpleError{jobs.ResponseError, "Unable to update the specified scheduled job."}
+	ErrScheduleDeleteFailed       = jobs.SimpleError{jobs.ResponseError, "Unable to delete the specified scheduled job."}
+	ErrScheduleRunFailed          = jobs.SimpleError{jobs.ResponseError, "Unable to start a run of the scheduled job."}
+	ErrListSchedulesFailed        = jobs.SimpleError{jobs.ResponseError, "Unable to list the scheduled jobs on this host."}
+	ErrRunCreateFailed            = jobs.SimpleError{jobs.ResponseError, "Unable to record the container execution."}
+	ErrRunNotFound                = jobs.SimpleError{jobs.ResponseNotFound, "No container execution with this identifier exists."}
+	ErrRunResultFailed            = jobs.SimpleError{jobs.ResponseError, "Unable to read the result of the container execution."}
+	ErrListScheduleRunsFailed     = jobs.SimpleError{jobs.ResponseError, "Unable to list the runs of the scheduled job."}
+	ErrContainerExportFailed      = jobs.SimpleError{jobs.ResponseError, "Unable to export the container."}
+	ErrContainerExportSecrets     = jobs.SimpleError{jobs.ResponseInvalidRequest, "The environment of the container has secret variables, which are encrypted for this host and cannot be moved. Remove the secrets and set them again on the new host."}
+	ErrContainerImportFailed      = jobs.SimpleError{jobs.ResponseError, "Unable to import the container."}
+	ErrProfileNotFound            = jobs.SimpleError{jobs.ResponseNotFound, "The specified security profile does not exist."}
+	ErrProfileUpdateFailed        = jobs.SimpleError{jobs.ResponseError, "Unable to update the specified security profile."}
+	ErrProfileDeleteFailed        = jobs.SimpleError{jobs.ResponseError, "Unable to delete the specified security profile."}
+	ErrProfileInUse               = jobs.SimpleError{jobs.ResponseInvalidRequest, "The security profile is used by one or more containers."}
+	ErrProfileReadOnlyIsolated    = jobs.SimpleError{jobs.ResponseInvalidRequest, "A read-only root filesystem may only be used by containers that are not isolated or that run in a user namespace."}
+	ErrListProfilesFailed         = jobs.SimpleError{jobs.ResponseError, "Unable to list the security profiles on this host."}
 )
@@ -0,0 +1,198 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/jobs"
+	"github.com/openshift/geard/systemd"
+	"github.com/openshift/geard/utils"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const PendingExitCodeName = "ExitCode"
+
+const switchnsPath = "/usr/bin/switchns"
+
+// The exit code of a command run inside a container
+type ExitCode int
+
+func (c ExitCode) ToHeader() string {
+	return strconv.Itoa(int(c))
+}
+func (c ExitCode) String() string {
+	return c.ToHeader()
+}
+
+// Run a command inside the namespaces of a running container using
+// switchns.  The output of the command is streamed to the response and
+// the exit code is returned as a pending value once the command
+// completes.
+type ExecContainerRequest struct {
+	Id          containers.Identifier `json:"-"`
+	Command     []string
+	Environment []string `json:"Environment,omitempty"`
+
+	// Keep the input of the command open
+	Interactive bool `json:"Interactive,omitempty"`
+	// Allocate a terminal for the command, implies Interactive
+	Tty bool `json:"Tty,omitempty"`
+
+	// The streams of an interactive command, set by the caller or
+	// the transport.  Output is written to the response when unset.
+	Stdin  io.Reader `json:"-"`
+	Stdout io.Writer `json:"-"`
+	Stderr io.Writer `json:"-"`
+}
+
+func (j *ExecContainerRequest) Check() error {
+	if len(j.Command) == 0 {
+		return errors.New("A command must be specified to execute in the container")
+	}
+	for i := range j.Environment {
+		if !strings.Contains(j.Environment[i], "=") || strings.HasPrefix(j.Environment[i], "=") {
+			return errors.New(fmt.Sprintf("The environment variable '%s' must be of the form KEY=VALUE", j.Environment[i]))
+		}
+	}
+	if j.Tty {
+		j.Interactive = true
+	}
+	return nil
+}
+
+// An attached job exchanges input and output with the caller while
+// it runs, rather than only writing a response.
+func (j *ExecContainerRequest) Attached() bool {
+	return j.Interactive || j.Tty
+}
+
+func (j *ExecContainerRequest) Attach(stdin io.Reader, stdout, stderr io.Writer) {
+	j.Stdin, j.Stdout, j.Stderr = stdin, stdout, stderr
+}
+
+func (j *ExecContainerRequest) Streams() (io.Reader, io.Writer, io.Writer) {
+	return j.Stdin, j.Stdout, j.Stderr
+}
+
+func (j *ExecContainerRequest) Execute(resp jobs.Response) {
+	unitName := j.Id.UnitNameFor()
+
+	if _, err := os.Stat(j.Id.UnitPathFor()); err != nil {
+		resp.Failure(ErrContainerNotFound)
+		return
+	}
+	running, err := systemd.IsUnitProperty(systemd.Connection(), unitName, func(p map[string]interface{}) bool {
+		return p["ActiveState"] == "active"
+	})
+	if err != nil || !running {
+		resp.Failure(ErrContainerNotRunning)
+		return
+	}
+	if _, err := os.Stat(switchnsPath); err != nil {
+		log.Printf("exec_container: Unable to find switchns: %v", err)
+		resp.Failure(ErrExecFailed)
+		return
+	}
+
+	args := []string{"--container=" + j.Id.ContainerFor()}
+	for i := range j.Environment {
+		args = append(args, "--env="+j.Environment[i])
+	}
+	args = append(args, "--")
+	args = append(args, j.Command...)
+	cmd := exec.Command(switchnsPath, args...)
+
+	var stdin io.WriteCloser
+	var terminal *os.File
+	if j.Tty {
+		master, slave, err := utils.OpenPty()
+		if err != nil {
+			log.Printf("exec_container: Unable to allocate a terminal: %v", err)
+			resp.Failure(ErrExecFailed)
+			return
+		}
+		defer master.Close()
+		defer slave.Close()
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = slave, slave, slave
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true}
+		stdin, terminal = master, master
+	} else if j.Interactive {
+		// the process may exit before the caller closes its input, so the
+		// copy cannot be left to exec.Cmd (which waits for it)
+		if stdin, err = cmd.StdinPipe(); err != nil {
+			log.Printf("exec_container: Unable to open command input: %v", err)
+			resp.Failure(ErrExecFailed)
+			return
+		}
+	}
+
+	stdout, stderr := j.Stdout, j.Stderr
+	if stdout == nil {
+		w := resp.SuccessWithWrite(jobs.ResponseAccepted, true, false)
+		stdout, stderr = w, w
+	} else {
+		resp.Success(jobs.ResponseOk)
+	}
+	if stderr == nil {
+		stderr = stdout
+	}
+	if !j.Tty {
+		cmd.Stdout, cmd.Stderr = stdout, stderr
+	}
+
+	log.Printf("exec_container: Running %v in %s", j.Command, j.Id)
+	if err := cmd.Start(); err != nil {
+		log.Printf("exec_container: Unable to start switchns: %v", err)
+		fmt.Fprintf(stderr, "Unable to execute the command: %v\n", err)
+		resp.WritePendingSuccess(PendingExitCodeName, ExitCode(126))
+		return
+	}
+
+	if stdin != nil {
+		go func() {
+			if j.Stdin != nil {
+				io.Copy(stdin, j.Stdin)
+			}
+			if !j.Tty {
+				stdin.Close()
+			}
+		}()
+	}
+	done := make(chan bool)
+	if terminal != nil {
+		// reads from the terminal fail once the last process holding the
+		// slave side exits
+		cmd.Stdin.(*os.File).Close()
+		go func() {
+			io.Copy(stdout, terminal)
+			close(done)
+		}()
+	} else {
+		close(done)
+	}
+
+	err = cmd.Wait()
+	<-done
+	resp.WritePendingSuccess(PendingExitCodeName, exitCodeFor(err))
+}
+
+func exitCodeFor(err error) ExitCode {
+	if err == nil {
+		return ExitCode(0)
+	}
+	if exit, ok := err.(*exec.ExitError); ok {
+		if status, ok := exit.Sys().(syscall.WaitStatus); ok {
+			if status.Signaled() {
+				return ExitCode(128 + int(status.Signal()))
+			}
+			return ExitCode(status.ExitStatus())
+		}
+	}
+	return ExitCode(1)
+}
@@ -0,0 +1,100 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/openshift/geard/docker"
+	"github.com/openshift/geard/jobs"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// The connection and response header timeouts of the source agent, and
+// the longest an image may take to transfer.
+const (
+	importConnectTimeout  = 30 * time.Second
+	importResponseTimeout = 2 * time.Minute
+	importTransferTimeout = 2 * time.Hour
+)
+
+var importClient = &http.Client{
+	Transport: &http.Transport{
+		Dial:                  (&net.Dialer{Timeout: importConnectTimeout}).Dial,
+		TLSHandshakeTimeout:   importConnectTimeout,
+		ResponseHeaderTimeout: importResponseTimeout,
+	},
+	// The token is only valid for the agent it was created for, so a
+	// redirect is never followed to another host
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return errors.New("The source agent may not redirect an image export")
+	},
+	Timeout: importTransferTimeout,
+}
+
+var allowedImageToken = regexp.MustCompile("\\A[a-zA-Z0-9\\-_=%\\.]+/[a-zA-Z0-9\\-_=]+/[a-zA-Z0-9\\-_=]+\\z")
+
+// Load an image exported by another agent.  The token, created with
+// 'gear create-token image <name>', authorizes this host to read the
+// image from the source agent.
+type ImportImageRequest struct {
+	// The base URL of the agent the image is exported from
+	Source string
+	Token  string
+
+	Label        string `json:"-"`
+	DockerSocket string `json:"-"`
+}
+
+func (j *ImportImageRequest) JobLabel() string {
+	return j.Label
+}
+
+func (j *ImportImageRequest) Check() error {
+	u, err := url.Parse(j.Source)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("The source must be the http URL of an agent")
+	}
+	if u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return errors.New("The source must be of the form http[s]://<host>[:<port>]")
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && (ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsMulticast()) {
+		return errors.New("The source must be the address of an agent")
+	}
+	if !allowedImageToken.MatchString(j.Token) {
+		return errors.New("The token must be of the form <key>/<signature>/<ciphertext>")
+	}
+	return nil
+}
+
+func (j *ImportImageRequest) Execute(resp jobs.Response) {
+	source := strings.TrimSuffix(j.Source, "/") + "/token/" + j.Token
+
+	r, err := importClient.Get(source)
+	if err != nil {
+		log.Printf("import_image: Unable to reach %s: %v", j.Source, err)
+		resp.Failure(ErrImageImportFailed)
+		return
+	}
+	defer r.Body.Close()
+	if r.StatusCode < 200 || r.StatusCode >= 300 {
+		log.Printf("import_image: Export from %s failed with status %d", j.Source, r.StatusCode)
+		resp.Failure(jobs.SimpleError{jobs.ResponseError, fmt.Sprintf("The source agent refused to export the image (%s)", r.Status)})
+		return
+	}
+
+	w := resp.SuccessWithWrite(jobs.ResponseAccepted, true, false)
+	if err := docker.LoadImage(j.DockerSocket, r.Body, w); err != nil {
+		log.Printf("import_image: Unable to load the image from %s: %v", j.Source, err)
+		fmt.Fprintf(w, "Error: unable to import the image: %s\n", err.Error())
+		return
+	}
+	fmt.Fprintf(w, "Imported the image from %s\n", j.Source)
+}
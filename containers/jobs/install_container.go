@@ -13,6 +13,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 )
 
 var ErrContainerCreateFailedPortsReserved = jobs.SimpleError{jobs.ResponseError, "Unable to create container: some ports could not be reserved."}
@@ -54,6 +55,9 @@ const PendingPortMappingName = "PortMapping"
 // In the future the need for #1 is removed by user namespaces, although given the
 // relative immaturity of that function in the kernel at the present time it is not
 // considered sufficiently secure for production use.
+// A container installed with UserNamespace instead runs in a user namespace that
+// maps its users to a range of host ids allocated to the container, so the image
+// is not changed to run as the container user.
 //
 type InstallContainerRequest struct {
 	jobs.RequestIdentifier `json:"-"`
@@ -68,6 +72,10 @@ type InstallContainerRequest struct {
 	// Should this container be run in an isolated fashion
 	// (separate user, permission changes)
 	Isolate bool
+	// Implies Isolated, but maps the users of the container to a
+	// range of host ids allocated to it instead of changing the
+	// image to run as the container user.
+	UserNamespace bool `json:"UserNamespace,omitempty"`
 	// Should this container be run in a socket activated fashion
 	// Implies Isolated (separate user, permission changes,
 	// no port forwarding, socket activated).
@@ -80,8 +88,44 @@ type InstallContainerRequest struct {
 	Environment  *containers.EnvironmentDescription
 	NetworkLinks *containers.NetworkLinks
 
+	// How systemd restarts the container when it exits, nil
+	// leaves the container stopped
+	RestartPolicy *containers.RestartPolicy `json:"RestartPolicy,omitempty"`
+
+	// Checks that determine whether the running container is available
+	HealthChecks containers.HealthChecks `json:"HealthChecks,omitempty"`
+
+	// Actions systemd runs before and after the container starts and
+	// stops
+	Hooks containers.LifecycleHooks `json:"Hooks,omitempty"`
+
+	// The slice the container is placed in, which determines the
+	// resource limits it shares with other containers.
+	Slice containers.SliceIdentifier
+
+	// Named volumes mounted into the container, which must exist
+	// before the container is installed
+	Volumes containers.VolumeMounts `json:"Volumes,omitempty"`
+
+	// Free-form labels recorded in the unit, used to select groups
+	// of containers
+	Labels containers.Labels `json:"Labels,omitempty"`
+
+	// Other containers on this host that are started before this
+	// container
+	Dependencies containers.ContainerDependencies `json:"Dependencies,omitempty"`
+
+	// The named security profile applied to the container, defaults
+	// to the profile configured for the daemon
+	SecurityProfile containers.ProfileIdentifier `json:"SecurityProfile,omitempty"`
+
 	// Should the container be started by default
 	Started bool
+
+	// Pull the image before installing, and fail if it cannot be
+	// fetched
+	PullFirst    bool   `json:"PullFirst,omitempty"`
+	DockerSocket string `json:"-"`
 }
 
 func (req *InstallContainerRequest) Check() error {
@@ -107,9 +151,56 @@ func (req *InstallContainerRequest) Check() error {
 			return err
 		}
 	}
+	if req.UserNamespace {
+		if !config.SystemDockerFeatures.UserNamespaces {
+			return errors.New("User namespaces are not supported by Docker on this host.")
+		}
+		if req.SocketActivation {
+			return errors.New("A socket activated container may not be run in a user namespace.")
+		}
+		req.Isolate = true
+	}
 	if req.Ports == nil {
 		req.Ports = make([]port.PortPair, 0)
 	}
+	if req.RestartPolicy != nil {
+		if err := req.RestartPolicy.Check(); err != nil {
+			return err
+		}
+	}
+	if err := req.HealthChecks.Check(); err != nil {
+		return err
+	}
+	if err := req.Hooks.Check(); err != nil {
+		return err
+	}
+	if req.Slice == "" {
+		req.Slice = containers.DefaultSlice
+	}
+	slice, err := containers.NewSliceIdentifier(string(req.Slice))
+	if err != nil {
+		return err
+	}
+	req.Slice = slice
+	if err := req.Volumes.Check(); err != nil {
+		return err
+	}
+	if err := req.Labels.Check(); err != nil {
+		return err
+	}
+	if err := req.Dependencies.Check(); err != nil {
+		return err
+	}
+	if req.SecurityProfile != "" {
+		if _, err := containers.NewProfileIdentifier(string(req.SecurityProfile)); err != nil {
+			return err
+		}
+	}
+	for i := range req.Dependencies {
+		if req.Dependencies[i].Id == req.Id {
+			return errors.New("A container may not depend on itself")
+		}
+	}
 	return nil
 }
 
@@ -137,10 +228,53 @@ func (req *InstallContainerRequest) Execute(resp jobs.Response) {
 		}
 	}
 
+	if _, err := os.Stat(req.Slice.UnitPathFor()); err != nil {
+		resp.Failure(ErrSliceNotFound)
+		return
+	}
+	if req.PullFirst {
+		if err := pullImageFirst(req.DockerSocket, req.Image); err != nil {
+			log.Printf("install_container: Unable to pull image %s: %v", req.Image, err)
+			resp.Failure(jobs.SimpleError{jobs.ResponseError, fmt.Sprintf("Unable to pull the image %s: %s", req.Image, err.Error())})
+			return
+		}
+	}
+	for i := range req.Volumes {
+		if _, err := os.Stat(req.Volumes[i].Name.PathFor()); err != nil {
+			resp.Failure(ErrVolumeNotFound)
+			return
+		}
+	}
+
+	// containers installed without a profile use the daemon default
+	profileId := req.SecurityProfile
+	if profileId == "" {
+		profileId = containers.ProfileIdentifier(config.DefaultSecurityProfile)
+	}
+	var profile *containers.SecurityProfile
+	if profileId != "" {
+		p, err := containers.ReadSecurityProfile(profileId)
+		if err != nil {
+			if os.IsNotExist(err) {
+				resp.Failure(ErrProfileNotFound)
+				return
+			}
+			log.Printf("install_container: Unable to read security profile %s: %v", profileId, err)
+			resp.Failure(ErrContainerCreateFailed)
+			return
+		}
+		// the init script of an isolated container changes the image
+		if p.ReadOnlyRootfs && (req.SocketActivation || (req.Isolate && !req.UserNamespace)) {
+			resp.Failure(ErrProfileReadOnlyIsolated)
+			return
+		}
+		profile = p
+	}
+
 	// attempt to download the environment if it is remote
 	env := req.Environment
 	if env != nil {
-		if err := env.Fetch(100 * 1024); err != nil {
+		if err := env.Fetch(environmentSourceLimit); err != nil {
 			resp.Failure(ErrContainerCreateFailed)
 			return
 		}
@@ -198,14 +332,23 @@ func (req *InstallContainerRequest) Execute(resp jobs.Response) {
 	}
 
 	// write the environment to disk
-	var environmentPath string
+	var environmentPath, resolvedEnvironmentPath string
 	if env != nil {
 		if errw := env.Write(false); errw != nil {
+			if errw == utils.ErrLockTaken {
+				resp.Failure(ErrEnvironmentLocked)
+				return
+			}
 			resp.Failure(ErrContainerCreateFailed)
 			return
 		}
 		environmentPath = env.Id.EnvironmentPathFor()
 	}
+	// references and secrets in the environment, and the variables of
+	// named links, are resolved when the container starts
+	if env != nil || req.NetworkLinks != nil {
+		resolvedEnvironmentPath = id.EnvironmentRunPathFor()
+	}
 
 	// write the network links (if any) to disk
 	if req.NetworkLinks != nil {
@@ -215,16 +358,45 @@ func (req *InstallContainerRequest) Execute(resp jobs.Response) {
 		}
 	}
 
-	slice := "container-small"
+	// write the health checks (if any) to disk, the daemon picks up changes
+	if errw := req.HealthChecks.Write(id); errw != nil {
+		log.Print("install_container: Unable to write health checks: ", errw)
+		resp.Failure(ErrContainerCreateFailed)
+		return
+	}
+
+	// allocate the host ids the users of the container are mapped to
+	var userns *containers.UserNamespace
+	var userId string
+	if req.UserNamespace {
+		if userns, err = containers.ReserveUserNamespace(id); err != nil {
+			log.Print("install_container: Unable to allocate a user namespace: ", err)
+			resp.Failure(ErrContainerCreateFailed)
+			return
+		}
+		userId = strconv.Itoa(userns.Start)
+	} else if err := containers.ReleaseUserNamespace(id); err != nil {
+		log.Print("install_container: Unable to release the user namespace: ", err)
+	}
 
 	// write the definition unit file
 	args := containers.ContainerUnit{
 		Id:       id,
 		Image:    req.Image,
 		PortSpec: portSpec,
-		Slice:    slice + ".slice",
+		Slice:    req.Slice.UnitNameFor(),
+		Restart:  req.RestartPolicy,
+		Volumes:  req.Volumes,
+		Labels:   req.Labels,
+
+		Dependencies: req.Dependencies,
+		Hooks:        req.Hooks,
 
-		Isolate: req.Isolate,
+		Isolate:       req.Isolate,
+		User:          userId,
+		UserNamespace: userns,
+
+		SecurityProfile: profile,
 
 		ReqId: req.RequestIdentifier.String(),
 
@@ -234,6 +406,8 @@ func (req *InstallContainerRequest) Execute(resp jobs.Response) {
 		ExecutablePath:  filepath.Join("/", "usr", "bin", "gear"),
 		IncludePath:     "",
 
+		ResolvedEnvironmentPath: resolvedEnvironmentPath,
+
 		PortPairs:            reserved,
 		SocketUnitName:       socketUnitName,
 		SocketActivationType: socketActivationType,
@@ -8,6 +8,7 @@ import (
 	"github.com/openshift/go-systemd/dbus"
 	"io"
 	"log"
+	"os"
 	"regexp"
 	"sort"
 	"text/tabwriter"
@@ -48,6 +49,8 @@ func unitsMatching(re *regexp.Regexp, found func(name string, unit *dbus.UnitSta
 
 type ListContainersRequest struct {
 	Label string
+	// Only list containers whose labels match
+	Selector containers.LabelSelector `json:"Selector,omitempty"`
 }
 
 func (l *ListContainersRequest) JobLabel() string {
@@ -57,7 +60,11 @@ func (l *ListContainersRequest) JobLabel() string {
 type ContainerUnitResponse struct {
 	unitResponse
 	LoadState string
-	JobType   string `json:"JobType,omitempty"`
+	JobType   string            `json:"JobType,omitempty"`
+	Health    containers.Health `json:"Health,omitempty"`
+	Labels    containers.Labels `json:"Labels,omitempty"`
+	// Whether the container is started on boot
+	StartOnBoot bool `json:"StartOnBoot,omitempty"`
 	// Used by consumers
 	Server string `json:"Server,omitempty"`
 }
@@ -86,12 +93,12 @@ func (r *ListContainersResponse) Sort() {
 
 func (l *ListContainersResponse) WriteTableTo(w io.Writer) error {
 	tw := tabwriter.NewWriter(w, 8, 4, 1, ' ', tabwriter.DiscardEmptyColumns)
-	if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", "ID", "ACTIVE", "SUB", "LOAD", "TYPE"); err != nil {
+	if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", "ID", "ACTIVE", "SUB", "LOAD", "TYPE", "HEALTH"); err != nil {
 		return err
 	}
 	for i := range l.Containers {
 		container := &l.Containers[i]
-		if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", container.Id, container.ActiveState, container.SubState, container.LoadState, container.JobType); err != nil {
+		if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", container.Id, container.ActiveState, container.SubState, container.LoadState, container.JobType, container.Health); err != nil {
 			return err
 		}
 	}
@@ -108,6 +115,18 @@ func (j *ListContainersRequest) Execute(resp jobs.Response) {
 		if unit.LoadState == "not-found" || unit.LoadState == "masked" {
 			return
 		}
+		id := containers.Identifier(name)
+		labels, err := containers.GetContainerLabels(id)
+		if err != nil && !os.IsNotExist(err) {
+			log.Printf("list_units: Unable to read the labels of %s: %v", name, err)
+		}
+		if !j.Selector.Matches(labels) {
+			return
+		}
+		started, err := id.UnitStartOnBoot()
+		if err != nil {
+			log.Printf("list_units: Unable to read whether %s is started on boot: %v", name, err)
+		}
 		r.Containers = append(r.Containers, ContainerUnitResponse{
 			unitResponse{
 				name,
@@ -116,6 +135,9 @@ func (j *ListContainersRequest) Execute(resp jobs.Response) {
 			},
 			unit.LoadState,
 			unit.JobType,
+			containers.GetHealth(id),
+			labels,
+			started,
 			"",
 		})
 	}); err != nil {
@@ -0,0 +1,310 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/docker"
+	"github.com/openshift/geard/jobs"
+	"github.com/openshift/geard/port"
+	"github.com/openshift/geard/ssh"
+	"github.com/openshift/geard/systemd"
+	"github.com/openshift/geard/utils"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+// Write a tar stream of the unit definition, environment, network
+// links, SSH access keys and data volumes of a container, which
+// ImportContainerRequest installs on another host.  The container
+// should be stopped so that its data is consistent.
+type ExportContainerRequest struct {
+	Id containers.Identifier
+	// The container was started on boot before it was stopped for the
+	// export, so it is started when imported
+	Started      bool   `json:"Started,omitempty"`
+	DockerSocket string `json:"-"`
+}
+
+func (j *ExportContainerRequest) Execute(resp jobs.Response) {
+	if _, err := os.Stat(j.Id.UnitPathFor()); err != nil {
+		resp.Failure(ErrContainerNotFound)
+		return
+	}
+
+	export, err := containers.NewContainerExport(j.Id)
+	if err == containers.ErrExportSecrets {
+		resp.Failure(ErrContainerExportSecrets)
+		return
+	}
+	if err != nil {
+		log.Printf("export_container: Unable to read the definition of %s: %v", j.Id, err)
+		resp.Failure(ErrContainerExportFailed)
+		return
+	}
+	export.Started = export.Started || j.Started
+	if export.AccessKeys, err = ssh.AccessKeysFor(j.Id); err != nil {
+		log.Printf("export_container: Unable to read the access keys of %s: %v", j.Id, err)
+		resp.Failure(ErrContainerExportFailed)
+		return
+	}
+
+	client, err := docker.GetConnection(j.DockerSocket)
+	if err != nil {
+		log.Printf("export_container: Unable to connect to docker: %v", err)
+		resp.Failure(ErrContainerExportFailed)
+		return
+	}
+	volumes := map[string]string{}
+	if data, err := client.GetContainer(j.Id.ContainerFor()+"-data", false); err == nil {
+		volumes = data.Volumes
+	}
+
+	w := resp.SuccessWithWrite(jobs.ResponseOk, false, false)
+	if err := containers.WriteContainerExport(w, export, volumes); err != nil {
+		log.Printf("export_container: Unable to write the export of %s: %v", j.Id, err)
+	}
+}
+
+// Install a container from the stream written by ExportContainerRequest.
+// New external ports are reserved for the exported port mappings, and
+// the new mappings are returned so that links to the container can be
+// updated.
+type ImportContainerRequest struct {
+	jobs.RequestIdentifier `json:"-"`
+
+	Id           containers.Identifier
+	Archive      io.Reader `json:"-"`
+	DockerSocket string    `json:"-"`
+}
+
+func (j *ImportContainerRequest) Check() error {
+	if len(j.RequestIdentifier) == 0 {
+		return errors.New("A request identifier is required to import a container.")
+	}
+	if j.Archive == nil {
+		return errors.New("The export of the container must be provided")
+	}
+	return nil
+}
+
+func (j *ImportContainerRequest) Execute(resp jobs.Response) {
+	id := j.Id
+	unitName := id.UnitNameFor()
+	unitPath := id.UnitPathFor()
+	unitVersionPath := id.VersionedUnitPathFor(j.RequestIdentifier.String())
+
+	export, data, err := containers.ReadContainerExport(j.Archive)
+	if err != nil {
+		log.Printf("import_container: Unable to read the export of %s: %v", id, err)
+		resp.Failure(ErrContainerImportFailed)
+		return
+	}
+	if export.Id != id {
+		resp.Failure(jobs.SimpleError{jobs.ResponseInvalidRequest, fmt.Sprintf("The export is of container %s, not %s.", export.Id, id)})
+		return
+	}
+	if err := export.Check(); err != nil {
+		resp.Failure(jobs.SimpleError{jobs.ResponseInvalidRequest, err.Error()})
+		return
+	}
+	if err := export.CheckHost(); err != nil {
+		resp.Failure(jobs.SimpleError{jobs.ResponseNotFound, err.Error() + "."})
+		return
+	}
+
+	// open and lock the base path (to prevent simultaneous updates)
+	state, exists, err := utils.OpenFileExclusive(unitPath, 0664)
+	if err != nil {
+		log.Print("import_container: Unable to lock unit file: ", err)
+		resp.Failure(ErrContainerImportFailed)
+		return
+	}
+	defer state.Close()
+	if exists {
+		resp.Failure(ErrContainerAlreadyExists)
+		return
+	}
+	// undo every step taken if the import does not complete, so that it
+	// can be retried
+	var (
+		imported    bool
+		reserved    port.PortPairs
+		userns      *containers.UserNamespace
+		wroteFiles  bool
+		enabled     bool
+		dataCreated bool
+	)
+	defer func() {
+		if imported {
+			return
+		}
+		if enabled {
+			if _, err := systemd.Connection().DisableUnitFiles([]string{unitPath, id.SocketUnitPathFor()}, false); err != nil {
+				log.Printf("import_container: Unable to disable units of %s: %v", id, err)
+			}
+		}
+		if err := id.SetUnitStartOnBoot(false); err != nil {
+			log.Printf("import_container: Unable to clear unit boot state of %s: %v", id, err)
+		}
+		os.Remove(unitPath)
+		os.Remove(id.SocketUnitPathFor())
+		os.Remove(unitVersionPath)
+		if enabled {
+			systemd.Connection().Reload()
+		}
+		if err := port.ReleaseExternalPorts(reserved); err != nil {
+			log.Printf("import_container: Unable to release ports of %s: %v", id, err)
+		}
+		if userns != nil {
+			if err := containers.ReleaseUserNamespace(id); err != nil {
+				log.Printf("import_container: Unable to release user namespace of %s: %v", id, err)
+			}
+		}
+		if wroteFiles {
+			if err := export.RemoveFiles(); err != nil {
+				log.Printf("import_container: Unable to remove the files of %s: %v", id, err)
+			}
+		}
+		if err := os.RemoveAll(ssh.SshAccessBasePath(id)); err != nil {
+			log.Printf("import_container: Unable to remove access keys of %s: %v", id, err)
+		}
+		if dataCreated {
+			if client, err := docker.GetConnection(j.DockerSocket); err == nil {
+				if err := client.RemoveContainer(id.ContainerFor() + "-data"); err != nil {
+					log.Printf("import_container: Unable to remove the data container of %s: %v", id, err)
+				}
+			}
+		}
+	}()
+
+	// reserve new external ports for each exported mapping
+	ports := make(port.PortPairs, len(export.Ports))
+	for i := range export.Ports {
+		ports[i] = port.PortPair{Internal: export.Ports[i].Internal}
+	}
+	reserved, erra := port.AtomicReserveExternalPorts(unitVersionPath, ports, port.PortPairs{})
+	if erra != nil {
+		log.Printf("import_container: Unable to reserve external ports: %+v", erra)
+		resp.Failure(ErrContainerCreateFailedPortsReserved)
+		return
+	}
+	if len(reserved) > 0 {
+		resp.WritePendingSuccess(PendingPortMappingName, reserved)
+	}
+
+	// allocate new host ids for a container in a user namespace, the
+	// data is given to them when the container starts
+	if export.UserNamespace != nil {
+		if userns, err = containers.ReserveUserNamespace(id); err != nil {
+			log.Printf("import_container: Unable to allocate a user namespace: %v", err)
+			resp.Failure(ErrContainerImportFailed)
+			return
+		}
+	}
+
+	wroteFiles = true
+	if err := export.WriteFiles(); err != nil {
+		log.Printf("import_container: Unable to write the files of %s: %v", id, err)
+		resp.Failure(ErrContainerImportFailed)
+		return
+	}
+	if err := utils.WriteToPathExclusive(unitVersionPath, strings.NewReader(export.UnitFor(reserved, userns)), 0664); err != nil {
+		log.Printf("import_container: Unable to write unit definition: %v", err)
+		resp.Failure(ErrContainerImportFailed)
+		return
+	}
+	if err := utils.AtomicReplaceLink(unitVersionPath, unitPath); err != nil {
+		log.Printf("import_container: Failed to activate unit: %+v", err)
+		resp.Failure(ErrContainerImportFailed)
+		return
+	}
+	state.Close()
+
+	if export.Started {
+		if err := id.SetUnitStartOnBoot(true); err != nil {
+			log.Print("import_container: Unable to write container boot link: ", err)
+			resp.Failure(ErrContainerImportFailed)
+			return
+		}
+	}
+
+	paths := []string{unitPath}
+	if export.SocketActivated {
+		socketUnitPath := id.SocketUnitPathFor()
+		if err := writeSocketUnit(socketUnitPath, &containers.ContainerUnit{Id: id, PortPairs: reserved}); err == nil {
+			paths = append(paths, socketUnitPath)
+		}
+	}
+	enabled = true
+	if err := systemd.EnableAndReloadUnit(systemd.Connection(), unitName, paths...); err != nil {
+		log.Printf("import_container: Could not enable container %s (%v): %v", unitName, paths, err)
+		resp.Failure(ErrContainerImportFailed)
+		return
+	}
+
+	if err := ssh.GrantAccessKeys(id, export.AccessKeys); err != nil {
+		log.Printf("import_container: Unable to grant access keys to %s: %v", id, err)
+		resp.Failure(ErrContainerImportFailed)
+		return
+	}
+
+	// create the data container and restore its volumes
+	if !export.SocketActivated {
+		client, err := docker.GetConnection(j.DockerSocket)
+		if err != nil {
+			log.Printf("import_container: Unable to connect to docker: %v", err)
+			resp.Failure(ErrContainerImportFailed)
+			return
+		}
+		name := id.ContainerFor() + "-data"
+		container, err := client.GetContainer(name, false)
+		if err != nil {
+			if err := docker.RunDataContainer(j.DockerSocket, name, export.Image); err != nil {
+				log.Printf("import_container: Unable to create the data container of %s: %v", id, err)
+				resp.Failure(ErrContainerImportFailed)
+				return
+			}
+			dataCreated = true
+			container, err = client.GetContainer(name, false)
+		}
+		if err != nil {
+			log.Printf("import_container: Unable to inspect the data container of %s: %v", id, err)
+			resp.Failure(ErrContainerImportFailed)
+			return
+		}
+		if err := containers.ExtractContainerData(data, container.Volumes); err != nil {
+			log.Printf("import_container: Unable to restore the data of %s: %v", id, err)
+			resp.Failure(ErrContainerImportFailed)
+			return
+		}
+	}
+	imported = true
+
+	if export.Started {
+		startName := unitName
+		if export.SocketActivated {
+			startName = id.SocketUnitNameFor()
+		}
+		if err := systemd.Connection().StartUnitJob(startName, "replace"); err != nil {
+			log.Printf("import_container: Could not start container %s: %v", startName, err)
+			resp.Failure(ErrContainerStartFailed)
+			return
+		}
+	}
+
+	w := resp.SuccessWithWrite(jobs.ResponseAccepted, true, false)
+	fmt.Fprintf(w, "Container %s imported\n", id)
+	for i := range export.Ports {
+		if to, found := reserved.Find(export.Ports[i].Internal); found {
+			fmt.Fprintf(w, "Port %d moved from %d to %d\n", to.Internal, export.Ports[i].External, to.External)
+		}
+	}
+}
+
+func (j *ImportContainerRequest) PortMappingsFrom(pending map[string]interface{}) (port.PortPairs, bool) {
+	p, ok := pending[PendingPortMappingName].(port.PortPairs)
+	return p, ok
+}
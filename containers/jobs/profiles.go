@@ -0,0 +1,156 @@
+package jobs
+
+import (
+	"fmt"
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/jobs"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Create or update a named security profile.  Installed containers
+// keep the options of the profile until they are installed again.
+type PutProfileRequest struct {
+	containers.SecurityProfile
+}
+
+func (j *PutProfileRequest) Check() error {
+	return j.SecurityProfile.Check()
+}
+
+func (j *PutProfileRequest) Execute(resp jobs.Response) {
+	if err := j.Write(); err != nil {
+		log.Printf("profiles: Unable to write profile %s: %v", j.Id, err)
+		resp.Failure(ErrProfileUpdateFailed)
+		return
+	}
+	resp.Success(jobs.ResponseOk)
+}
+
+type DeleteProfileRequest struct {
+	Id    containers.ProfileIdentifier
+	Label string
+}
+
+func (j *DeleteProfileRequest) JobLabel() string {
+	return j.Label
+}
+
+func (j *DeleteProfileRequest) Execute(resp jobs.Response) {
+	path := j.Id.DescriptionPathFor()
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		resp.Success(jobs.ResponseOk)
+		return
+	}
+
+	inUse, err := profileInUse(j.Id)
+	if err != nil {
+		log.Printf("profiles: Unable to determine whether profile %s is in use: %v", j.Id, err)
+		resp.Failure(ErrProfileDeleteFailed)
+		return
+	}
+	if inUse {
+		resp.Failure(ErrProfileInUse)
+		return
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Printf("profiles: Unable to remove profile %s: %v", j.Id, err)
+		resp.Failure(ErrProfileDeleteFailed)
+		return
+	}
+
+	resp.Success(jobs.ResponseOk)
+}
+
+// A profile is in use while any installed container unit records it.
+func profileInUse(id containers.ProfileIdentifier) (bool, error) {
+	found := false
+	err := containers.WalkContainerUnits(func(ctr containers.Identifier) {
+		if profile, _ := containers.GetContainerSecurityProfile(ctr); profile == id {
+			found = true
+		}
+	})
+	return found, err
+}
+
+type ListProfilesRequest struct {
+	Label string
+}
+
+func (l *ListProfilesRequest) JobLabel() string {
+	return l.Label
+}
+
+type ProfileResponse struct {
+	containers.SecurityProfile
+	// Used by consumers
+	Server string `json:"Server,omitempty"`
+}
+type ProfileResponses []ProfileResponse
+
+func (c ProfileResponses) Less(a, b int) bool {
+	return c[a].Id < c[b].Id
+}
+func (c ProfileResponses) Len() int {
+	return len(c)
+}
+func (c ProfileResponses) Swap(a, b int) {
+	c[a], c[b] = c[b], c[a]
+}
+
+type ListProfilesResponse struct {
+	Profiles ProfileResponses
+}
+
+func (r *ListProfilesResponse) Append(other *ListProfilesResponse) {
+	r.Profiles = append(r.Profiles, other.Profiles...)
+}
+func (r *ListProfilesResponse) Sort() {
+	sort.Sort(r.Profiles)
+}
+
+func (l *ListProfilesResponse) WriteTableTo(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 8, 4, 1, ' ', tabwriter.DiscardEmptyColumns)
+	if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", "PROFILE", "SERVER", "CAP DROP", "CAP ADD", "SECCOMP", "OPTIONS"); err != nil {
+		return err
+	}
+	for i := range l.Profiles {
+		p := &l.Profiles[i]
+		options := []string{}
+		if p.ReadOnlyRootfs {
+			options = append(options, "read-only")
+		}
+		if p.NoNewPrivileges {
+			options = append(options, "no-new-privileges")
+		}
+		if p.SELinuxType != "" {
+			options = append(options, "type="+p.SELinuxType)
+		}
+		if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", p.Id, p.Server, strings.Join(p.CapDrop, ","), strings.Join(p.CapAdd, ","), p.Seccomp, strings.Join(options, ",")); err != nil {
+			return err
+		}
+	}
+	tw.Flush()
+	return nil
+}
+
+func (j *ListProfilesRequest) Execute(resp jobs.Response) {
+	profiles, err := containers.ListSecurityProfiles()
+	if err != nil {
+		log.Printf("profiles: Unable to list profiles: %v", err)
+		resp.Failure(ErrListProfilesFailed)
+		return
+	}
+
+	r := &ListProfilesResponse{make(ProfileResponses, 0, len(profiles))}
+	for i := range profiles {
+		r.Profiles = append(r.Profiles, ProfileResponse{profiles[i], ""})
+	}
+	r.Sort()
+	resp.SuccessWithData(jobs.ResponseOk, r)
+}
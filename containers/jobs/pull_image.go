@@ -0,0 +1,115 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/openshift/geard/docker"
+	"github.com/openshift/geard/jobs"
+	"io"
+	"log"
+	"regexp"
+)
+
+var allowedImageRepository = regexp.MustCompile("\\A[a-z0-9\\-_\\./]{1,255}\\z")
+var allowedImageRegistry = regexp.MustCompile("\\A[a-zA-Z0-9\\-\\.]+(:[0-9]{1,5})?\\z")
+var allowedImageTag = regexp.MustCompile("\\A[a-zA-Z0-9\\-_\\.]{1,128}\\z")
+
+// Download an image to this host ahead of the containers that use it,
+// writing the progress of each layer.
+type PullImageRequest struct {
+	// The host and port of the registry, empty for the default
+	Registry   string `json:"Registry,omitempty"`
+	Repository string
+	// Defaults to latest
+	Tag string `json:"Tag,omitempty"`
+
+	Label        string `json:"-"`
+	DockerSocket string `json:"-"`
+}
+
+func (j *PullImageRequest) JobLabel() string {
+	return j.Label
+}
+
+func (j *PullImageRequest) Check() error {
+	if j.Registry != "" && !allowedImageRegistry.MatchString(j.Registry) {
+		return errors.New("The registry must be a host name with an optional port")
+	}
+	if !allowedImageRepository.MatchString(j.Repository) {
+		return errors.New("The repository must match " + allowedImageRepository.String())
+	}
+	if j.Tag == "" {
+		j.Tag = "latest"
+	}
+	if !allowedImageTag.MatchString(j.Tag) {
+		return errors.New("The tag must match " + allowedImageTag.String())
+	}
+	return nil
+}
+
+// The repository including the registry
+func (j *PullImageRequest) RepositoryName() string {
+	if j.Registry != "" {
+		return j.Registry + "/" + j.Repository
+	}
+	return j.Repository
+}
+
+func (j *PullImageRequest) Image() string {
+	return j.RepositoryName() + ":" + j.Tag
+}
+
+func (j *PullImageRequest) Execute(resp jobs.Response) {
+	client, err := docker.GetConnection(j.DockerSocket)
+	if err != nil {
+		log.Printf("pull_image: Unable to connect to docker: %v", err)
+		resp.Failure(ErrImagePullFailed)
+		return
+	}
+
+	// failures reported before any progress change the response
+	w := &deferredWriter{resp: resp, success: jobs.ResponseAccepted, flush: true}
+	if err := client.PullImage(j.RepositoryName(), j.Tag, w); err != nil {
+		log.Printf("pull_image: Unable to pull %s: %v", j.Image(), err)
+		if w.w == nil {
+			resp.Failure(jobs.SimpleError{jobs.ResponseError, fmt.Sprintf("Unable to pull %s: %s", j.Image(), err.Error())})
+			return
+		}
+		fmt.Fprintf(w, "\nError: unable to pull %s: %s\n", j.Image(), err.Error())
+		return
+	}
+	fmt.Fprintf(w, "\nPulled %s\n", j.Image())
+}
+
+// Begins a successful response on the first write, so that a job can
+// still fail until it has output.
+type deferredWriter struct {
+	resp    jobs.Response
+	success jobs.ResponseSuccess
+	flush   bool
+	w       io.Writer
+}
+
+func (p *deferredWriter) Write(b []byte) (int, error) {
+	if p.w == nil {
+		p.w = p.resp.SuccessWithWrite(p.success, p.flush, false)
+	}
+	return p.w.Write(b)
+}
+
+// Pull the image of a container before it is installed, so that a
+// missing image fails the install instead of the first start.
+func pullImageFirst(dockerSocket, image string) error {
+	client, err := docker.GetConnection(dockerSocket)
+	if err != nil {
+		return err
+	}
+	registry, repository, tag := docker.ParseImageName(image)
+	if registry != "" {
+		repository = registry + "/" + repository
+	}
+	if tag == "" {
+		tag = "latest"
+	}
+	return client.PullImage(repository, tag, nil)
+}
@@ -9,50 +9,50 @@ import (
 	"github.com/openshift/geard/utils"
 	"github.com/openshift/go-systemd/dbus"
 	"io"
+	"io/ioutil"
 	"log"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"time"
 )
 
 type RunContainerRequest struct {
-	Name      string
-	Image     string
-	Command   string
-	Arguments []string
+	containers.ContainerRun
+
+	// Resume the output of an earlier run with the same name after the
+	// entry with this journal cursor, instead of starting a new run
+	Cursor string `json:"Cursor,omitempty"`
+	// Write the output as JSON journal entries that carry their cursors
+	JsonLog bool `json:"JsonLog,omitempty"`
 }
 
 func (e *RunContainerRequest) Check() error {
 	if e.Name == "" {
 		return errors.New("A name must be specified for this container execution")
 	}
-	if e.Image == "" {
+	if e.Image == "" && e.Cursor == "" {
 		return errors.New("An image must be specified for this container execution")
 	}
-	return nil
+	if err := e.ContainerRun.Check(); err != nil {
+		return err
+	}
+	return (&systemd.LogOptions{Cursor: e.Cursor}).Check()
 }
 
+// The unit runs the container through gear, which records the result
+// of the run when the container exits.
 func (j *RunContainerRequest) UnitCommand() []string {
-	command := []string{
-		"/usr/bin/docker", "run",
-		"--rm",
-	}
-	if j.Command != "" {
-		command = append(command, "--entrypoint", j.Command)
-	}
-	if j.Image != "" {
-		command = append(command, "-t", j.Image)
-	}
-	if len(j.Arguments) > 0 {
-		command = append(command, j.Arguments...)
-	}
-	return command
+	return []string{filepath.Join("/", "usr", "bin", "gear"), "run-job", "--", j.Name}
 }
 
 func (j *RunContainerRequest) Execute(resp jobs.Response) {
 	command := j.UnitCommand()
 	unitName := containers.JobIdentifier(j.Name).UnitNameFor()
-	unitDescription := fmt.Sprintf("Execute image '%s': %s %s", j.Image, j.Command, strings.Join(command, " "))
+	// the docker command carries the environment, which may hold
+	// credentials, so only the command of the user is described
+	unitDescription := fmt.Sprintf("Execute image '%s': %s", j.Image, strings.TrimSpace(j.Command+" "+strings.Join(j.Arguments, " ")))
 
 	var (
 		stdout  io.ReadCloser
@@ -60,8 +60,45 @@ func (j *RunContainerRequest) Execute(resp jobs.Response) {
 		errch   <-chan error
 	)
 
+	resume := j.Cursor != ""
+	if !resume {
+		if _, err := os.Stat(j.Slice.UnitPathFor()); err != nil && !j.Slice.Builtin() {
+			resp.Failure(ErrSliceNotFound)
+			return
+		}
+		for i := range j.Volumes {
+			if _, err := os.Stat(j.Volumes[i].Name.PathFor()); err != nil {
+				resp.Failure(ErrVolumeNotFound)
+				return
+			}
+		}
+		if j.EnvironmentId != "" {
+			if _, err := os.Stat(j.EnvironmentId.EnvironmentPathFor()); err != nil {
+				resp.Failure(ErrEnvironmentNotFound)
+				return
+			}
+		}
+		if err := j.ContainerRun.Write(); err != nil {
+			log.Printf("run_container: Unable to write the description of %s: %v", unitName, err)
+			resp.Failure(ErrRunCreateFailed)
+			return
+		}
+	}
+	logs := &systemd.LogOptions{Since: "now", Follow: true, Json: j.JsonLog}
+	if resume {
+		if loaded, err := systemd.IsUnitLoadState(systemd.Connection(), unitName, "loaded"); err != nil || !loaded {
+			resp.Failure(jobs.SimpleError{jobs.ResponseNotFound, fmt.Sprintf("No run named %s exists to resume", j.Name)})
+			return
+		}
+		running, _ := systemd.IsUnitProperty(systemd.Connection(), unitName, func(p map[string]interface{}) bool {
+			return p["SubState"] == "running"
+		})
+		// the remaining output of a finished run is written without waiting
+		logs = &systemd.LogOptions{Cursor: j.Cursor, Follow: running, Json: j.JsonLog}
+	}
+
 	if resp.StreamResult() {
-		r, err := systemd.ProcessLogsForUnit(unitName)
+		r, err := systemd.OpenLogsForUnit(unitName, logs)
 		if err != nil {
 			r = utils.EmptyReader
 			log.Printf("run_container: Unable to fetch container run logs: %s, %+v", err.Error(), err)
@@ -94,16 +131,21 @@ func (j *RunContainerRequest) Execute(resp jobs.Response) {
 		errch = ech
 	}
 
-	log.Printf("run_container: Running container %s", unitName)
-
-	status, err := systemd.Connection().StartTransientUnit(
-		unitName,
-		"fail",
-		dbus.PropExecStart(command, true),
-		dbus.PropDescription(unitDescription),
-		dbus.PropRemainAfterExit(true),
-		dbus.PropSlice("container.slice"),
-	)
+	status := "done"
+	var err error
+	if resume {
+		log.Printf("run_container: Resuming output of %s", unitName)
+	} else {
+		log.Printf("run_container: Running container %s", unitName)
+		status, err = systemd.Connection().StartTransientUnit(
+			unitName,
+			"fail",
+			dbus.PropExecStart(command, true),
+			dbus.PropDescription(unitDescription),
+			dbus.PropRemainAfterExit(true),
+			dbus.PropSlice(j.Slice.UnitNameFor()),
+		)
+	}
 
 	switch {
 	case err != nil:
@@ -119,6 +161,11 @@ func (j *RunContainerRequest) Execute(resp jobs.Response) {
 	}
 
 	w := resp.SuccessWithWrite(jobs.ResponseAccepted, true, false)
+	if !logs.Follow {
+		io.Copy(w, stdout)
+		stdout.Close()
+		return
+	}
 	go io.Copy(w, stdout)
 
 wait:
@@ -140,3 +187,53 @@ wait:
 
 	stdout.Close()
 }
+
+// The most output returned with the result of a run
+const maxRunResultOutput = 1024 * 1024
+
+// Return the outcome of a container execution and the output it wrote.
+type ContainerRunResultRequest struct {
+	Name string
+	// Return only this many of the last lines of output, 0 for all
+	Lines int `json:"Lines,omitempty"`
+}
+
+type ContainerRunResultResponse struct {
+	containers.ContainerRunResult
+	Name   string
+	Image  string
+	Output string `json:"Output,omitempty"`
+}
+
+func (j *ContainerRunResultRequest) Execute(resp jobs.Response) {
+	id := containers.JobIdentifier(j.Name)
+	run, err := containers.ReadContainerRun(id)
+	if os.IsNotExist(err) {
+		resp.Failure(ErrRunNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("run_container: Unable to read the description of %s: %v", id.UnitNameFor(), err)
+		resp.Failure(ErrRunResultFailed)
+		return
+	}
+	result, err := containers.ReadContainerRunResult(id)
+	if err != nil {
+		log.Printf("run_container: Unable to read the result of %s: %v", id.UnitNameFor(), err)
+		resp.Failure(ErrRunResultFailed)
+		return
+	}
+
+	r := &ContainerRunResultResponse{ContainerRunResult: *result, Name: j.Name, Image: run.Image}
+	if result.Cursor != "" {
+		logs, err := systemd.OpenLogsForUnit(id.UnitNameFor(), &systemd.LogOptions{Cursor: result.Cursor, Lines: j.Lines})
+		if err != nil {
+			log.Printf("run_container: Unable to read the output of %s: %v", id.UnitNameFor(), err)
+		} else {
+			output, _ := ioutil.ReadAll(io.LimitReader(logs, maxRunResultOutput))
+			logs.Close()
+			r.Output = string(output)
+		}
+	}
+	resp.SuccessWithData(jobs.ResponseOk, r)
+}
@@ -0,0 +1,251 @@
+package jobs
+
+import (
+	"fmt"
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/jobs"
+	"github.com/openshift/geard/systemd"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// Create or update a job that runs an image whenever its calendar
+// specification elapses.
+type PutScheduleRequest struct {
+	containers.ScheduleDescription
+}
+
+func (j *PutScheduleRequest) Check() error {
+	return j.ScheduleDescription.Check()
+}
+
+func (j *PutScheduleRequest) Execute(resp jobs.Response) {
+	if err := j.Write(); err != nil {
+		log.Printf("schedules: Unable to write schedule %s: %v", j.Id, err)
+		resp.Failure(ErrScheduleUpdateFailed)
+		return
+	}
+
+	timerName := j.Id.TimerUnitNameFor()
+	if err := systemd.EnableAndReloadUnit(systemd.Connection(), timerName, j.Id.UnitPathFor(), j.Id.TimerUnitPathFor()); err != nil {
+		log.Printf("schedules: Could not enable schedule %s: %v", timerName, err)
+		resp.Failure(ErrScheduleUpdateFailed)
+		return
+	}
+	// restarting the timer applies a changed calendar specification
+	if err := systemd.Connection().RestartUnitJob(timerName, "replace"); err != nil {
+		log.Printf("schedules: Could not start schedule %s: %v", timerName, err)
+		resp.Failure(ErrScheduleUpdateFailed)
+		return
+	}
+
+	resp.Success(jobs.ResponseOk)
+}
+
+type DeleteScheduleRequest struct {
+	Id    containers.ScheduleIdentifier
+	Label string
+}
+
+func (j *DeleteScheduleRequest) JobLabel() string {
+	return j.Label
+}
+
+func (j *DeleteScheduleRequest) Execute(resp jobs.Response) {
+	if _, err := os.Stat(j.Id.DescriptionPathFor()); os.IsNotExist(err) {
+		resp.Success(jobs.ResponseOk)
+		return
+	}
+
+	timerName := j.Id.TimerUnitNameFor()
+	unitName := j.Id.UnitNameFor()
+	paths := []string{j.Id.TimerUnitPathFor(), j.Id.UnitPathFor()}
+	for _, name := range []string{timerName, unitName} {
+		if err := systemd.Connection().StopUnitJob(name, "fail"); err != nil {
+			log.Printf("schedules: Unable to queue stop unit job for %s: %v", name, err)
+		}
+	}
+	if _, err := systemd.Connection().DisableUnitFiles(paths, false); err != nil {
+		log.Printf("schedules: Schedule %s has not been disabled: %v", timerName, err)
+	}
+
+	if err := j.Id.Remove(); err != nil {
+		log.Printf("schedules: Unable to remove schedule %s: %v", j.Id, err)
+		resp.Failure(ErrScheduleDeleteFailed)
+		return
+	}
+
+	if err := systemd.Connection().Reload(); err != nil {
+		log.Printf("schedules: Unable to reload systemd: %v", err)
+	}
+
+	resp.Success(jobs.ResponseOk)
+}
+
+// Start a run of a scheduled job now.
+type RunScheduleRequest struct {
+	Id    containers.ScheduleIdentifier
+	Label string
+}
+
+func (j *RunScheduleRequest) JobLabel() string {
+	return j.Label
+}
+
+func (j *RunScheduleRequest) Execute(resp jobs.Response) {
+	if _, err := os.Stat(j.Id.DescriptionPathFor()); err != nil {
+		resp.Failure(ErrScheduleNotFound)
+		return
+	}
+
+	unitName := j.Id.UnitNameFor()
+	if err := systemd.Connection().StartUnitJob(unitName, "replace"); err != nil {
+		log.Printf("schedules: Unable to start a run of %s: %v", unitName, err)
+		resp.Failure(ErrScheduleRunFailed)
+		return
+	}
+
+	w := resp.SuccessWithWrite(jobs.ResponseAccepted, true, false)
+	fmt.Fprintf(w, "Scheduled job %s is running\n", j.Id)
+}
+
+type ListSchedulesRequest struct {
+	Label string
+}
+
+func (l *ListSchedulesRequest) JobLabel() string {
+	return l.Label
+}
+
+type ScheduleResponse struct {
+	containers.ScheduleDescription
+	LastRun *containers.ScheduleRun `json:"LastRun,omitempty"`
+	// Used by consumers
+	Server string `json:"Server,omitempty"`
+}
+type ScheduleResponses []ScheduleResponse
+
+func (c ScheduleResponses) Less(a, b int) bool {
+	return c[a].Id < c[b].Id
+}
+func (c ScheduleResponses) Len() int {
+	return len(c)
+}
+func (c ScheduleResponses) Swap(a, b int) {
+	c[a], c[b] = c[b], c[a]
+}
+
+type ListSchedulesResponse struct {
+	Schedules ScheduleResponses
+}
+
+func (r *ListSchedulesResponse) Append(other *ListSchedulesResponse) {
+	r.Schedules = append(r.Schedules, other.Schedules...)
+}
+func (r *ListSchedulesResponse) Sort() {
+	sort.Sort(r.Schedules)
+}
+
+func (l *ListSchedulesResponse) WriteTableTo(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 8, 4, 1, ' ', tabwriter.DiscardEmptyColumns)
+	if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", "SCHEDULE", "SERVER", "CALENDAR", "IMAGE", "LAST RUN", "RESULT"); err != nil {
+		return err
+	}
+	for i := range l.Schedules {
+		schedule := &l.Schedules[i]
+		last, result := "", ""
+		if schedule.LastRun != nil {
+			last = schedule.LastRun.Started.Format(time.RFC3339)
+			result = schedule.LastRun.Result
+		}
+		if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", schedule.Id, schedule.Server, schedule.Calendar, schedule.Image, last, result); err != nil {
+			return err
+		}
+	}
+	tw.Flush()
+	return nil
+}
+
+func (j *ListSchedulesRequest) Execute(resp jobs.Response) {
+	schedules, err := containers.ListSchedules()
+	if err != nil {
+		log.Printf("schedules: Unable to list schedules: %v", err)
+		resp.Failure(ErrListSchedulesFailed)
+		return
+	}
+
+	r := &ListSchedulesResponse{make(ScheduleResponses, 0, len(schedules))}
+	for i := range schedules {
+		var last *containers.ScheduleRun
+		if runs, err := schedules[i].Id.Runs(); err == nil && len(runs) > 0 {
+			last = &runs[len(runs)-1]
+		}
+		r.Schedules = append(r.Schedules, ScheduleResponse{schedules[i], last, ""})
+	}
+	r.Sort()
+	resp.SuccessWithData(jobs.ResponseOk, r)
+}
+
+// Return the recorded runs of a scheduled job, newest first.
+type ScheduleRunsRequest struct {
+	Id    containers.ScheduleIdentifier
+	Label string
+}
+
+func (j *ScheduleRunsRequest) JobLabel() string {
+	return j.Label
+}
+
+type ScheduleRunResponse struct {
+	containers.ScheduleRun
+	Id containers.ScheduleIdentifier
+	// Used by consumers
+	Server string `json:"Server,omitempty"`
+}
+
+type ScheduleRunsResponse struct {
+	Runs []ScheduleRunResponse
+}
+
+func (r *ScheduleRunsResponse) Append(other *ScheduleRunsResponse) {
+	r.Runs = append(r.Runs, other.Runs...)
+}
+
+func (l *ScheduleRunsResponse) WriteTableTo(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 8, 4, 1, ' ', tabwriter.DiscardEmptyColumns)
+	if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", "SCHEDULE", "SERVER", "STARTED", "DURATION", "RESULT", "EXIT", "CURSOR"); err != nil {
+		return err
+	}
+	for i := range l.Runs {
+		run := &l.Runs[i]
+		duration := run.Duration / time.Millisecond * time.Millisecond
+		if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", run.Id, run.Server, run.Started.Format(time.RFC3339), duration, run.Result, run.ExitCode, run.Cursor); err != nil {
+			return err
+		}
+	}
+	tw.Flush()
+	return nil
+}
+
+func (j *ScheduleRunsRequest) Execute(resp jobs.Response) {
+	if _, err := os.Stat(j.Id.DescriptionPathFor()); err != nil {
+		resp.Failure(ErrScheduleNotFound)
+		return
+	}
+	runs, err := j.Id.Runs()
+	if err != nil {
+		log.Printf("schedules: Unable to read the runs of %s: %v", j.Id, err)
+		resp.Failure(ErrListScheduleRunsFailed)
+		return
+	}
+
+	r := &ScheduleRunsResponse{make([]ScheduleRunResponse, 0, len(runs))}
+	for i := len(runs) - 1; i >= 0; i-- {
+		r.Runs = append(r.Runs, ScheduleRunResponse{runs[i], j.Id, ""})
+	}
+	resp.SuccessWithData(jobs.ResponseOk, r)
+}
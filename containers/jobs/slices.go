@@ -0,0 +1,286 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/jobs"
+	"github.com/openshift/geard/systemd"
+	"github.com/openshift/geard/utils"
+	"github.com/openshift/go-systemd/dbus"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"text/tabwriter"
+)
+
+type PutSliceRequest struct {
+	containers.SliceDescription
+}
+
+func (j *PutSliceRequest) Execute(resp jobs.Response) {
+	if j.Parent != "" {
+		if _, err := os.Stat(j.Parent.UnitPathFor()); err != nil {
+			resp.Failure(ErrSliceParentNotFound)
+			return
+		}
+	}
+
+	if err := j.Write(); err != nil {
+		log.Printf("slices: Unable to write slice %s: %v", j.Id, err)
+		resp.Failure(ErrSliceUpdateFailed)
+		return
+	}
+
+	unitName := j.Id.UnitNameFor()
+	if err := systemd.EnableAndReloadUnit(systemd.Connection(), unitName, j.Id.UnitPathFor()); err != nil {
+		log.Printf("slices: Could not enable slice %s: %v", unitName, err)
+		resp.Failure(ErrSliceUpdateFailed)
+		return
+	}
+
+	// apply the new limits to the running slice, the unit file covers the next boot
+	if props := j.Properties(); len(props) > 0 {
+		if err := systemd.Connection().SetUnitProperties(unitName, true, props...); err != nil {
+			log.Printf("slices: Unable to apply limits to running slice %s: %v", unitName, err)
+		}
+	}
+
+	resp.Success(jobs.ResponseOk)
+}
+
+type DeleteSliceRequest struct {
+	Id    containers.SliceIdentifier
+	Label string
+}
+
+func (j *DeleteSliceRequest) JobLabel() string {
+	return j.Label
+}
+
+func (j *DeleteSliceRequest) Execute(resp jobs.Response) {
+	if j.Id.Builtin() {
+		resp.Failure(ErrSliceBuiltin)
+		return
+	}
+
+	unitName := j.Id.UnitNameFor()
+	unitPath := j.Id.UnitPathFor()
+
+	if _, err := os.Stat(unitPath); os.IsNotExist(err) {
+		resp.Success(jobs.ResponseOk)
+		return
+	}
+
+	inUse, err := sliceInUse(j.Id)
+	if err != nil {
+		log.Printf("slices: Unable to determine whether slice %s is in use: %v", j.Id, err)
+		resp.Failure(ErrSliceDeleteFailed)
+		return
+	}
+	if inUse {
+		resp.Failure(ErrSliceInUse)
+		return
+	}
+
+	if err := systemd.Connection().StopUnitJob(unitName, "fail"); err != nil {
+		log.Printf("slices: Unable to queue stop unit job: %v", err)
+	}
+
+	if err := os.Remove(unitPath); err != nil && !os.IsNotExist(err) {
+		resp.Failure(ErrSliceDeleteFailed)
+		return
+	}
+
+	if _, err := systemd.Connection().DisableUnitFiles([]string{unitPath}, false); err != nil {
+		log.Printf("slices: Slice %s has not been disabled: %v", unitName, err)
+	}
+
+	if err := systemd.Connection().Reload(); err != nil {
+		log.Printf("slices: Unable to reload systemd: %v", err)
+	}
+
+	resp.Success(jobs.ResponseOk)
+}
+
+// A slice is in use while any container unit or child slice references it.
+func sliceInUse(id containers.SliceIdentifier) (bool, error) {
+	slices, err := containers.ListSlices()
+	if err != nil {
+		return false, err
+	}
+	for i := range slices {
+		if slices[i].Parent == id {
+			return true, nil
+		}
+	}
+	found := false
+	err = containers.WalkContainerUnits(func(ctr containers.Identifier) {
+		if slice, _ := containers.GetContainerSlice(ctr); slice == id {
+			found = true
+		}
+	})
+	return found, err
+}
+
+type ListSlicesRequest struct {
+	Label string
+}
+
+func (l *ListSlicesRequest) JobLabel() string {
+	return l.Label
+}
+
+type SliceResponse struct {
+	containers.SliceDescription
+	// Used by consumers
+	Server string `json:"Server,omitempty"`
+}
+type SliceResponses []SliceResponse
+
+func (c SliceResponses) Less(a, b int) bool {
+	return c[a].Id < c[b].Id
+}
+func (c SliceResponses) Len() int {
+	return len(c)
+}
+func (c SliceResponses) Swap(a, b int) {
+	c[a], c[b] = c[b], c[a]
+}
+
+type ListSlicesResponse struct {
+	Slices SliceResponses
+}
+
+func (r *ListSlicesResponse) Append(other *ListSlicesResponse) {
+	r.Slices = append(r.Slices, other.Slices...)
+}
+func (r *ListSlicesResponse) Sort() {
+	sort.Sort(r.Slices)
+}
+
+func (l *ListSlicesResponse) WriteTableTo(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 8, 4, 1, ' ', tabwriter.DiscardEmptyColumns)
+	if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", "SLICE", "SERVER", "PARENT", "MEMORY", "CPU", "TASKS"); err != nil {
+		return err
+	}
+	for i := range l.Slices {
+		slice := &l.Slices[i]
+		if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", slice.Id, slice.Server, slice.Parent, slice.MemoryLimit, limitString(slice.CPUShares), limitString(slice.TasksMax)); err != nil {
+			return err
+		}
+	}
+	tw.Flush()
+	return nil
+}
+
+func limitString(value uint64) string {
+	if value == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d", value)
+}
+
+func (j *ListSlicesRequest) Execute(resp jobs.Response) {
+	slices, err := containers.ListSlices()
+	if err != nil {
+		log.Printf("slices: Unable to list slices: %v", err)
+		resp.Failure(ErrListSlicesFailed)
+		return
+	}
+
+	r := &ListSlicesResponse{make(SliceResponses, 0, len(slices))}
+	for i := range slices {
+		r.Slices = append(r.Slices, SliceResponse{slices[i], ""})
+	}
+	r.Sort()
+	resp.SuccessWithData(jobs.ResponseOk, r)
+}
+
+// Move an installed container into a different slice.
+type ContainerSliceRequest struct {
+	jobs.RequestIdentifier `json:"-"`
+
+	Id    containers.Identifier `json:"-"`
+	Slice containers.SliceIdentifier
+}
+
+func (j *ContainerSliceRequest) Check() error {
+	if len(j.RequestIdentifier) == 0 {
+		return errors.New("A request identifier is required to change the slice of a container.")
+	}
+	slice, err := containers.NewSliceIdentifier(string(j.Slice))
+	if err != nil {
+		return err
+	}
+	j.Slice = slice
+	return nil
+}
+
+var reUnitSlice = regexp.MustCompile("(?m)^Slice=.*$")
+
+func (j *ContainerSliceRequest) Execute(resp jobs.Response) {
+	unitName := j.Id.UnitNameFor()
+	unitPath := j.Id.UnitPathFor()
+	unitVersionPath := j.Id.VersionedUnitPathFor(j.RequestIdentifier.String())
+
+	if _, err := os.Stat(j.Slice.UnitPathFor()); err != nil {
+		resp.Failure(ErrSliceNotFound)
+		return
+	}
+
+	state, exists, err := utils.OpenFileExclusive(unitPath, 0664)
+	if err != nil {
+		log.Print("container_slice: Unable to lock unit file: ", err)
+		resp.Failure(ErrContainerSliceFailed)
+		return
+	}
+	defer state.Close()
+	if !exists {
+		os.Remove(unitPath)
+		resp.Failure(ErrContainerNotFound)
+		return
+	}
+
+	existing, err := ioutil.ReadAll(state)
+	if err != nil {
+		log.Print("container_slice: Unable to read unit file: ", err)
+		resp.Failure(ErrContainerSliceFailed)
+		return
+	}
+	if !reUnitSlice.Match(existing) {
+		log.Printf("container_slice: Unit %s does not define a slice", unitName)
+		resp.Failure(ErrContainerSliceFailed)
+		return
+	}
+	changed := reUnitSlice.ReplaceAll(existing, []byte("Slice="+j.Slice.UnitNameFor()))
+
+	if err := utils.CreateFileOnce(unitVersionPath, changed, 0664); err != nil {
+		log.Print("container_slice: Unable to write unit file definition: ", err)
+		resp.Failure(ErrContainerSliceFailed)
+		return
+	}
+	if err := utils.AtomicReplaceLink(unitVersionPath, unitPath); err != nil {
+		log.Printf("container_slice: Failed to activate new unit: %+v", err)
+		resp.Failure(ErrContainerSliceFailed)
+		return
+	}
+	state.Close()
+
+	if err := systemd.EnableAndReloadUnit(systemd.Connection(), unitName, unitPath); err != nil {
+		log.Printf("container_slice: Could not enable container %s: %v", unitName, err)
+		resp.Failure(ErrContainerSliceFailed)
+		return
+	}
+
+	w := resp.SuccessWithWrite(jobs.ResponseAccepted, true, false)
+	if err := systemd.Connection().SetUnitProperties(unitName, true, dbus.PropSlice(j.Slice.UnitNameFor())); err != nil {
+		log.Printf("container_slice: Unable to move running container %s: %v", unitName, err)
+		fmt.Fprintf(w, "Container %s will be placed in %s when it is next restarted\n", j.Id, j.Slice)
+		return
+	}
+	fmt.Fprintf(w, "Container %s moved to %s\n", j.Id, j.Slice)
+}
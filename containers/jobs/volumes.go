@@ -0,0 +1,147 @@
+package jobs
+
+import (
+	"fmt"
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/jobs"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"text/tabwriter"
+)
+
+type PutVolumeRequest struct {
+	Id    containers.VolumeIdentifier
+	Label string
+}
+
+func (j *PutVolumeRequest) JobLabel() string {
+	return j.Label
+}
+
+func (j *PutVolumeRequest) Execute(resp jobs.Response) {
+	if err := j.Id.Create(); err != nil {
+		log.Printf("volumes: Unable to create volume %s: %v", j.Id, err)
+		resp.Failure(ErrVolumeCreateFailed)
+		return
+	}
+	resp.Success(jobs.ResponseOk)
+}
+
+type DeleteVolumeRequest struct {
+	Id    containers.VolumeIdentifier
+	Label string
+}
+
+func (j *DeleteVolumeRequest) JobLabel() string {
+	return j.Label
+}
+
+func (j *DeleteVolumeRequest) Execute(resp jobs.Response) {
+	path := j.Id.PathFor()
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		resp.Success(jobs.ResponseOk)
+		return
+	}
+
+	inUse, err := volumeInUse(j.Id)
+	if err != nil {
+		log.Printf("volumes: Unable to determine whether volume %s is in use: %v", j.Id, err)
+		resp.Failure(ErrVolumeDeleteFailed)
+		return
+	}
+	if inUse {
+		resp.Failure(ErrVolumeInUse)
+		return
+	}
+
+	if err := os.RemoveAll(path); err != nil {
+		log.Printf("volumes: Unable to remove volume %s: %v", j.Id, err)
+		resp.Failure(ErrVolumeDeleteFailed)
+		return
+	}
+
+	resp.Success(jobs.ResponseOk)
+}
+
+// A volume is in use while any installed container unit mounts it.
+func volumeInUse(id containers.VolumeIdentifier) (bool, error) {
+	found := false
+	err := containers.WalkContainerUnits(func(ctr containers.Identifier) {
+		mounts, _ := containers.GetContainerVolumes(ctr)
+		for i := range mounts {
+			if mounts[i].Name == id {
+				found = true
+			}
+		}
+	})
+	return found, err
+}
+
+type ListVolumesRequest struct {
+	Label string
+}
+
+func (l *ListVolumesRequest) JobLabel() string {
+	return l.Label
+}
+
+type VolumeResponse struct {
+	containers.VolumeDescription
+	// Used by consumers
+	Server string `json:"Server,omitempty"`
+}
+type VolumeResponses []VolumeResponse
+
+func (c VolumeResponses) Less(a, b int) bool {
+	return c[a].Id < c[b].Id
+}
+func (c VolumeResponses) Len() int {
+	return len(c)
+}
+func (c VolumeResponses) Swap(a, b int) {
+	c[a], c[b] = c[b], c[a]
+}
+
+type ListVolumesResponse struct {
+	Volumes VolumeResponses
+}
+
+func (r *ListVolumesResponse) Append(other *ListVolumesResponse) {
+	r.Volumes = append(r.Volumes, other.Volumes...)
+}
+func (r *ListVolumesResponse) Sort() {
+	sort.Sort(r.Volumes)
+}
+
+func (l *ListVolumesResponse) WriteTableTo(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 8, 4, 1, ' ', tabwriter.DiscardEmptyColumns)
+	if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\n", "VOLUME", "SERVER", "CREATED"); err != nil {
+		return err
+	}
+	for i := range l.Volumes {
+		volume := &l.Volumes[i]
+		if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\n", volume.Id, volume.Server, volume.Created.Format("2006-01-02 15:04:05")); err != nil {
+			return err
+		}
+	}
+	tw.Flush()
+	return nil
+}
+
+func (j *ListVolumesRequest) Execute(resp jobs.Response) {
+	volumes, err := containers.ListVolumes()
+	if err != nil {
+		log.Printf("volumes: Unable to list volumes: %v", err)
+		resp.Failure(ErrListVolumesFailed)
+		return
+	}
+
+	r := &ListVolumesResponse{make(VolumeResponses, 0, len(volumes))}
+	for i := range volumes {
+		r.Volumes = append(r.Volumes, VolumeResponse{volumes[i], ""})
+	}
+	r.Sort()
+	resp.SuccessWithData(jobs.ResponseOk, r)
+}
@@ -0,0 +1,182 @@
+package containers
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Free-form key/value pairs attached to a container so that groups of
+// containers can be selected together.
+type Labels map[string]string
+
+var allowedLabelKey = regexp.MustCompile("\\A[a-zA-Z0-9][a-zA-Z0-9\\-_\\.]{0,62}\\z")
+var allowedLabelValue = regexp.MustCompile("\\A[a-zA-Z0-9\\-_\\.]{0,63}\\z")
+
+func checkLabel(key, value string) error {
+	if !allowedLabelKey.MatchString(key) {
+		return errors.New(fmt.Sprintf("The label key '%s' must match %s", key, allowedLabelKey.String()))
+	}
+	if !allowedLabelValue.MatchString(value) {
+		return errors.New(fmt.Sprintf("The value of label %s must match %s", key, allowedLabelValue.String()))
+	}
+	return nil
+}
+
+func (l Labels) Check() error {
+	for key, value := range l {
+		if err := checkLabel(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Add a label of the form <key>=<value>
+func (l Labels) Set(s string) error {
+	pair := strings.SplitN(s, "=", 2)
+	if len(pair) != 2 {
+		return errors.New(fmt.Sprintf("The label '%s' must be of the form <key>=<value>", s))
+	}
+	if err := checkLabel(pair[0], pair[1]); err != nil {
+		return err
+	}
+	l[pair[0]] = pair[1]
+	return nil
+}
+
+func (l Labels) String() string {
+	keys := make([]string, 0, len(l))
+	for key := range l {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + "=" + l[key]
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Return the labels recorded in the container unit.
+func GetContainerLabels(id Identifier) (Labels, error) {
+	f, err := os.Open(id.UnitPathFor())
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readLabelsFromUnitFile(f)
+}
+
+func readLabelsFromUnitFile(r io.Reader) (Labels, error) {
+	labels := Labels{}
+	scan := bufio.NewScanner(r)
+	for scan.Scan() {
+		line := scan.Text()
+		if strings.HasPrefix(line, "X-Label-") {
+			if err := labels.Set(strings.TrimPrefix(line, "X-Label-")); err != nil {
+				continue
+			}
+		}
+	}
+	if err := scan.Err(); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+type labelOperator string
+
+const (
+	labelEquals    labelOperator = "="
+	labelNotEquals labelOperator = "!="
+	labelExists    labelOperator = ""
+	labelMissing   labelOperator = "!"
+)
+
+type labelRequirement struct {
+	Key      string
+	Operator labelOperator
+	Value    string
+}
+
+// A comma separated list of requirements that must all hold for a set
+// of labels to match: <key>=<value>, <key>!=<value>, <key> (the label
+// is set) or !<key> (the label is not set).  An empty selector matches
+// everything.
+type LabelSelector []labelRequirement
+
+func NewLabelSelector(s string) (LabelSelector, error) {
+	selector := LabelSelector{}
+	if strings.TrimSpace(s) == "" {
+		return selector, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		var r labelRequirement
+		switch {
+		case strings.Contains(part, "!="):
+			pair := strings.SplitN(part, "!=", 2)
+			r = labelRequirement{pair[0], labelNotEquals, pair[1]}
+		case strings.Contains(part, "="):
+			pair := strings.SplitN(strings.Replace(part, "==", "=", 1), "=", 2)
+			r = labelRequirement{pair[0], labelEquals, pair[1]}
+		case strings.HasPrefix(part, "!"):
+			r = labelRequirement{strings.TrimPrefix(part, "!"), labelMissing, ""}
+		default:
+			r = labelRequirement{part, labelExists, ""}
+		}
+		if err := checkLabel(r.Key, r.Value); err != nil {
+			return nil, errors.New(fmt.Sprintf("The selector '%s' is not valid: %s", part, err.Error()))
+		}
+		selector = append(selector, r)
+	}
+	return selector, nil
+}
+
+func (s LabelSelector) Empty() bool {
+	return len(s) == 0
+}
+
+func (s LabelSelector) Matches(labels Labels) bool {
+	for _, r := range s {
+		value, ok := labels[r.Key]
+		switch r.Operator {
+		case labelEquals:
+			if !ok || value != r.Value {
+				return false
+			}
+		case labelNotEquals:
+			if ok && value == r.Value {
+				return false
+			}
+		case labelExists:
+			if !ok {
+				return false
+			}
+		case labelMissing:
+			if ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (s LabelSelector) String() string {
+	parts := make([]string, len(s))
+	for i, r := range s {
+		switch r.Operator {
+		case labelMissing:
+			parts[i] = "!" + r.Key
+		default:
+			parts[i] = r.Key + string(r.Operator) + r.Value
+		}
+	}
+	return strings.Join(parts, ",")
+}
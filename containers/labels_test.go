@@ -0,0 +1,51 @@
+package containers
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLabelSelector(t *testing.T) {
+	labels := Labels{"app": "shop", "tier": "web"}
+	for s, expected := range map[string]bool{
+		"":                   true,
+		"app=shop":           true,
+		"app==shop,tier":     true,
+		"app=shop,tier!=web": false,
+		"app!=blog":          true,
+		"!canary":            true,
+		"canary":             false,
+	} {
+		selector, err := NewLabelSelector(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if selector.Matches(labels) != expected {
+			t.Errorf("Expected selector %q to match %t", s, expected)
+		}
+	}
+	for _, s := range []string{"=shop", "app=a b", "app=shop,,tier", "tier = web"} {
+		if _, err := NewLabelSelector(s); err == nil {
+			t.Errorf("Expected %q to be an invalid selector", s)
+		}
+	}
+}
+
+func TestLabelsInUnitFile(t *testing.T) {
+	unit := ContainerUnit{
+		Id:     Identifier("test"),
+		Image:  "busybox",
+		Labels: Labels{"app": "shop", "tier": "web"},
+	}
+	buf := &bytes.Buffer{}
+	if err := ContainerUnitTemplate.ExecuteTemplate(buf, "SIMPLE", unit); err != nil {
+		t.Fatal(err)
+	}
+	labels, err := readLabelsFromUnitFile(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if labels.String() != "app=shop,tier=web" {
+		t.Errorf("Unexpected labels read from unit: %s", labels)
+	}
+}
@@ -1,12 +1,15 @@
 package containers
 
 import (
+	"bufio"
 	"bytes"
 	"errors"
 	"fmt"
 	"github.com/openshift/geard/port"
+	"io"
 	"log"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
 )
@@ -16,10 +19,15 @@ type NetworkLink struct {
 	FromPort port.Port
 	ToPort   port.Port `json:"ToPort,omitempty"`
 	ToHost   string    `json:"ToHost,omitempty"`
+	// Names the LINK_<NAME>_HOST and LINK_<NAME>_PORT variables
+	// available to the container environment
+	Name string `json:"Name,omitempty"`
 }
 
 type NetworkLinks []NetworkLink
 
+var allowedLinkName = regexp.MustCompile("\\A[a-zA-Z0-9_\\-\\.]{1,64}\\z")
+
 func (n *NetworkLink) Check() error {
 	if err := n.FromPort.Check(); err != nil {
 		return errors.New("The from port value must be a positive integer less than 65536")
@@ -29,9 +37,20 @@ func (n *NetworkLink) Check() error {
 			return errors.New("The to port value must be a positive integer less than 65536 or zero")
 		}
 	}
+	if n.Name != "" && !allowedLinkName.MatchString(n.Name) {
+		return errors.New("The link name must match " + allowedLinkName.String())
+	}
 	return nil
 }
 
+var linkVariableReplacer = strings.NewReplacer("-", "_", ".", "_")
+
+// The prefix of the variables describing the link, e.g. DB_MAIN for a
+// link named 'db.main'.
+func (n *NetworkLink) VariablePrefix() string {
+	return strings.ToUpper(linkVariableReplacer.Replace(n.Name))
+}
+
 func (n *NetworkLink) Complete() bool {
 	return n.ToPort >= 1 && n.ToHost != ""
 }
@@ -63,11 +82,9 @@ func (n NetworkLinks) Write(path string, appends bool) error {
 	}
 	defer file.Close()
 
-	for i := range n {
-		if _, errw := fmt.Fprintf(file, "%s\t%d\t%d\t%s\n", n[i].FromHost, n[i].FromPort, n[i].ToPort, n[i].ToHost); errw != nil {
-			log.Print("network_links: Unable to write network links: ", err)
-			return err
-		}
+	if _, errw := n.WriteTo(file); errw != nil {
+		log.Print("network_links: Unable to write network links: ", errw)
+		return errw
 	}
 	if errc := file.Close(); errc != nil {
 		log.Print("network_links: Unable to network links: ", errc)
@@ -76,6 +93,71 @@ func (n NetworkLinks) Write(path string, appends bool) error {
 	return nil
 }
 
+// Write the links in the form read when the links are applied to a
+// container.
+func (n NetworkLinks) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+	for i := range n {
+		line := fmt.Sprintf("%s\t%d\t%d\t%s", n[i].FromHost, n[i].FromPort, n[i].ToPort, n[i].ToHost)
+		if n[i].Name != "" {
+			line += "\t" + n[i].Name
+		}
+		c, err := fmt.Fprintln(w, line)
+		written += int64(c)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// Read the links written by WriteTo, skipping any that are not valid.
+func ReadNetworkLinksFrom(r io.Reader) (NetworkLinks, error) {
+	links := NetworkLinks{}
+	scan := bufio.NewScanner(r)
+	for scan.Scan() {
+		fields := strings.Split(scan.Text(), "\t")
+		if len(fields) < 4 {
+			continue
+		}
+		link := NetworkLink{FromHost: fields[0], ToHost: fields[3]}
+		from, errf := strconv.Atoi(fields[1])
+		to, errt := strconv.Atoi(fields[2])
+		if errf != nil || errt != nil {
+			log.Printf("network_links: Could not read link %q", scan.Text())
+			continue
+		}
+		link.FromPort, link.ToPort = port.Port(from), port.Port(to)
+		if len(fields) > 4 {
+			link.Name = fields[4]
+		}
+		if err := link.Check(); err != nil {
+			log.Printf("network_links: Link in file is not valid: %v", err)
+			continue
+		}
+		links = append(links, link)
+	}
+	return links, scan.Err()
+}
+
+// The host and port of the first link of each name, as seen from inside
+// the container.
+func (n NetworkLinks) Variables() map[string]string {
+	vars := make(map[string]string)
+	for i := range n {
+		if n[i].Name == "" {
+			continue
+		}
+		prefix := "LINK_" + n[i].VariablePrefix()
+		if _, found := vars[prefix+"_HOST"]; found {
+			continue
+		}
+		vars[prefix+"_HOST"] = n[i].FromHost
+		vars[prefix+"_PORT"] = strconv.Itoa(int(n[i].FromPort))
+	}
+	return vars
+}
+
 func (n NetworkLinks) String() string {
 	var pairs bytes.Buffer
 	for i := range n {
@@ -107,6 +189,10 @@ func NewNetworkLinksFromString(s string) (NetworkLinks, error) {
 }
 
 func NewNetworkLinkFromString(s string) (*NetworkLink, error) {
+	var name string
+	if i := strings.Index(s, "="); i != -1 {
+		name, s = s[:i], s[i+1:]
+	}
 	value := strings.Split(s, ":")
 	if len(value) < 3 {
 		return nil, errors.New(fmt.Sprintf("The network link '%s' must be of the form <from_host>:<from_port>:<to_host>:<to_port> where <from_host> is optional", s))
@@ -117,7 +203,7 @@ func NewNetworkLinkFromString(s string) (*NetworkLink, error) {
 		value = append([]string{"127.0.0.1"}, value...)
 	}
 
-	link := NetworkLink{}
+	link := NetworkLink{Name: name}
 	link.FromHost = value[0]
 	from_port, err := strconv.Atoi(value[1])
 	if err != nil {
@@ -138,6 +224,9 @@ func NewNetworkLinkFromString(s string) (*NetworkLink, error) {
 			return nil, errors.New("To port value must be between 0 and 65535")
 		}
 	}
+	if err := link.Check(); err != nil {
+		return nil, err
+	}
 	return &link, nil
 }
 
@@ -147,6 +236,10 @@ func (n NetworkLinks) ToCompact() string {
 		if i != 0 {
 			pairs.WriteString(",")
 		}
+		if n[i].Name != "" {
+			pairs.WriteString(n[i].Name)
+			pairs.WriteString("=")
+		}
 		pairs.WriteString(n[i].FromHost)
 		pairs.WriteString(":")
 		pairs.WriteString(strconv.Itoa(int(n[i].FromPort)))
@@ -0,0 +1,187 @@
+package containers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/openshift/geard/config"
+	"github.com/openshift/geard/utils"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// A named set of restrictions applied to the processes of the
+// containers installed with it.
+type ProfileIdentifier string
+
+var InvalidProfileIdentifier = ProfileIdentifier("")
+var allowedProfileIdentifier = regexp.MustCompile("\\A[a-zA-Z0-9\\-]{1,64}\\z")
+
+var allowedCapability = regexp.MustCompile("\\A(ALL|[A-Z][A-Z_]{1,31})\\z")
+var allowedSeccompPath = regexp.MustCompile("\\A/[a-zA-Z0-9\\-_\\./]+\\z")
+var allowedSELinuxType = regexp.MustCompile("\\A[a-z0-9_]{1,64}_t\\z")
+
+func NewProfileIdentifier(s string) (ProfileIdentifier, error) {
+	switch {
+	case s == "":
+		return InvalidProfileIdentifier, errors.New("Profile name may not be empty")
+	case !allowedProfileIdentifier.MatchString(s):
+		return InvalidProfileIdentifier, errors.New("Profile name must match " + allowedProfileIdentifier.String())
+	}
+	return ProfileIdentifier(s), nil
+}
+
+func ProfilesPath() string {
+	return filepath.Join(config.ContainerBasePath(), "profiles")
+}
+
+func (p ProfileIdentifier) DescriptionPathFor() string {
+	return filepath.Join(ProfilesPath(), string(p)+".json")
+}
+
+// The capabilities, seccomp filter, root filesystem and SELinux type
+// of a container.  A container keeps the options of its profile at the
+// time it was installed.
+type SecurityProfile struct {
+	Id ProfileIdentifier
+	// Capabilities removed from and added to the Docker defaults, e.g.
+	// ALL or NET_RAW
+	CapDrop []string `json:"CapDrop,omitempty"`
+	CapAdd  []string `json:"CapAdd,omitempty"`
+	// The path of a seccomp profile on the host, or 'unconfined'
+	Seccomp         string `json:"Seccomp,omitempty"`
+	ReadOnlyRootfs  bool   `json:"ReadOnlyRootfs,omitempty"`
+	NoNewPrivileges bool   `json:"NoNewPrivileges,omitempty"`
+	// The SELinux type the processes of the container run as
+	SELinuxType string `json:"SELinuxType,omitempty"`
+}
+
+func (p *SecurityProfile) Check() error {
+	if _, err := NewProfileIdentifier(string(p.Id)); err != nil {
+		return err
+	}
+	for i := range p.CapDrop {
+		p.CapDrop[i] = strings.TrimPrefix(strings.ToUpper(p.CapDrop[i]), "CAP_")
+		if !allowedCapability.MatchString(p.CapDrop[i]) {
+			return errors.New(fmt.Sprintf("The capability '%s' is not valid", p.CapDrop[i]))
+		}
+	}
+	for i := range p.CapAdd {
+		p.CapAdd[i] = strings.TrimPrefix(strings.ToUpper(p.CapAdd[i]), "CAP_")
+		if !allowedCapability.MatchString(p.CapAdd[i]) {
+			return errors.New(fmt.Sprintf("The capability '%s' is not valid", p.CapAdd[i]))
+		}
+	}
+	if p.Seccomp != "" && p.Seccomp != "unconfined" && !allowedSeccompPath.MatchString(p.Seccomp) {
+		return errors.New("The seccomp profile must be an absolute path on the host or 'unconfined'")
+	}
+	if p.SELinuxType != "" && !allowedSELinuxType.MatchString(p.SELinuxType) {
+		return errors.New("The SELinux type must match " + allowedSELinuxType.String())
+	}
+	return nil
+}
+
+// The arguments to docker run that apply the profile.
+func (p *SecurityProfile) DockerSpec() string {
+	var spec bytes.Buffer
+	for i := range p.CapDrop {
+		spec.WriteString("--cap-drop " + p.CapDrop[i] + " ")
+	}
+	for i := range p.CapAdd {
+		spec.WriteString("--cap-add " + p.CapAdd[i] + " ")
+	}
+	if p.Seccomp != "" {
+		spec.WriteString("--security-opt seccomp=" + p.Seccomp + " ")
+	}
+	if p.ReadOnlyRootfs {
+		spec.WriteString("--read-only ")
+	}
+	if p.NoNewPrivileges {
+		spec.WriteString("--security-opt no-new-privileges ")
+	}
+	if p.SELinuxType != "" {
+		spec.WriteString("--security-opt label=type:" + p.SELinuxType + " ")
+	}
+	return spec.String()
+}
+
+func (p *SecurityProfile) Write() error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return utils.WriteToPathExclusive(p.Id.DescriptionPathFor(), bytes.NewBuffer(data), 0660)
+}
+
+func ReadSecurityProfile(id ProfileIdentifier) (*SecurityProfile, error) {
+	data, err := ioutil.ReadFile(id.DescriptionPathFor())
+	if err != nil {
+		return nil, err
+	}
+	p := &SecurityProfile{}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, err
+	}
+	p.Id = id
+	return p, nil
+}
+
+type SecurityProfiles []SecurityProfile
+
+func (s SecurityProfiles) Less(a, b int) bool {
+	return s[a].Id < s[b].Id
+}
+func (s SecurityProfiles) Len() int {
+	return len(s)
+}
+func (s SecurityProfiles) Swap(a, b int) {
+	s[a], s[b] = s[b], s[a]
+}
+
+func ListSecurityProfiles() (SecurityProfiles, error) {
+	infos, err := ioutil.ReadDir(ProfilesPath())
+	if err != nil {
+		return nil, err
+	}
+	profiles := make(SecurityProfiles, 0, len(infos))
+	for _, info := range infos {
+		if info.IsDir() || filepath.Ext(info.Name()) != ".json" {
+			continue
+		}
+		id, err := NewProfileIdentifier(strings.TrimSuffix(info.Name(), ".json"))
+		if err != nil {
+			continue
+		}
+		p, err := ReadSecurityProfile(id)
+		if err != nil {
+			continue
+		}
+		profiles = append(profiles, *p)
+	}
+	sort.Sort(profiles)
+	return profiles, nil
+}
+
+// Return the security profile recorded in the container unit, if any.
+func GetContainerSecurityProfile(id Identifier) (ProfileIdentifier, error) {
+	f, err := os.Open(id.UnitPathFor())
+	if err != nil {
+		return InvalidProfileIdentifier, err
+	}
+	defer f.Close()
+
+	scan := bufio.NewScanner(f)
+	for scan.Scan() {
+		line := strings.TrimSpace(scan.Text())
+		if strings.HasPrefix(line, "X-SecurityProfile=") {
+			return ProfileIdentifier(strings.TrimPrefix(line, "X-SecurityProfile=")), nil
+		}
+	}
+	return InvalidProfileIdentifier, scan.Err()
+}
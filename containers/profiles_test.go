@@ -0,0 +1,35 @@
+package containers
+
+import (
+	"testing"
+)
+
+func TestSecurityProfileDockerSpec(t *testing.T) {
+	p := &SecurityProfile{
+		Id:              ProfileIdentifier("locked"),
+		CapDrop:         []string{"all"},
+		CapAdd:          []string{"CAP_NET_BIND_SERVICE"},
+		Seccomp:         "/etc/geard/seccomp.json",
+		ReadOnlyRootfs:  true,
+		NoNewPrivileges: true,
+		SELinuxType:     "svirt_lxc_net_t",
+	}
+	if err := p.Check(); err != nil {
+		t.Fatal(err)
+	}
+	spec := "--cap-drop ALL --cap-add NET_BIND_SERVICE --security-opt seccomp=/etc/geard/seccomp.json --read-only --security-opt no-new-privileges --security-opt label=type:svirt_lxc_net_t "
+	if p.DockerSpec() != spec {
+		t.Errorf("Expected docker spec %q, got %q", spec, p.DockerSpec())
+	}
+
+	for _, invalid := range []*SecurityProfile{
+		{Id: ProfileIdentifier("a b")},
+		{Id: ProfileIdentifier("a"), CapAdd: []string{"NET RAW"}},
+		{Id: ProfileIdentifier("a"), Seccomp: "seccomp.json"},
+		{Id: ProfileIdentifier("a"), SELinuxType: "svirt_lxc_net"},
+	} {
+		if err := invalid.Check(); err == nil {
+			t.Errorf("Expected profile %+v to be rejected", invalid)
+		}
+	}
+}
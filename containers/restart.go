@@ -0,0 +1,60 @@
+package containers
+
+import (
+	"errors"
+	"fmt"
+)
+
+type RestartPolicyType string
+
+const (
+	RestartNever     RestartPolicyType = "no"
+	RestartOnFailure RestartPolicyType = "on-failure"
+	RestartAlways    RestartPolicyType = "always"
+)
+
+const (
+	DefaultRestartBackoff     = 5
+	DefaultRestartMaxAttempts = 5
+)
+
+// How systemd should react when the container process exits.  If the
+// container is restarted more than MaxAttempts times within the start
+// limit interval, systemd stops restarting it and the unit fails with a
+// start-limit result (reported as a CrashLooping event).
+type RestartPolicy struct {
+	Policy RestartPolicyType
+	// Seconds to wait before each restart
+	Backoff int `json:"Backoff,omitempty"`
+	// Restarts allowed within the start limit interval
+	MaxAttempts int `json:"MaxAttempts,omitempty"`
+}
+
+func (r *RestartPolicy) Check() error {
+	switch r.Policy {
+	case RestartNever, RestartOnFailure, RestartAlways:
+	default:
+		return errors.New(fmt.Sprintf("Unrecognized restart policy '%s', must be one of no, on-failure, or always", r.Policy))
+	}
+	if r.Backoff < 0 || r.MaxAttempts < 0 {
+		return errors.New("Restart backoff and maximum attempts may not be negative")
+	}
+	if r.Backoff == 0 {
+		r.Backoff = DefaultRestartBackoff
+	}
+	if r.MaxAttempts == 0 {
+		r.MaxAttempts = DefaultRestartMaxAttempts
+	}
+	return nil
+}
+
+// The window in seconds over which restarts are counted.  It must be long
+// enough to contain MaxAttempts restarts separated by Backoff, otherwise
+// the limit could never be reached.
+func (r *RestartPolicy) StartLimitInterval() int {
+	interval := 2 * r.MaxAttempts * r.Backoff
+	if interval < 60 {
+		interval = 60
+	}
+	return interval
+}
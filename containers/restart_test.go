@@ -0,0 +1,80 @@
+package containers
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRestartPolicyCheck(t *testing.T) {
+	for _, c := range []struct {
+		policy  RestartPolicy
+		valid   bool
+		backoff int
+		max     int
+	}{
+		{RestartPolicy{Policy: RestartAlways}, true, DefaultRestartBackoff, DefaultRestartMaxAttempts},
+		{RestartPolicy{Policy: RestartOnFailure, Backoff: 2, MaxAttempts: 10}, true, 2, 10},
+		{RestartPolicy{Policy: RestartNever}, true, DefaultRestartBackoff, DefaultRestartMaxAttempts},
+		{RestartPolicy{Policy: "sometimes"}, false, 0, 0},
+		{RestartPolicy{}, false, 0, 0},
+		{RestartPolicy{Policy: RestartAlways, Backoff: -1}, false, 0, 0},
+		{RestartPolicy{Policy: RestartAlways, MaxAttempts: -1}, false, 0, 0},
+	} {
+		policy := c.policy
+		err := policy.Check()
+		if (err == nil) != c.valid {
+			t.Errorf("Expected %+v to be valid=%t: %v", c.policy, c.valid, err)
+			continue
+		}
+		if c.valid && (policy.Backoff != c.backoff || policy.MaxAttempts != c.max) {
+			t.Errorf("Expected %+v to default to a backoff of %d and %d attempts, got %+v", c.policy, c.backoff, c.max, policy)
+		}
+	}
+}
+
+func TestRestartPolicyStartLimitInterval(t *testing.T) {
+	for _, c := range []struct {
+		policy   RestartPolicy
+		interval int
+	}{
+		{RestartPolicy{Backoff: 5, MaxAttempts: 5}, 60},
+		{RestartPolicy{Backoff: 10, MaxAttempts: 6}, 120},
+	} {
+		if interval := c.policy.StartLimitInterval(); interval != c.interval {
+			t.Errorf("Expected %+v to have a start limit interval of %d, got %d", c.policy, c.interval, interval)
+		}
+	}
+}
+
+func TestRestartPolicyInUnitFile(t *testing.T) {
+	unit := ContainerUnit{
+		Id:      Identifier("test"),
+		Image:   "busybox",
+		Restart: &RestartPolicy{Policy: RestartOnFailure, Backoff: 10, MaxAttempts: 6},
+	}
+	buf := &bytes.Buffer{}
+	if err := ContainerUnitTemplate.ExecuteTemplate(buf, "SIMPLE", unit); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	for _, line := range []string{
+		"Restart=on-failure\n",
+		"RestartSec=10\n",
+		"StartLimitInterval=120\n",
+		"StartLimitBurst=6\n",
+	} {
+		if !strings.Contains(s, line) {
+			t.Errorf("Expected %q in unit: %s", line, s)
+		}
+	}
+
+	unit.Restart = nil
+	buf.Reset()
+	if err := ContainerUnitTemplate.ExecuteTemplate(buf, "SIMPLE", unit); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "Restart=") || strings.Contains(buf.String(), "StartLimitBurst=") {
+		t.Errorf("Expected no restart policy in unit: %s", buf.String())
+	}
+}
@@ -0,0 +1,213 @@
+package containers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/openshift/geard/config"
+	"github.com/openshift/geard/utils"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func RunsPath() string {
+	return filepath.Join(config.ContainerBasePath(), "jobs")
+}
+
+// The name of the docker container of a one-off run
+func (j JobIdentifier) ContainerFor() string {
+	return fmt.Sprintf("job-%s", safeUnitName(j))
+}
+
+func (j JobIdentifier) RunPathFor() string {
+	return filepath.Join(RunsPath(), safeUnitName(j)+".json")
+}
+
+func (j JobIdentifier) ResultPathFor() string {
+	return filepath.Join(RunsPath(), safeUnitName(j)+".result")
+}
+
+// The variables of a run passed to docker, which exists only while the
+// run does
+func (j JobIdentifier) EnvironmentPathFor() string {
+	return filepath.Join(RunsPath(), safeUnitName(j)+".env")
+}
+
+// A one-off run of an image.  The container is removed when it exits,
+// and its exit code and output remain available from the result.
+type ContainerRun struct {
+	Name      string
+	Image     string
+	Command   string
+	Arguments []string
+
+	// Variables set in the container, and a stored environment
+	// whose variables are set before them
+	Environment   EnvironmentVariables `json:"Environment,omitempty"`
+	EnvironmentId Identifier           `json:"EnvironmentId,omitempty"`
+
+	// Named volumes mounted into the container
+	Volumes VolumeMounts `json:"Volumes,omitempty"`
+	// Loopback addresses inside the container that are forwarded
+	// to other hosts
+	NetworkLinks NetworkLinks `json:"NetworkLinks,omitempty"`
+
+	// Seconds the container may run before it is stopped, 0 for
+	// no limit
+	Timeout int `json:"Timeout,omitempty"`
+	// The slice the run is placed in, defaults to the root
+	// container slice
+	Slice SliceIdentifier `json:"Slice,omitempty"`
+}
+
+func (r *ContainerRun) Check() error {
+	for i := range r.Environment {
+		if err := r.Environment[i].Check(); err != nil {
+			return err
+		}
+		// docker reads an environment file one variable per line
+		if strings.ContainsAny(r.Environment[i].Name+r.Environment[i].Value, "\r\n") {
+			return errors.New(fmt.Sprintf("The variable %s may not contain a line break", r.Environment[i].Name))
+		}
+	}
+	if r.EnvironmentId != "" {
+		if _, err := NewIdentifier(string(r.EnvironmentId)); err != nil {
+			return err
+		}
+	}
+	if err := r.Volumes.Check(); err != nil {
+		return err
+	}
+	if err := r.NetworkLinks.Check(); err != nil {
+		return err
+	}
+	if r.Timeout < 0 {
+		return errors.New("The timeout of a run may not be negative")
+	}
+	if r.Slice == "" {
+		r.Slice = RootSlice
+	}
+	slice, err := NewSliceIdentifier(string(r.Slice))
+	if err != nil {
+		return err
+	}
+	r.Slice = slice
+	return nil
+}
+
+// The docker command that runs the container in the foreground.  The
+// variables of the run are read from the file written by
+// WriteEnvironment, so that their values are not visible in the
+// arguments of the process.
+func (r *ContainerRun) DockerCommand() []string {
+	id := JobIdentifier(r.Name)
+	command := []string{
+		"/usr/bin/docker", "run",
+		"--rm", "--name", id.ContainerFor(),
+		"-a", "stdout", "-a", "stderr",
+	}
+	if r.EnvironmentId != "" {
+		command = append(command, "--env-file", r.EnvironmentId.EnvironmentPathFor())
+	}
+	if len(r.Environment) > 0 {
+		command = append(command, "--env-file", id.EnvironmentPathFor())
+	}
+	for i := range r.Volumes {
+		command = append(command, "-v", r.Volumes[i].BindSpec())
+	}
+	if r.Command != "" {
+		command = append(command, "--entrypoint", r.Command)
+	}
+	command = append(command, r.Image)
+	return append(command, r.Arguments...)
+}
+
+// Write the variables of the run to a file only root may read, which
+// the caller removes when the run exits.
+func (r *ContainerRun) WriteEnvironment() error {
+	if len(r.Environment) == 0 {
+		return nil
+	}
+	return r.writeEnvironmentTo(JobIdentifier(r.Name).EnvironmentPathFor())
+}
+
+func (r *ContainerRun) writeEnvironmentTo(path string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	for i := range r.Environment {
+		if _, err := fmt.Fprintf(file, "%s=%s\n", r.Environment[i].Name, r.Environment[i].Value); err != nil {
+			return err
+		}
+	}
+	return file.Close()
+}
+
+func (r *ContainerRun) Write() error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return utils.WriteToPathExclusive(JobIdentifier(r.Name).RunPathFor(), bytes.NewBuffer(data), 0660)
+}
+
+func ReadContainerRun(id JobIdentifier) (*ContainerRun, error) {
+	data, err := ioutil.ReadFile(id.RunPathFor())
+	if err != nil {
+		return nil, err
+	}
+	r := &ContainerRun{}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+const (
+	RunPending  = "pending"
+	RunRunning  = "running"
+	RunSuccess  = "success"
+	RunExitCode = "exit-code"
+	RunTimeout  = "timeout"
+	RunFailed   = "failed"
+)
+
+// The outcome of a run.  The output of the run follows the journal
+// entry identified by Cursor.
+type ContainerRunResult struct {
+	Result   string
+	ExitCode int        `json:"ExitCode"`
+	Started  time.Time  `json:"Started,omitempty"`
+	Finished *time.Time `json:"Finished,omitempty"`
+	Cursor   string     `json:"Cursor,omitempty"`
+}
+
+func (r *ContainerRunResult) Write(id JobIdentifier) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return utils.WriteToPathExclusive(id.ResultPathFor(), bytes.NewBuffer(data), 0660)
+}
+
+// Return the result of a run, which is pending until the run starts.
+func ReadContainerRunResult(id JobIdentifier) (*ContainerRunResult, error) {
+	data, err := ioutil.ReadFile(id.ResultPathFor())
+	if os.IsNotExist(err) {
+		return &ContainerRunResult{Result: RunPending}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	r := &ContainerRunResult{}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
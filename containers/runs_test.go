@@ -0,0 +1,69 @@
+package containers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestContainerRunDockerCommand(t *testing.T) {
+	r := ContainerRun{
+		Name:          "abc",
+		Image:         "busybox",
+		Command:       "/bin/sh",
+		Arguments:     []string{"-c", "exit 3"},
+		Environment:   EnvironmentVariables{{Name: "DB", Value: "test"}},
+		EnvironmentId: Identifier("ci-env"),
+		Volumes:       VolumeMounts{{Name: "cache", Path: "/cache", ReadOnly: true}},
+	}
+	if err := r.Check(); err != nil {
+		t.Fatal(err)
+	}
+	if r.Slice != RootSlice {
+		t.Errorf("Expected the run to default to the root slice, got %s", r.Slice)
+	}
+
+	command := strings.Join(r.DockerCommand(), " ")
+	for _, part := range []string{
+		"/usr/bin/docker run --rm --name job-YWJj -a stdout -a stderr ",
+		"--env-file " + Identifier("ci-env").EnvironmentPathFor() + " --env-file " + JobIdentifier("abc").EnvironmentPathFor() + " ",
+		"-v " + VolumeIdentifier("cache").PathFor() + ":/cache:ro ",
+		"--entrypoint /bin/sh busybox -c exit 3",
+	} {
+		if !strings.Contains(command, part) {
+			t.Errorf("Expected %q in command: %s", part, command)
+		}
+	}
+	if strings.Contains(command, "test") {
+		t.Errorf("Expected the values of the variables to be kept out of the command: %s", command)
+	}
+
+	dir, err := ioutil.TempDir("", "geard-run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "run.env")
+	if err := r.writeEnvironmentTo(path); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the environment of the run to be readable only by its owner: %v %v", err, info)
+	}
+	if data, err := ioutil.ReadFile(path); err != nil || string(data) != "DB=test\n" {
+		t.Errorf("Unexpected environment of the run %q: %v", data, err)
+	}
+
+	r.Environment = EnvironmentVariables{{Name: "DB", Value: "a\nB=b"}}
+	if err := r.Check(); err == nil {
+		t.Errorf("Expected a variable with a line break to be invalid")
+	}
+	r.Environment = nil
+
+	r.Timeout = -1
+	if err := r.Check(); err == nil {
+		t.Errorf("Expected a negative timeout to be invalid")
+	}
+}
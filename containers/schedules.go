@@ -0,0 +1,323 @@
+package containers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/openshift/geard/config"
+	"github.com/openshift/geard/utils"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The number of past runs kept for each scheduled job.
+const ScheduleRunHistory = 50
+
+// A container image run on a schedule by a systemd timer.
+type ScheduleIdentifier string
+
+var InvalidScheduleIdentifier = ScheduleIdentifier("")
+var allowedScheduleIdentifier = regexp.MustCompile("\\A[a-zA-Z0-9\\-]{1,64}\\z")
+
+func NewScheduleIdentifier(s string) (ScheduleIdentifier, error) {
+	switch {
+	case s == "":
+		return InvalidScheduleIdentifier, errors.New("Schedule name may not be empty")
+	case !allowedScheduleIdentifier.MatchString(s):
+		return InvalidScheduleIdentifier, errors.New("Schedule name must match " + allowedScheduleIdentifier.String())
+	}
+	return ScheduleIdentifier(s), nil
+}
+
+func SchedulesPath() string {
+	return filepath.Join(config.ContainerBasePath(), "schedules")
+}
+
+func (s ScheduleIdentifier) UnitNameFor() string {
+	return fmt.Sprintf("sched-%s.service", s)
+}
+
+func (s ScheduleIdentifier) UnitPathFor() string {
+	return filepath.Join(SchedulesPath(), s.UnitNameFor())
+}
+
+func (s ScheduleIdentifier) TimerUnitNameFor() string {
+	return fmt.Sprintf("sched-%s.timer", s)
+}
+
+func (s ScheduleIdentifier) TimerUnitPathFor() string {
+	return filepath.Join(SchedulesPath(), s.TimerUnitNameFor())
+}
+
+func (s ScheduleIdentifier) DescriptionPathFor() string {
+	return filepath.Join(SchedulesPath(), string(s)+".json")
+}
+
+func (s ScheduleIdentifier) RunsPathFor() string {
+	return filepath.Join(SchedulesPath(), string(s)+".runs")
+}
+
+func (s ScheduleIdentifier) currentRunPathFor() string {
+	return filepath.Join(SchedulesPath(), string(s)+".run")
+}
+
+// An image and command run whenever the calendar specification (see
+// systemd.time(7)) elapses.
+type ScheduleDescription struct {
+	Id        ScheduleIdentifier
+	Calendar  string
+	Image     string
+	Command   string   `json:"Command,omitempty"`
+	Arguments []string `json:"Arguments,omitempty"`
+
+	// Run once at startup if a run was missed while the host was down
+	Persistent bool `json:"Persistent,omitempty"`
+}
+
+func checkUnitValue(name, value string) error {
+	if strings.IndexFunc(value, func(r rune) bool { return r < ' ' || r == 0x7f }) != -1 {
+		return errors.New(fmt.Sprintf("The %s may not contain control characters", name))
+	}
+	return nil
+}
+
+func (d *ScheduleDescription) Check() error {
+	if _, err := NewScheduleIdentifier(string(d.Id)); err != nil {
+		return err
+	}
+	if strings.TrimSpace(d.Calendar) == "" {
+		return errors.New("A calendar specification is required, e.g. 'daily' or '*-*-* 02:00:00'")
+	}
+	if d.Image == "" {
+		return errors.New("An image must be specified for the scheduled job")
+	}
+	if err := checkUnitValue("calendar specification", d.Calendar); err != nil {
+		return err
+	}
+	if err := checkUnitValue("image", d.Image); err != nil {
+		return err
+	}
+	if err := checkUnitValue("command", d.Command); err != nil {
+		return err
+	}
+	for i := range d.Arguments {
+		if err := checkUnitValue("arguments", d.Arguments[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Quote a value for an Exec line of a unit file.
+func unitQuote(s string) string {
+	s = strings.Replace(s, "\\", "\\\\", -1)
+	s = strings.Replace(s, "\"", "\\\"", -1)
+	s = strings.Replace(s, "%", "%%", -1)
+	s = strings.Replace(s, "$", "$$", -1)
+	return "\"" + s + "\""
+}
+
+// The docker options, image and arguments of the job.
+func (d *ScheduleDescription) RunSpec() string {
+	parts := []string{}
+	if d.Command != "" {
+		parts = append(parts, "--entrypoint", unitQuote(d.Command))
+	}
+	parts = append(parts, unitQuote(d.Image))
+	for i := range d.Arguments {
+		parts = append(parts, unitQuote(d.Arguments[i]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Write the description and the service and timer units of the job.
+func (d *ScheduleDescription) Write() error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	if err := utils.WriteToPathExclusive(d.Id.DescriptionPathFor(), bytes.NewBuffer(data), 0660); err != nil {
+		return err
+	}
+
+	unit := ScheduleUnit{
+		Id:             d.Id,
+		Calendar:       d.Calendar,
+		Persistent:     d.Persistent,
+		RunSpec:        d.RunSpec(),
+		UnitName:       d.Id.UnitNameFor(),
+		ExecutablePath: filepath.Join("/", "usr", "bin", "gear"),
+	}
+	buf := &bytes.Buffer{}
+	if err := ScheduleServiceTemplate.Execute(buf, unit); err != nil {
+		return err
+	}
+	if err := utils.WriteToPathExclusive(d.Id.UnitPathFor(), buf, 0666); err != nil {
+		return err
+	}
+	buf.Reset()
+	if err := ScheduleTimerTemplate.Execute(buf, unit); err != nil {
+		return err
+	}
+	return utils.WriteToPathExclusive(d.Id.TimerUnitPathFor(), buf, 0666)
+}
+
+func ReadScheduleDescription(id ScheduleIdentifier) (*ScheduleDescription, error) {
+	data, err := ioutil.ReadFile(id.DescriptionPathFor())
+	if err != nil {
+		return nil, err
+	}
+	d := &ScheduleDescription{}
+	if err := json.Unmarshal(data, d); err != nil {
+		return nil, err
+	}
+	d.Id = id
+	return d, nil
+}
+
+// Remove the units, description and run history of the job.
+func (s ScheduleIdentifier) Remove() error {
+	for _, path := range []string{s.TimerUnitPathFor(), s.UnitPathFor(), s.DescriptionPathFor(), s.RunsPathFor(), s.currentRunPathFor()} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+type ScheduleDescriptions []ScheduleDescription
+
+func (s ScheduleDescriptions) Less(a, b int) bool {
+	return s[a].Id < s[b].Id
+}
+func (s ScheduleDescriptions) Len() int {
+	return len(s)
+}
+func (s ScheduleDescriptions) Swap(a, b int) {
+	s[a], s[b] = s[b], s[a]
+}
+
+func ListSchedules() (ScheduleDescriptions, error) {
+	infos, err := ioutil.ReadDir(SchedulesPath())
+	if err != nil {
+		return nil, err
+	}
+	schedules := make(ScheduleDescriptions, 0, len(infos))
+	for _, info := range infos {
+		if info.IsDir() || filepath.Ext(info.Name()) != ".json" {
+			continue
+		}
+		id, err := NewScheduleIdentifier(strings.TrimSuffix(info.Name(), ".json"))
+		if err != nil {
+			continue
+		}
+		d, err := ReadScheduleDescription(id)
+		if err != nil {
+			continue
+		}
+		schedules = append(schedules, *d)
+	}
+	sort.Sort(schedules)
+	return schedules, nil
+}
+
+// A completed run of a scheduled job.  The output of the run follows
+// the journal entry identified by Cursor.
+type ScheduleRun struct {
+	Started  time.Time
+	Duration time.Duration
+	// The result reported by systemd, e.g. 'success' or 'exit-code'
+	Result   string
+	ExitCode string `json:"ExitCode,omitempty"`
+	Cursor   string `json:"Cursor,omitempty"`
+}
+type ScheduleRuns []ScheduleRun
+
+// Record the start of a run.
+func (s ScheduleIdentifier) StartRun(cursor string) error {
+	data, err := json.Marshal(&ScheduleRun{Started: time.Now(), Cursor: cursor})
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(s.currentRunPathFor(), data, 0660)
+}
+
+// Record the end of the current run in the history of the job, keeping
+// the most recent runs.
+func (s ScheduleIdentifier) FinishRun(result, exitCode string) error {
+	run := ScheduleRun{}
+	if data, err := ioutil.ReadFile(s.currentRunPathFor()); err == nil {
+		json.Unmarshal(data, &run)
+	}
+	if !run.Started.IsZero() {
+		run.Duration = time.Since(run.Started)
+	}
+	run.Result = result
+	run.ExitCode = exitCode
+
+	f, _, err := utils.OpenFileExclusive(s.RunsPathFor(), 0660)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	runs, err := readScheduleRuns(f)
+	if err != nil {
+		return err
+	}
+	runs = append(runs, run)
+	if len(runs) > ScheduleRunHistory {
+		runs = runs[len(runs)-ScheduleRunHistory:]
+	}
+
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	for i := range runs {
+		if err := encoder.Encode(&runs[i]); err != nil {
+			return err
+		}
+	}
+	if _, err := f.Seek(0, 0); err != nil {
+		return err
+	}
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := buf.WriteTo(f); err != nil {
+		return err
+	}
+	os.Remove(s.currentRunPathFor())
+	return nil
+}
+
+// Return the recorded runs of the job, oldest first.
+func (s ScheduleIdentifier) Runs() (ScheduleRuns, error) {
+	f, err := os.Open(s.RunsPathFor())
+	if os.IsNotExist(err) {
+		return ScheduleRuns{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readScheduleRuns(f)
+}
+
+func readScheduleRuns(f *os.File) (ScheduleRuns, error) {
+	runs := ScheduleRuns{}
+	scan := bufio.NewScanner(f)
+	for scan.Scan() {
+		run := ScheduleRun{}
+		if err := json.Unmarshal(scan.Bytes(), &run); err != nil {
+			continue
+		}
+		runs = append(runs, run)
+	}
+	return runs, scan.Err()
+}
@@ -0,0 +1,48 @@
+package containers
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestScheduleUnits(t *testing.T) {
+	d := ScheduleDescription{
+		Id:         ScheduleIdentifier("backup"),
+		Calendar:   "*-*-* 02:00:00",
+		Image:      "busybox",
+		Command:    "/bin/sh",
+		Arguments:  []string{"-c", "echo \"$HOME\" 100%"},
+		Persistent: true,
+	}
+	if err := d.Check(); err != nil {
+		t.Fatal(err)
+	}
+	spec := `--entrypoint "/bin/sh" "busybox" "-c" "echo \"$$HOME\" 100%%"`
+	if d.RunSpec() != spec {
+		t.Errorf("Expected run spec %s, got %s", spec, d.RunSpec())
+	}
+
+	unit := ScheduleUnit{Id: d.Id, Calendar: d.Calendar, Persistent: d.Persistent, RunSpec: d.RunSpec(), UnitName: d.Id.UnitNameFor(), ExecutablePath: "/usr/bin/gear"}
+	buf := &bytes.Buffer{}
+	if err := ScheduleServiceTemplate.Execute(buf, unit); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "-a stdout -a stderr "+spec+"\n") {
+		t.Errorf("Expected the run spec in the service: %s", buf.String())
+	}
+	buf.Reset()
+	if err := ScheduleTimerTemplate.Execute(buf, unit); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"OnCalendar=*-*-* 02:00:00\n", "Persistent=true\n", "Unit=sched-backup.service\n"} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("Expected %q in timer: %s", line, buf.String())
+		}
+	}
+
+	d.Calendar = "daily\nExecStart=/bin/false"
+	if err := d.Check(); err == nil {
+		t.Errorf("Expected a calendar with a newline to be invalid")
+	}
+}
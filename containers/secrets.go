@@ -0,0 +1,241 @@
+package containers
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/openshift/geard/config"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// The value shown in place of a secret in the environment content API.
+const RedactedSecretValue = "**REDACTED**"
+
+const (
+	secretKeySize = 32
+	tmpfsMagic    = 0x01021994
+)
+
+// The AES key that encrypts the secret environment values on this host.
+func SecretKeyPath() string {
+	return filepath.Join(config.ContainerBasePath(), "env", "secret.key")
+}
+
+// Read the key of this host, creating it the first time a secret is
+// stored.
+func LoadSecretKey() ([]byte, error) {
+	key, err := ioutil.ReadFile(SecretKeyPath())
+	if os.IsNotExist(err) {
+		key = make([]byte, secretKeySize)
+		if _, err := io.ReadFull(rand.Reader, key); err != nil {
+			return nil, err
+		}
+		file, errc := os.OpenFile(SecretKeyPath(), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if os.IsExist(errc) {
+			return LoadSecretKey()
+		}
+		if errc != nil {
+			return nil, errc
+		}
+		defer file.Close()
+		if _, err := file.Write(key); err != nil {
+			os.Remove(SecretKeyPath())
+			return nil, err
+		}
+		return key, file.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != secretKeySize {
+		return nil, errors.New("The secret key " + SecretKeyPath() + " is not valid")
+	}
+	return key, nil
+}
+
+func encryptSecret(key []byte, value string) (string, error) {
+	gcm, err := newSecretCipher(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(value), nil)), nil
+}
+
+func decryptSecret(key []byte, value string) (string, error) {
+	gcm, err := newSecretCipher(key)
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("The secret value is not valid")
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+func newSecretCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// The secret variables of the description.
+func (j *EnvironmentDescription) Secrets() EnvironmentVariables {
+	secrets := EnvironmentVariables{}
+	for i := range j.Variables {
+		if j.Variables[i].Secret {
+			secrets = append(secrets, j.Variables[i])
+		}
+	}
+	return secrets
+}
+
+// Encrypt the secret variables into their own file next to the
+// environment, which replaces the existing secrets unless appending.
+func (j *EnvironmentDescription) writeSecrets(appends bool) error {
+	path := j.Id.SecretsPathFor()
+	secrets := j.Secrets()
+	if len(secrets) == 0 {
+		if !appends {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		return nil
+	}
+
+	key, err := LoadSecretKey()
+	if err != nil {
+		return err
+	}
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if appends {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	file, err := os.OpenFile(path, flags, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := writeSecretsTo(file, key, secrets); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+func writeSecretsTo(w io.Writer, key []byte, secrets EnvironmentVariables) error {
+	for i := range secrets {
+		value, err := encryptSecret(key, secrets[i].Value)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "%s=%s\n", secrets[i].Name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Read the secrets in the order they were first set, decrypting them
+// when a key is provided.  A later value of a name replaces an earlier.
+func readSecretsFrom(r io.Reader, key []byte) (EnvironmentVariables, error) {
+	secrets := EnvironmentVariables{}
+	index := make(map[string]int)
+	scan := bufio.NewScanner(r)
+	for scan.Scan() {
+		pair := strings.SplitN(scan.Text(), "=", 2)
+		if len(pair) != 2 {
+			continue
+		}
+		e := Environment{Name: pair[0], Secret: true}
+		if key != nil {
+			value, err := decryptSecret(key, pair[1])
+			if err != nil {
+				return nil, err
+			}
+			e.Value = value
+		}
+		if i, ok := index[e.Name]; ok {
+			secrets[i] = e
+			continue
+		}
+		index[e.Name] = len(secrets)
+		secrets = append(secrets, e)
+	}
+	if err := scan.Err(); err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+// Decrypt the secret variables of an environment.
+func ReadSecrets(id Identifier) (EnvironmentVariables, error) {
+	file, err := os.Open(id.SecretsPathFor())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return EnvironmentVariables{}, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+	key, err := LoadSecretKey()
+	if err != nil {
+		return nil, err
+	}
+	return readSecretsFrom(file, key)
+}
+
+// The names of the secret variables of an environment, which may be
+// read without the key.
+func ReadSecretNames(id Identifier) ([]string, error) {
+	file, err := os.Open(id.SecretsPathFor())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+	secrets, err := readSecretsFrom(file, nil)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(secrets))
+	for i := range secrets {
+		names[i] = secrets[i].Name
+	}
+	return names, nil
+}
+
+func checkTmpfs(path string) error {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(path, &fs); err != nil {
+		return err
+	}
+	if int64(fs.Type) != tmpfsMagic {
+		return errors.New(path + " is not a tmpfs, secret values may not be written to it")
+	}
+	return nil
+}
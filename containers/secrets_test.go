@@ -0,0 +1,42 @@
+package containers
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSecretsRoundTrip(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, secretKeySize)
+	buf := &bytes.Buffer{}
+	if err := writeSecretsTo(buf, key, EnvironmentVariables{
+		{Name: "DB_PASSWORD", Value: "first", Secret: true},
+		{Name: "API_TOKEN", Value: "a=b\nc", Secret: true},
+		{Name: "DB_PASSWORD", Value: "second", Secret: true},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "second") || strings.Contains(buf.String(), "a=b") {
+		t.Fatalf("Secret values were written in plain text: %s", buf.String())
+	}
+
+	secrets, err := readSecretsFrom(bytes.NewReader(buf.Bytes()), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secrets) != 2 || secrets[0].Name != "DB_PASSWORD" || secrets[0].Value != "second" || secrets[1].Value != "a=b\nc" || !secrets[1].Secret {
+		t.Errorf("Unexpected secrets %+v", secrets)
+	}
+
+	names, err := readSecretsFrom(bytes.NewReader(buf.Bytes()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[1].Name != "API_TOKEN" || names[1].Value != "" {
+		t.Errorf("Unexpected secret names %+v", names)
+	}
+
+	if _, err := readSecretsFrom(bytes.NewReader(buf.Bytes()), bytes.Repeat([]byte{8}, secretKeySize)); err == nil {
+		t.Errorf("Expected secrets to be unreadable with another key")
+	}
+}
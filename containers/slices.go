@@ -0,0 +1,248 @@
+package containers
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/openshift/geard/config"
+	"github.com/openshift/geard/systemd"
+	"github.com/openshift/geard/utils"
+	"github.com/openshift/go-systemd/dbus"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A named systemd slice that containers may be placed in to share
+// a set of resource limits.
+type SliceIdentifier string
+
+const (
+	DefaultSlice = SliceIdentifier("container-small")
+	RootSlice    = SliceIdentifier("container")
+)
+
+var InvalidSliceIdentifier = SliceIdentifier("")
+var allowedSliceIdentifier = regexp.MustCompile("\\A[a-zA-Z0-9\\-]{1,64}\\z")
+
+func NewSliceIdentifier(s string) (SliceIdentifier, error) {
+	s = strings.TrimSuffix(s, ".slice")
+	switch {
+	case s == "":
+		return InvalidSliceIdentifier, errors.New("Slice name may not be empty")
+	case !allowedSliceIdentifier.MatchString(s):
+		return InvalidSliceIdentifier, errors.New("Slice name must match " + allowedSliceIdentifier.String())
+	}
+	return SliceIdentifier(s), nil
+}
+
+func (s SliceIdentifier) UnitNameFor() string {
+	return string(s) + ".slice"
+}
+
+func (s SliceIdentifier) UnitPathFor() string {
+	return filepath.Join(config.ContainerBasePath(), "slices", s.UnitNameFor())
+}
+
+// systemd derives the slice hierarchy from the unit name, so the parent
+// of a slice is everything before the last dash.
+func (s SliceIdentifier) Parent() SliceIdentifier {
+	if i := strings.LastIndex(string(s), "-"); i > 0 {
+		return s[:i]
+	}
+	return InvalidSliceIdentifier
+}
+
+func (s SliceIdentifier) Builtin() bool {
+	return s == RootSlice || s == DefaultSlice
+}
+
+// The resource limits applied to a slice.  Empty values are left
+// unbounded by systemd.
+type SliceDescription struct {
+	Id          SliceIdentifier
+	Parent      SliceIdentifier `json:"Parent,omitempty"`
+	MemoryLimit string          `json:"MemoryLimit,omitempty"`
+	CPUShares   uint64          `json:"CPUShares,omitempty"`
+	TasksMax    uint64          `json:"TasksMax,omitempty"`
+}
+
+func (d *SliceDescription) Check() error {
+	if _, err := NewSliceIdentifier(string(d.Id)); err != nil {
+		return err
+	}
+	if d.Id != RootSlice {
+		if d.Parent == "" {
+			d.Parent = d.Id.Parent()
+		}
+		if d.Parent == "" {
+			return errors.New(fmt.Sprintf("The slice %s must be nested under another slice, e.g. %s-%s", d.Id, RootSlice, d.Id))
+		}
+		if !strings.HasPrefix(string(d.Id), string(d.Parent)+"-") {
+			return errors.New(fmt.Sprintf("The slice name must begin with the name of its parent: %s-<name>", d.Parent))
+		}
+	}
+	if d.MemoryLimit != "" {
+		if _, err := ParseMemoryLimit(d.MemoryLimit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Return the systemd properties that may be changed on a running slice.
+func (d *SliceDescription) Properties() []dbus.Property {
+	props := []dbus.Property{}
+	if d.MemoryLimit != "" {
+		if limit, err := ParseMemoryLimit(d.MemoryLimit); err == nil {
+			props = append(props, systemd.PropMemoryLimit(limit))
+		}
+	}
+	if d.CPUShares != 0 {
+		props = append(props, systemd.PropCPUShares(d.CPUShares))
+	}
+	if d.TasksMax != 0 {
+		props = append(props, systemd.PropTasksMax(d.TasksMax))
+	}
+	return props
+}
+
+func (d *SliceDescription) Write() error {
+	buf := &bytes.Buffer{}
+	if err := SliceUnitTemplate.Execute(buf, d.unit()); err != nil {
+		return err
+	}
+	return utils.WriteToPathExclusive(d.Id.UnitPathFor(), buf, 0666)
+}
+
+func (d *SliceDescription) unit() SliceUnit {
+	return SliceUnit{
+		Name:        string(d.Id),
+		Parent:      string(d.Parent),
+		MemoryLimit: d.MemoryLimit,
+		CPUShares:   d.CPUShares,
+		TasksMax:    d.TasksMax,
+	}
+}
+
+func ReadSliceDescription(id SliceIdentifier) (*SliceDescription, error) {
+	f, err := os.Open(id.UnitPathFor())
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readSliceDescriptionFrom(id, f)
+}
+
+func readSliceDescriptionFrom(id SliceIdentifier, r io.Reader) (*SliceDescription, error) {
+	d := &SliceDescription{Id: id}
+	scan := bufio.NewScanner(r)
+	for scan.Scan() {
+		pair := strings.SplitN(strings.TrimSpace(scan.Text()), "=", 2)
+		if len(pair) != 2 {
+			continue
+		}
+		switch pair[0] {
+		case "Slice":
+			d.Parent = SliceIdentifier(strings.TrimSuffix(pair[1], ".slice"))
+		case "MemoryLimit":
+			d.MemoryLimit = pair[1]
+		case "CPUShares":
+			d.CPUShares, _ = strconv.ParseUint(pair[1], 10, 64)
+		case "TasksMax":
+			d.TasksMax, _ = strconv.ParseUint(pair[1], 10, 64)
+		}
+	}
+	if err := scan.Err(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+type SliceDescriptions []SliceDescription
+
+func (s SliceDescriptions) Less(a, b int) bool {
+	return s[a].Id < s[b].Id
+}
+func (s SliceDescriptions) Len() int {
+	return len(s)
+}
+func (s SliceDescriptions) Swap(a, b int) {
+	s[a], s[b] = s[b], s[a]
+}
+
+func ListSlices() (SliceDescriptions, error) {
+	infos, err := ioutil.ReadDir(filepath.Join(config.ContainerBasePath(), "slices"))
+	if err != nil {
+		return nil, err
+	}
+	slices := make(SliceDescriptions, 0, len(infos))
+	for _, info := range infos {
+		if info.IsDir() || filepath.Ext(info.Name()) != ".slice" {
+			continue
+		}
+		id, err := NewSliceIdentifier(info.Name())
+		if err != nil {
+			continue
+		}
+		d, err := ReadSliceDescription(id)
+		if err != nil {
+			continue
+		}
+		slices = append(slices, *d)
+	}
+	sort.Sort(slices)
+	return slices, nil
+}
+
+// Return the slice the container unit is assigned to.
+func GetContainerSlice(id Identifier) (SliceIdentifier, error) {
+	f, err := os.Open(id.UnitPathFor())
+	if err != nil {
+		return InvalidSliceIdentifier, err
+	}
+	defer f.Close()
+	return readSliceFromUnitFile(f)
+}
+
+func readSliceFromUnitFile(r io.Reader) (SliceIdentifier, error) {
+	scan := bufio.NewScanner(r)
+	for scan.Scan() {
+		line := strings.TrimSpace(scan.Text())
+		if strings.HasPrefix(line, "Slice=") {
+			return SliceIdentifier(strings.TrimSuffix(strings.TrimPrefix(line, "Slice="), ".slice")), nil
+		}
+	}
+	return InvalidSliceIdentifier, scan.Err()
+}
+
+var memoryUnits = map[byte]uint64{
+	'K': 1 << 10,
+	'M': 1 << 20,
+	'G': 1 << 30,
+	'T': 1 << 40,
+}
+
+// Convert a systemd style memory size (512M, 2G, 1048576) to bytes.
+func ParseMemoryLimit(s string) (uint64, error) {
+	value := strings.ToUpper(strings.TrimSpace(s))
+	if value == "" {
+		return 0, errors.New("Memory limit may not be empty")
+	}
+	multiplier := uint64(1)
+	if m, ok := memoryUnits[value[len(value)-1]]; ok {
+		multiplier = m
+		value = value[:len(value)-1]
+	}
+	i, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || i == 0 {
+		return 0, errors.New(fmt.Sprintf("The memory limit '%s' must be a positive number of bytes with an optional K, M, G, or T suffix", s))
+	}
+	return i * multiplier, nil
+}
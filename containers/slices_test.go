@@ -0,0 +1,66 @@
+package containers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseMemoryLimit(t *testing.T) {
+	for s, expected := range map[string]uint64{
+		"1048576": 1048576,
+		"512K":    512 * 1024,
+		"512M":    512 * 1024 * 1024,
+		"2g":      2 * 1024 * 1024 * 1024,
+	} {
+		if out, err := ParseMemoryLimit(s); err != nil || out != expected {
+			t.Errorf("Expected %s to be %d, got %d: %v", s, expected, out, err)
+		}
+	}
+	for _, s := range []string{"", "M", "0", "-1M", "1.5G", "12X"} {
+		if _, err := ParseMemoryLimit(s); err == nil {
+			t.Errorf("Expected %s to be an invalid memory limit", s)
+		}
+	}
+}
+
+func TestSliceDescriptionCheck(t *testing.T) {
+	d := &SliceDescription{Id: "container-large"}
+	if err := d.Check(); err != nil {
+		t.Fatal(err)
+	}
+	if d.Parent != RootSlice {
+		t.Errorf("Parent should default to the name prefix, got %s", d.Parent)
+	}
+	if err := (&SliceDescription{Id: "large"}).Check(); err == nil {
+		t.Error("A slice without a parent should be rejected")
+	}
+	if err := (&SliceDescription{Id: "container-large", Parent: "other"}).Check(); err == nil {
+		t.Error("A slice whose name does not begin with its parent should be rejected")
+	}
+}
+
+func TestReadSliceDescription(t *testing.T) {
+	unit := `[Unit]
+Description=Container slice container-large
+
+[Slice]
+MemoryLimit=2G
+CPUShares=2048
+TasksMax=500
+Slice=container.slice
+`
+	d, err := readSliceDescriptionFrom("container-large", strings.NewReader(unit))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Parent != RootSlice || d.MemoryLimit != "2G" || d.CPUShares != 2048 || d.TasksMax != 500 {
+		t.Errorf("Unexpected slice description %+v", d)
+	}
+	names := []string{}
+	for _, prop := range d.Properties() {
+		names = append(names, prop.Name)
+	}
+	if strings.Join(names, ",") != "MemoryLimit,CPUShares,TasksMax" {
+		t.Errorf("Expected every limit to be applied to the running slice, got %v", names)
+	}
+}
@@ -1,8 +1,10 @@
 package containers
 
 import (
+	"github.com/openshift/geard/config"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 func (i Identifier) activeUnitPathFor() string {
@@ -31,3 +33,33 @@ func (i Identifier) SetUnitStartOnBoot(active bool) error {
 	}
 	return nil
 }
+
+// Invoke fn for every container that has a unit file installed on this host.
+func WalkContainerUnits(fn func(Identifier)) error {
+	unitsPath := filepath.Join(config.ContainerBasePath(), "units")
+	return filepath.Walk(unitsPath, func(path string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			// versioned definitions live two levels below the units directory
+			if path != unitsPath && filepath.Dir(path) != unitsPath {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		name := info.Name()
+		if filepath.Ext(name) != ".service" || !strings.HasPrefix(name, IdentifierPrefix) {
+			return nil
+		}
+		id, errn := NewIdentifier(strings.TrimSuffix(strings.TrimPrefix(name, IdentifierPrefix), ".service"))
+		if errn != nil {
+			return nil
+		}
+		fn(id)
+		return nil
+	})
+}
@@ -0,0 +1,183 @@
+package containers
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The mount point of the cgroup hierarchies.
+var CgroupRoot = "/sys/fs/cgroup"
+
+// Memory limits at or above this value are reported by the kernel when
+// no limit is set.
+const unlimitedMemory = 1 << 62
+
+// The resource usage of the processes in a container unit, read from
+// the unit's cgroup.
+type ContainerStats struct {
+	Id   Identifier
+	Time time.Time
+
+	// Total CPU time consumed, in nanoseconds
+	CPUTime uint64
+	// Memory in use and the limit in bytes, 0 if unlimited
+	MemoryUsage uint64
+	MemoryLimit uint64 `json:"MemoryLimit,omitempty"`
+	// The number of tasks (threads) in the cgroup
+	Tasks int
+	// Bytes read from and written to block devices
+	BlockRead  uint64
+	BlockWrite uint64
+}
+
+// Read the statistics of the cgroup at path, relative to the root of
+// each hierarchy (the ControlGroup property of a systemd unit).
+func ReadCgroupStats(id Identifier, path string) (*ContainerStats, error) {
+	stats := &ContainerStats{Id: id, Time: time.Now()}
+	if _, err := os.Stat(filepath.Join(CgroupRoot, "cgroup.controllers")); err == nil {
+		return stats, readUnifiedStats(stats, filepath.Join(CgroupRoot, path))
+	}
+	return stats, readLegacyStats(stats, path)
+}
+
+func readLegacyStats(stats *ContainerStats, path string) error {
+	var err error
+	if stats.CPUTime, err = readCgroupValue(filepath.Join(CgroupRoot, "cpuacct", path, "cpuacct.usage")); err != nil {
+		return err
+	}
+	if stats.MemoryUsage, err = readCgroupValue(filepath.Join(CgroupRoot, "memory", path, "memory.usage_in_bytes")); err != nil {
+		return err
+	}
+	if stats.MemoryLimit, err = readCgroupValue(filepath.Join(CgroupRoot, "memory", path, "memory.limit_in_bytes")); err != nil {
+		return err
+	}
+	if stats.MemoryLimit >= unlimitedMemory {
+		stats.MemoryLimit = 0
+	}
+	if stats.Tasks, err = countCgroupLines(filepath.Join(CgroupRoot, "systemd", path, "tasks")); err != nil {
+		return err
+	}
+
+	// blkio accounting may not be enabled for the unit
+	f, err := os.Open(filepath.Join(CgroupRoot, "blkio", path, "blkio.throttle.io_service_bytes"))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	scan := bufio.NewScanner(f)
+	for scan.Scan() {
+		fields := strings.Fields(scan.Text())
+		if len(fields) != 3 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[1] {
+		case "Read":
+			stats.BlockRead += value
+		case "Write":
+			stats.BlockWrite += value
+		}
+	}
+	return scan.Err()
+}
+
+func readUnifiedStats(stats *ContainerStats, dir string) error {
+	usage, err := readCgroupKeys(filepath.Join(dir, "cpu.stat"))
+	if err != nil {
+		return err
+	}
+	stats.CPUTime = usage["usage_usec"] * 1000
+	if stats.MemoryUsage, err = readCgroupValue(filepath.Join(dir, "memory.current")); err != nil {
+		return err
+	}
+	if stats.MemoryLimit, err = readCgroupValue(filepath.Join(dir, "memory.max")); err != nil {
+		return err
+	}
+	if stats.Tasks, err = countCgroupLines(filepath.Join(dir, "cgroup.threads")); err != nil {
+		return err
+	}
+
+	f, err := os.Open(filepath.Join(dir, "io.stat"))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	scan := bufio.NewScanner(f)
+	for scan.Scan() {
+		for _, field := range strings.Fields(scan.Text()) {
+			pair := strings.SplitN(field, "=", 2)
+			if len(pair) != 2 {
+				continue
+			}
+			value, err := strconv.ParseUint(pair[1], 10, 64)
+			if err != nil {
+				continue
+			}
+			switch pair[0] {
+			case "rbytes":
+				stats.BlockRead += value
+			case "wbytes":
+				stats.BlockWrite += value
+			}
+		}
+	}
+	return scan.Err()
+}
+
+// Read a file holding a single number, "max" is read as 0 (unlimited).
+func readCgroupValue(path string) (uint64, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	s := strings.TrimSpace(string(data))
+	if s == "max" {
+		return 0, nil
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+// Read a file of "<key> <value>" lines.
+func readCgroupKeys(path string) (map[string]uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	values := make(map[string]uint64)
+	scan := bufio.NewScanner(f)
+	for scan.Scan() {
+		fields := strings.Fields(scan.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if value, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			values[fields[0]] = value
+		}
+	}
+	return values, scan.Err()
+}
+
+func countCgroupLines(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	count := 0
+	scan := bufio.NewScanner(f)
+	for scan.Scan() {
+		if scan.Text() != "" {
+			count++
+		}
+	}
+	return count, scan.Err()
+}
@@ -0,0 +1,49 @@
+package containers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadUnifiedCgroupStats(t *testing.T) {
+	root, err := ioutil.TempDir("", "geard-cgroup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	old := CgroupRoot
+	CgroupRoot = root
+	defer func() { CgroupRoot = old }()
+
+	dir := filepath.Join(root, "container.slice/ctr-web.service")
+	os.MkdirAll(dir, 0755)
+	ioutil.WriteFile(filepath.Join(root, "cgroup.controllers"), []byte("cpu io memory pids\n"), 0644)
+	for name, contents := range map[string]string{
+		"cpu.stat":       "usage_usec 2500\nuser_usec 2000\nsystem_usec 500\n",
+		"memory.current": "1048576\n",
+		"memory.max":     "max\n",
+		"cgroup.threads": "100\n101\n102\n",
+		"io.stat":        "8:0 rbytes=4096 wbytes=512 rios=1 wios=1\n8:16 rbytes=1024 wbytes=0 rios=1 wios=0\n",
+	} {
+		ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644)
+	}
+
+	stats, err := ReadCgroupStats(Identifier("web"), "/container.slice/ctr-web.service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.CPUTime != 2500000 {
+		t.Errorf("Unexpected CPU time %d", stats.CPUTime)
+	}
+	if stats.MemoryUsage != 1048576 || stats.MemoryLimit != 0 {
+		t.Errorf("Unexpected memory usage %d and limit %d", stats.MemoryUsage, stats.MemoryLimit)
+	}
+	if stats.Tasks != 3 {
+		t.Errorf("Unexpected task count %d", stats.Tasks)
+	}
+	if stats.BlockRead != 5120 || stats.BlockWrite != 512 {
+		t.Errorf("Unexpected block IO %d/%d", stats.BlockRead, stats.BlockWrite)
+	}
+}
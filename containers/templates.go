@@ -12,15 +12,32 @@ type ContainerUnit struct {
 	PortSpec string
 	RunSpec  string
 	Slice    string
+	Restart  *RestartPolicy
+	Volumes  VolumeMounts
+	Labels   Labels
 	Isolate  bool
 	User     string
 	ReqId    string
 
+	// Map the users of the container to a range of host ids instead
+	// of running the image as a host user
+	UserNamespace *UserNamespace
+	// The capabilities and other restrictions the container runs with
+	SecurityProfile *SecurityProfile
+	// Actions run by systemd before and after the container starts
+	// and stops
+	Hooks LifecycleHooks
+
+	Dependencies ContainerDependencies
+
 	HomeDir         string
 	RunDir          string
 	EnvironmentPath string
 	ExecutablePath  string
 	IncludePath     string
+	// The tmpfs file gear init resolves the environment of the
+	// container to before it starts
+	ResolvedEnvironmentPath string
 
 	PortPairs            port.PortPairs
 	SocketUnitName       string
@@ -29,20 +46,46 @@ type ContainerUnit struct {
 	DockerFeatures config.DockerFeatures
 }
 
+// Isolated containers that are not run in a user namespace are started
+// with an init script that changes the image to run as the container user.
+func (u ContainerUnit) RewritesImage() bool {
+	return u.Isolate && u.UserNamespace == nil
+}
+
 var ContainerUnitTemplate = template.Must(template.New("unit.service").Parse(`
 {{define "COMMON_UNIT"}}
 [Unit]
 Description=Container {{.Id}}
-{{end}}
+{{range .Dependencies}}After={{.Id.UnitNameFor}}
+{{ if .Required }}Requires{{ else }}Wants{{ end }}={{.Id.UnitNameFor}}
+{{end}}{{end}}
 
 {{define "COMMON_SERVICE"}}
 [Service]
 Type=simple
-TimeoutStartSec=5m
+TimeoutStartSec={{ if .Hooks }}{{.Hooks.StartTimeout}}{{ else }}5m{{ end }}
+{{ if .Hooks }}TimeoutStopSec={{.Hooks.StopTimeout}}{{ end }}
 {{ if .Slice }}Slice={{.Slice}}{{ end }}
 {{ if .EnvironmentPath }}EnvironmentFile={{.EnvironmentPath}}{{ end }}
+{{ with .Restart }}Restart={{.Policy}}
+RestartSec={{.Backoff}}
+StartLimitInterval={{.StartLimitInterval}}
+StartLimitBurst={{.MaxAttempts}}{{ end }}
 {{end}}
 
+{{define "HOOKS_PRE_START"}}{{range .Hooks.For "pre-start"}}
+ExecStartPre={{ if .Ignored }}-{{ end }}{{$.ExecutablePath}} hook "{{$.Id}}" {{.HookSpec}}{{end}}{{end}}
+{{define "HOOKS_POST_START"}}{{range .Hooks.For "post-start"}}
+ExecStartPost={{ if .Ignored }}-{{ end }}{{$.ExecutablePath}} hook "{{$.Id}}" {{.HookSpec}}{{end}}{{end}}
+{{define "HOOKS_PRE_STOP"}}{{range .Hooks.For "pre-stop"}}
+ExecStop={{ if .Ignored }}-{{ end }}{{$.ExecutablePath}} hook "{{$.Id}}" {{.HookSpec}}{{end}}{{end}}
+{{define "HOOKS_POST_STOP"}}{{range .Hooks.For "post-stop"}}
+ExecStopPost={{ if .Ignored }}-{{ end }}{{$.ExecutablePath}} hook "{{$.Id}}" {{.HookSpec}}{{end}}{{end}}
+
+{{define "ENVIRONMENT_FILE"}}{{ if .DockerFeatures.EnvironmentFile }}{{ if .ResolvedEnvironmentPath }}--env-file "{{ .ResolvedEnvironmentPath }}"{{ else if .EnvironmentPath }}--env-file "{{ .EnvironmentPath }}"{{ end }}{{ end }}{{end}}
+{{define "ENVIRONMENT_POST_STOP"}}{{ if .ResolvedEnvironmentPath }}
+ExecStopPost=-/bin/rm -f "{{.ResolvedEnvironmentPath}}"{{ end }}{{end}}
+
 {{define "COMMON_CONTAINER"}}
 [Install]
 WantedBy=container.target
@@ -52,8 +95,12 @@ X-ContainerId={{.Id}}
 X-ContainerImage={{.Image}}
 X-ContainerUserId={{.User}}
 X-ContainerRequestId={{.ReqId}}
-X-ContainerType={{ if .Isolate }}isolated{{ else }}simple{{ end }}
-{{range .PortPairs}}X-PortMapping={{.Internal}}:{{.External}}
+X-ContainerType={{ if .UserNamespace }}userns{{ else if .Isolate }}isolated{{ else }}simple{{ end }}
+{{ with .UserNamespace }}X-UserNamespace={{.}}
+{{ end }}{{ with .SecurityProfile }}X-SecurityProfile={{.Id}}
+{{ end }}{{range .PortPairs}}X-PortMapping={{.Internal}}:{{.External}}
+{{end}}{{range .Volumes}}X-Volume={{.String}}
+{{end}}{{range $key, $value := .Labels}}X-Label-{{$key}}={{$value}}
 {{end}}
 {{end}}
 
@@ -65,18 +112,22 @@ X-ContainerType={{ if .Isolate }}isolated{{ else }}simple{{ end }}
 ExecStartPre=/bin/sh -c '/usr/bin/docker inspect --format="Reusing {{"{{.ID}}"}}" "{{.Id}}-data" || exec docker run --name "{{.Id}}-data" --volumes-from "{{.Id}}-data" --entrypoint true "{{.Image}}"'
 ExecStartPre=-/usr/bin/docker rm "{{.Id}}"
 {{ if .Isolate }}# Initialize user and volumes
-ExecStartPre={{.ExecutablePath}} init --pre "{{.Id}}" "{{.Image}}"{{ end }}
+ExecStartPre={{.ExecutablePath}} init --pre "{{.Id}}" "{{.Image}}"{{ else if .ResolvedEnvironmentPath }}# Resolve the environment
+ExecStartPre={{.ExecutablePath}} init --pre "{{.Id}}" "{{.Image}}"{{ end }}{{template "HOOKS_PRE_START" .}}
 ExecStart=/usr/bin/docker run --rm --name "{{.Id}}" \
           --volumes-from "{{.Id}}-data" \
-          {{ if and .EnvironmentPath .DockerFeatures.EnvironmentFile }}--env-file "{{ .EnvironmentPath }}"{{ end }} \
+          {{range .Volumes}}-v "{{.BindSpec}}" {{end}} \
+          {{template "ENVIRONMENT_FILE" .}} \
           -a stdout -a stderr {{.PortSpec}} {{.RunSpec}} \
-          {{ if .Isolate }} -v {{.RunDir}}/container-cmd.sh:/.container.cmd:ro -v {{.RunDir}}/container-init.sh:/.container.init:ro -u root {{end}} \
-          "{{.Image}}" {{ if .Isolate }} /.container.init {{ end }}
+          {{ with .UserNamespace }}{{.DockerSpec}}{{ end }} \
+          {{ with .SecurityProfile }}{{.DockerSpec}}{{ end }} \
+          {{ if .RewritesImage }} -v {{.RunDir}}/container-cmd.sh:/.container.cmd:ro -v {{.RunDir}}/container-init.sh:/.container.init:ro -u root {{end}} \
+          "{{.Image}}" {{ if .RewritesImage }} /.container.init {{ end }}
 # Set links (requires container have a name)
-ExecStartPost=-{{.ExecutablePath}} init --post "{{.Id}}" "{{.Image}}"
+ExecStartPost=-{{.ExecutablePath}} init --post "{{.Id}}" "{{.Image}}"{{template "HOOKS_POST_START" .}}
 ExecReload=-/usr/bin/docker stop "{{.Id}}"
-ExecReload=-/usr/bin/docker rm "{{.Id}}"
-ExecStop=-/usr/bin/docker stop "{{.Id}}"
+ExecReload=-/usr/bin/docker rm "{{.Id}}"{{template "HOOKS_PRE_STOP" .}}
+ExecStop=-/usr/bin/docker stop "{{.Id}}"{{template "HOOKS_POST_STOP" .}}{{template "ENVIRONMENT_POST_STOP" .}}
 {{template "COMMON_CONTAINER" .}}
 {{end}}
 
@@ -88,15 +139,19 @@ ExecStop=-/usr/bin/docker stop "{{.Id}}"
 ExecStartPre=/bin/sh -c '/usr/bin/docker inspect --format="Reusing {{"{{.ID}}"}}" "{{.Id}}-data" || exec docker run --name "{{.Id}}-data" --volumes-from "{{.Id}}-data" --entrypoint true "{{.Image}}"'
 ExecStartPre=-/usr/bin/docker rm "{{.Id}}"
 {{ if .Isolate }}# Initialize user and volumes
-ExecStartPre={{.ExecutablePath}} init --pre "{{.Id}}" "{{.Image}}"{{ end }}
+ExecStartPre={{.ExecutablePath}} init --pre "{{.Id}}" "{{.Image}}"{{ else if .ResolvedEnvironmentPath }}# Resolve the environment
+ExecStartPre={{.ExecutablePath}} init --pre "{{.Id}}" "{{.Image}}"{{ end }}{{template "HOOKS_PRE_START" .}}
 ExecStart=/usr/bin/docker run --rm --foreground \
-          {{ if and .EnvironmentPath .DockerFeatures.EnvironmentFile }}--env-file "{{ .EnvironmentPath }}"{{ end }} \
+          {{template "ENVIRONMENT_FILE" .}} \
           {{.PortSpec}} {{.RunSpec}} \
           --name "{{.Id}}" --volumes-from "{{.Id}}-data" \
-          {{ if .Isolate }} -v {{.RunDir}}/container-cmd.sh:/.container.cmd:ro -v {{.RunDir}}/container-init.sh:/.container.init:ro -u root {{end}} \
-          "{{.Image}}" {{ if .Isolate }} /.container.init {{ end }}
+          {{range .Volumes}}-v "{{.BindSpec}}" {{end}} \
+          {{ with .UserNamespace }}{{.DockerSpec}}{{ end }} \
+          {{ with .SecurityProfile }}{{.DockerSpec}}{{ end }} \
+          {{ if .RewritesImage }} -v {{.RunDir}}/container-cmd.sh:/.container.cmd:ro -v {{.RunDir}}/container-init.sh:/.container.init:ro -u root {{end}} \
+          "{{.Image}}" {{ if .RewritesImage }} /.container.init {{ end }}
 # Set links (requires container have a name)
-ExecStartPost=-{{.ExecutablePath}} init --post "{{.Id}}" "{{.Image}}"
+ExecStartPost=-{{.ExecutablePath}} init --post "{{.Id}}" "{{.Image}}"{{template "HOOKS_POST_START" .}}{{template "HOOKS_PRE_STOP" .}}{{template "HOOKS_POST_STOP" .}}{{template "ENVIRONMENT_POST_STOP" .}}
 {{template "COMMON_CONTAINER" .}}
 {{end}}
 
@@ -106,19 +161,21 @@ ExecStartPost=-{{.ExecutablePath}} init --post "{{.Id}}" "{{.Image}}"
 BindsTo={{.SocketUnitName}}
 
 {{template "COMMON_SERVICE" .}}
-ExecStartPre={{.ExecutablePath}} init --pre "{{.Id}}" "{{.Image}}"
+ExecStartPre={{.ExecutablePath}} init --pre "{{.Id}}" "{{.Image}}"{{template "HOOKS_PRE_START" .}}
 ExecStart=/usr/bin/docker run \
             --name "{{.Id}}" \
             --volumes-from "{{.Id}}" \
-            {{ if and .EnvironmentPath .DockerFeatures.EnvironmentFile }}--env-file "{{ .EnvironmentPath }}"{{ end }} \
+            {{range .Volumes}}-v "{{.BindSpec}}" {{end}} \
+            {{template "ENVIRONMENT_FILE" .}} \
             -a stdout -a stderr {{.RunSpec}} \
             --env LISTEN_FDS \
+            {{ with .SecurityProfile }}{{.DockerSpec}}{{ end }} \
             -v {{.RunDir}}/container-init.sh:/.container.init:ro \
             -v {{.RunDir}}/container-cmd.sh:/.container.cmd:ro \
             -v /usr/sbin/systemd-socket-proxyd:/usr/sbin/systemd-socket-proxyd:ro \
             -u root -f --rm \
             "{{.Image}}" /.container.init
-ExecStartPost=-{{.ExecutablePath}} init --post "{{.Id}}" "{{.Image}}"
+ExecStartPost=-{{.ExecutablePath}} init --post "{{.Id}}" "{{.Image}}"{{template "HOOKS_POST_START" .}}{{template "HOOKS_PRE_STOP" .}}{{template "HOOKS_POST_STOP" .}}{{template "ENVIRONMENT_POST_STOP" .}}
 {{template "COMMON_CONTAINER" .}}
 X-SocketActivated={{.SocketActivationType}}
 {{end}}
@@ -207,8 +264,11 @@ WantedBy={{.WantedBy}}
 `))
 
 type SliceUnit struct {
-	Name   string
-	Parent string
+	Name        string
+	Parent      string
+	MemoryLimit string
+	CPUShares   uint64
+	TasksMax    uint64
 }
 
 var SliceUnitTemplate = template.Must(template.New("unit.slice").Parse(`
@@ -218,9 +278,50 @@ Description=Container slice {{.Name}}
 [Slice]
 CPUAccounting=yes
 MemoryAccounting=yes
-MemoryLimit=512M
-{{ if .Parent }}Slice={{.Parent}}{{ end }}
+{{ if .MemoryLimit }}MemoryLimit={{.MemoryLimit}}{{ end }}
+{{ if .CPUShares }}CPUShares={{.CPUShares}}{{ end }}
+{{ if .TasksMax }}TasksMax={{.TasksMax}}{{ end }}
+{{ if .Parent }}Slice={{.Parent}}.slice{{ end }}
 
 [Install]
 WantedBy=container.target container-active.target
 `))
+
+type ScheduleUnit struct {
+	Id             ScheduleIdentifier
+	Calendar       string
+	Persistent     bool
+	RunSpec        string
+	UnitName       string
+	ExecutablePath string
+}
+
+var ScheduleServiceTemplate = template.Must(template.New("schedule.service").Parse(`
+[Unit]
+Description=Scheduled job {{.Id}}
+
+[Service]
+Type=oneshot
+TimeoutStartSec=0
+Slice=container.slice
+ExecStartPre=-/usr/bin/docker rm "sched-{{.Id}}"
+ExecStartPre=-{{.ExecutablePath}} record-run --pre "{{.Id}}"
+ExecStart=/usr/bin/docker run --rm --name "sched-{{.Id}}" -a stdout -a stderr {{.RunSpec}}
+ExecStopPost=-{{.ExecutablePath}} record-run --post "{{.Id}}"
+
+# Scheduled job information
+X-ScheduleId={{.Id}}
+`))
+
+var ScheduleTimerTemplate = template.Must(template.New("schedule.timer").Parse(`
+[Unit]
+Description=Schedule of job {{.Id}}
+
+[Timer]
+OnCalendar={{.Calendar}}
+{{ if .Persistent }}Persistent=true{{ end }}
+Unit={{.UnitName}}
+
+[Install]
+WantedBy=container.target
+`))
@@ -0,0 +1,138 @@
+package containers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/openshift/geard/config"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+// Host ids are allocated to containers in ranges of UserNamespaceSize
+// starting at UserNamespaceBase.  The base is a multiple of the size so
+// that an id in any range maps to the same id in another.
+const (
+	UserNamespaceBase  = 1 << 20
+	UserNamespaceSize  = 1 << 16
+	UserNamespaceCount = 4096
+)
+
+var ErrNoUserNamespaces = errors.New("All user namespace ranges are allocated")
+
+// A range of host uids and gids that the users of a container are
+// mapped to.  The root user of the container is Start on the host.
+type UserNamespace struct {
+	Start int
+	Size  int
+}
+
+func (r *UserNamespace) Contains(id int) bool {
+	return id >= r.Start && id < r.Start+r.Size
+}
+
+// The host id of an id in the container, or of an id in the range of
+// another container.
+func (r *UserNamespace) Map(id int) int {
+	if r.Contains(id) {
+		return id
+	}
+	return r.Start + id%r.Size
+}
+
+// The arguments to docker run that map the users of the container.
+func (r *UserNamespace) DockerSpec() string {
+	return fmt.Sprintf("--uidmap 0:%d:%d --gidmap 0:%d:%d ", r.Start, r.Size, r.Start, r.Size)
+}
+
+func (r *UserNamespace) String() string {
+	return fmt.Sprintf("%d:%d", r.Start, r.Size)
+}
+
+// Change the owner of every file under path that is not already within
+// the range to the mapped id.  Files copied from an image or from
+// another container become owned by the same user in this container.
+func (r *UserNamespace) ShiftOwnership(path string) error {
+	return filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		if !ok {
+			return nil
+		}
+		uid, gid := int(stat.Uid), int(stat.Gid)
+		if r.Contains(uid) && r.Contains(gid) {
+			return nil
+		}
+		return os.Lchown(p, r.Map(uid), r.Map(gid))
+	})
+}
+
+func userNamespacesPath() string {
+	return filepath.Join(config.ContainerBasePath(), "userns")
+}
+
+// Allocate a range of host ids to the container, or return the range
+// it already holds.
+func ReserveUserNamespace(id Identifier) (*UserNamespace, error) {
+	return reserveUserNamespace(userNamespacesPath(), id)
+}
+
+// The range of host ids allocated to the container, or nil if the
+// container is not run in a user namespace.
+func UserNamespaceFor(id Identifier) (*UserNamespace, error) {
+	return userNamespaceFor(userNamespacesPath(), id)
+}
+
+// Return the range of host ids held by the container to the pool.
+func ReleaseUserNamespace(id Identifier) error {
+	r, err := UserNamespaceFor(id)
+	if err != nil || r == nil {
+		return err
+	}
+	return os.Remove(filepath.Join(userNamespacesPath(), strconv.Itoa(r.Start)))
+}
+
+// Each allocation is a link named by the start of the range that
+// points to the container id, created atomically.
+func reserveUserNamespace(dir string, id Identifier) (*UserNamespace, error) {
+	if r, err := userNamespaceFor(dir, id); err != nil || r != nil {
+		return r, err
+	}
+	for i := 0; i < UserNamespaceCount; i++ {
+		start := UserNamespaceBase + i*UserNamespaceSize
+		if err := os.Symlink(string(id), filepath.Join(dir, strconv.Itoa(start))); err != nil {
+			if os.IsExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		return &UserNamespace{start, UserNamespaceSize}, nil
+	}
+	return nil, ErrNoUserNamespaces
+}
+
+func userNamespaceFor(dir string, id Identifier) (*UserNamespace, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	for _, info := range infos {
+		target, err := os.Readlink(filepath.Join(dir, info.Name()))
+		if err != nil || target != string(id) {
+			continue
+		}
+		start, err := strconv.Atoi(info.Name())
+		if err != nil {
+			continue
+		}
+		return &UserNamespace{start, UserNamespaceSize}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,41 @@
+package containers
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestReserveUserNamespace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geard-userns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	a, err := reserveUserNamespace(dir, Identifier("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := reserveUserNamespace(dir, Identifier("b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Start != UserNamespaceBase || b.Start != UserNamespaceBase+UserNamespaceSize {
+		t.Errorf("Unexpected ranges %s and %s", a, b)
+	}
+	again, err := reserveUserNamespace(dir, Identifier("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Start != a.Start {
+		t.Errorf("Expected the existing range %s, got %s", a, again)
+	}
+
+	if a.Map(0) != a.Start || a.Map(b.Start+33) != a.Start+33 || a.Map(a.Start+5) != a.Start+5 {
+		t.Errorf("Unexpected mapping of ids into %s", a)
+	}
+	if spec := b.DockerSpec(); spec != "--uidmap 0:1114112:65536 --gidmap 0:1114112:65536 " {
+		t.Errorf("Unexpected docker spec %q", spec)
+	}
+}
@@ -0,0 +1,131 @@
+package containers
+
+import (
+	"bufio"
+	"github.com/openshift/geard/port"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A unit definition written by a previous install of a container.
+type ContainerVersion struct {
+	Id      string
+	Created time.Time
+	Image   string
+	Ports   port.PortPairs `json:"Ports,omitempty"`
+	Current bool           `json:"Current,omitempty"`
+
+	socketActivated bool
+}
+
+func (v *ContainerVersion) SocketActivated() bool {
+	return v.socketActivated
+}
+
+// Sorted from newest to oldest
+type ContainerVersions []ContainerVersion
+
+func (v ContainerVersions) Less(a, b int) bool {
+	return v[a].Created.After(v[b].Created)
+}
+func (v ContainerVersions) Len() int {
+	return len(v)
+}
+func (v ContainerVersions) Swap(a, b int) {
+	v[a], v[b] = v[b], v[a]
+}
+
+// Return the version that is currently active.
+func (v ContainerVersions) Current() (*ContainerVersion, bool) {
+	for i := range v {
+		if v[i].Current {
+			return &v[i], true
+		}
+	}
+	return nil, false
+}
+
+func (v ContainerVersions) Find(id string) (*ContainerVersion, bool) {
+	for i := range v {
+		if v[i].Id == id {
+			return &v[i], true
+		}
+	}
+	return nil, false
+}
+
+// Return the newest version created before the active version.
+func (v ContainerVersions) Previous() (*ContainerVersion, bool) {
+	for i := range v {
+		if v[i].Current {
+			if i+1 < len(v) {
+				return &v[i+1], true
+			}
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+// List the unit definitions that have been installed for a container.
+func ListVersions(id Identifier) (ContainerVersions, error) {
+	active, err := os.Stat(id.UnitPathFor())
+	if err != nil {
+		return nil, err
+	}
+
+	base := id.VersionedUnitsPathFor()
+	infos, err := ioutil.ReadDir(base)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make(ContainerVersions, 0, len(infos))
+	for _, info := range infos {
+		if info.IsDir() || strings.HasSuffix(info.Name(), ".tmp") {
+			continue
+		}
+		version, err := readVersion(filepath.Join(base, info.Name()))
+		if err != nil {
+			continue
+		}
+		version.Id = info.Name()
+		version.Created = info.ModTime()
+		version.Current = os.SameFile(active, info)
+		versions = append(versions, *version)
+	}
+	sort.Sort(versions)
+	return versions, nil
+}
+
+func readVersion(path string) (*ContainerVersion, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	version := &ContainerVersion{Ports: port.PortPairs{}}
+	scan := bufio.NewScanner(f)
+	for scan.Scan() {
+		line := scan.Text()
+		switch {
+		case strings.HasPrefix(line, "X-ContainerImage="):
+			version.Image = strings.TrimPrefix(line, "X-ContainerImage=")
+		case strings.HasPrefix(line, "X-PortMapping="):
+			if found, err := port.FromPortPairHeader(strings.TrimPrefix(line, "X-PortMapping=")); err == nil {
+				version.Ports = append(version.Ports, found...)
+			}
+		case strings.HasPrefix(line, "X-SocketActivated="):
+			version.socketActivated = true
+		}
+	}
+	if err := scan.Err(); err != nil {
+		return nil, err
+	}
+	return version, nil
+}
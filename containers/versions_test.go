@@ -0,0 +1,30 @@
+package containers
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestContainerVersionsPrevious(t *testing.T) {
+	now := time.Now()
+	versions := ContainerVersions{
+		{Id: "a", Created: now.Add(-2 * time.Hour)},
+		{Id: "c", Created: now},
+		{Id: "b", Created: now.Add(-time.Hour), Current: true},
+	}
+	sort.Sort(versions)
+	if versions[0].Id != "c" || versions[2].Id != "a" {
+		t.Fatalf("Versions not sorted newest first: %+v", versions)
+	}
+	if current, ok := versions.Current(); !ok || current.Id != "b" {
+		t.Errorf("Unexpected current version %+v", current)
+	}
+	if previous, ok := versions.Previous(); !ok || previous.Id != "a" {
+		t.Errorf("Unexpected previous version %+v", previous)
+	}
+	versions[2].Current, versions[1].Current = true, false
+	if _, ok := versions.Previous(); ok {
+		t.Errorf("The oldest version should have no previous version")
+	}
+}
@@ -0,0 +1,231 @@
+package containers
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/openshift/geard/config"
+	"github.com/openshift/geard/selinux"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// A named directory on the host that outlives the containers it is
+// mounted into.
+type VolumeIdentifier string
+
+var InvalidVolumeIdentifier = VolumeIdentifier("")
+var allowedVolumeIdentifier = regexp.MustCompile("\\A[a-zA-Z0-9\\-_\\.]{1,64}\\z")
+
+func NewVolumeIdentifier(s string) (VolumeIdentifier, error) {
+	switch {
+	case s == "":
+		return InvalidVolumeIdentifier, errors.New("Volume name may not be empty")
+	case s == "." || s == "..":
+		return InvalidVolumeIdentifier, errors.New("Volume name may not be '.' or '..'")
+	case !allowedVolumeIdentifier.MatchString(s):
+		return InvalidVolumeIdentifier, errors.New("Volume name must match " + allowedVolumeIdentifier.String())
+	}
+	return VolumeIdentifier(s), nil
+}
+
+func VolumesPath() string {
+	return filepath.Join(config.ContainerBasePath(), "volumes")
+}
+
+func (v VolumeIdentifier) PathFor() string {
+	return filepath.Join(VolumesPath(), string(v))
+}
+
+// Create the volume directory if it does not exist and apply the
+// SELinux context of the volumes directory to it.
+func (v VolumeIdentifier) Create() error {
+	path := v.PathFor()
+	if err := os.Mkdir(path, 0755); err != nil && !os.IsExist(err) {
+		return err
+	}
+	return selinux.RestoreCon(path, true)
+}
+
+// The user that owns the volume directory.  A volume is owned by root
+// until it is given to the first container that writes to it, and
+// containers that share it later leave it with that owner, so that the
+// ownership of a shared volume does not change on every start.
+func (v VolumeIdentifier) Owner() (int, error) {
+	owner, err := v.FileOwner()
+	if err != nil {
+		return 0, err
+	}
+	return owner.Uid, nil
+}
+
+// The user and group that own the volume directory.
+func (v VolumeIdentifier) FileOwner() (*FileOwner, error) {
+	info, err := os.Stat(v.PathFor())
+	if err != nil {
+		return nil, err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return &FileOwner{}, nil
+	}
+	return &FileOwner{int(stat.Uid), int(stat.Gid)}, nil
+}
+
+// Give ownership of the volume contents to a container user.
+func (v VolumeIdentifier) Chown(uid, gid int) error {
+	return filepath.Walk(v.PathFor(), func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Lchown(p, uid, gid)
+	})
+}
+
+type VolumeDescription struct {
+	Id      VolumeIdentifier
+	Created time.Time
+}
+
+type VolumeDescriptions []VolumeDescription
+
+func (v VolumeDescriptions) Less(a, b int) bool {
+	return v[a].Id < v[b].Id
+}
+func (v VolumeDescriptions) Len() int {
+	return len(v)
+}
+func (v VolumeDescriptions) Swap(a, b int) {
+	v[a], v[b] = v[b], v[a]
+}
+
+func ListVolumes() (VolumeDescriptions, error) {
+	infos, err := ioutil.ReadDir(VolumesPath())
+	if err != nil {
+		return nil, err
+	}
+	volumes := make(VolumeDescriptions, 0, len(infos))
+	for _, info := range infos {
+		if !info.IsDir() {
+			continue
+		}
+		id, err := NewVolumeIdentifier(info.Name())
+		if err != nil {
+			continue
+		}
+		volumes = append(volumes, VolumeDescription{id, info.ModTime()})
+	}
+	sort.Sort(volumes)
+	return volumes, nil
+}
+
+// A volume mounted at a path inside a container.
+type VolumeMount struct {
+	Name     VolumeIdentifier
+	Path     string
+	ReadOnly bool `json:"ReadOnly,omitempty"`
+}
+
+type VolumeMounts []VolumeMount
+
+// Parse a mount of the form <name>:<path>[:ro]
+func NewVolumeMountFromString(s string) (*VolumeMount, error) {
+	value := strings.Split(s, ":")
+	if len(value) < 2 || len(value) > 3 {
+		return nil, errors.New(fmt.Sprintf("The volume '%s' must be of the form <name>:<path>[:ro]", s))
+	}
+	mount := &VolumeMount{Name: VolumeIdentifier(value[0]), Path: value[1]}
+	if len(value) == 3 {
+		switch value[2] {
+		case "ro":
+			mount.ReadOnly = true
+		case "rw":
+		default:
+			return nil, errors.New(fmt.Sprintf("The volume mode '%s' must be 'ro' or 'rw'", value[2]))
+		}
+	}
+	if err := mount.Check(); err != nil {
+		return nil, err
+	}
+	return mount, nil
+}
+
+func (m *VolumeMount) Check() error {
+	if _, err := NewVolumeIdentifier(string(m.Name)); err != nil {
+		return err
+	}
+	if !filepath.IsAbs(m.Path) {
+		return errors.New(fmt.Sprintf("The mount path for volume %s must be absolute", m.Name))
+	}
+	if strings.ContainsAny(m.Path, ": \t\n\"'") {
+		return errors.New(fmt.Sprintf("The mount path for volume %s may not contain colons, quotes, or whitespace", m.Name))
+	}
+	m.Path = filepath.Clean(m.Path)
+	return nil
+}
+
+func (m *VolumeMount) String() string {
+	if m.ReadOnly {
+		return string(m.Name) + ":" + m.Path + ":ro"
+	}
+	return string(m.Name) + ":" + m.Path
+}
+
+// The docker bind mount specification for this volume.
+func (m *VolumeMount) BindSpec() string {
+	spec := m.Name.PathFor() + ":" + m.Path
+	if m.ReadOnly {
+		spec += ":ro"
+	}
+	return spec
+}
+
+func (v VolumeMounts) Check() error {
+	paths := make(map[string]bool)
+	for i := range v {
+		if err := v[i].Check(); err != nil {
+			return err
+		}
+		if paths[v[i].Path] {
+			return errors.New(fmt.Sprintf("Only one volume may be mounted at %s", v[i].Path))
+		}
+		paths[v[i].Path] = true
+	}
+	return nil
+}
+
+// Return the volumes mounted by the container unit.
+func GetContainerVolumes(id Identifier) (VolumeMounts, error) {
+	f, err := os.Open(id.UnitPathFor())
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readVolumesFromUnitFile(f)
+}
+
+func readVolumesFromUnitFile(r io.Reader) (VolumeMounts, error) {
+	mounts := VolumeMounts{}
+	scan := bufio.NewScanner(r)
+	for scan.Scan() {
+		line := scan.Text()
+		if strings.HasPrefix(line, "X-Volume=") {
+			mount, err := NewVolumeMountFromString(strings.TrimPrefix(line, "X-Volume="))
+			if err != nil {
+				continue
+			}
+			mounts = append(mounts, *mount)
+		}
+	}
+	if err := scan.Err(); err != nil {
+		return mounts, err
+	}
+	return mounts, nil
+}
@@ -0,0 +1,47 @@
+package containers
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestNewVolumeMountFromString(t *testing.T) {
+	mount, err := NewVolumeMountFromString("data:/var/lib/data/:ro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mount.Name != "data" || mount.Path != "/var/lib/data" || !mount.ReadOnly {
+		t.Errorf("Unexpected mount %+v", mount)
+	}
+	for _, s := range []string{"data", "data:relative", ":/path", "../etc:/path", "data:/path:rx", "data:/a b"} {
+		if _, err := NewVolumeMountFromString(s); err == nil {
+			t.Errorf("Expected %s to be an invalid volume mount", s)
+		}
+	}
+	if err := (VolumeMounts{{Name: "a", Path: "/data"}, {Name: "b", Path: "/data/"}}).Check(); err == nil {
+		t.Errorf("Expected two volumes mounted at the same path to be invalid")
+	}
+}
+
+func TestVolumesInUnitFile(t *testing.T) {
+	unit := ContainerUnit{
+		Id:      Identifier("test"),
+		Image:   "busybox",
+		Volumes: VolumeMounts{{Name: "data", Path: "/data"}, {Name: "config", Path: "/etc/app", ReadOnly: true}},
+	}
+	buf := &bytes.Buffer{}
+	if err := ContainerUnitTemplate.ExecuteTemplate(buf, "SIMPLE", unit); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `/volumes/config:/etc/app:ro"`) {
+		t.Errorf("Volume bind mounts not in unit: %s", buf.String())
+	}
+	mounts, err := readVolumesFromUnitFile(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mounts) != 2 || mounts[0].String() != "data:/data" || mounts[1].String() != "config:/etc/app:ro" {
+		t.Errorf("Unexpected volumes read from unit %+v", mounts)
+	}
+}
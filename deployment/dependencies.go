@@ -0,0 +1,85 @@
+package deployment
+
+import (
+	"errors"
+	"fmt"
+	"log"
+)
+
+// A start ordering between two containers.  Instances of the source are
+// started after the instances of the target on the same host.
+type Dependency struct {
+	To string
+
+	// Stop the source instances when the target stops
+	Required bool `json:"Required,omitempty"`
+}
+type Dependencies []Dependency
+
+type containerDependency struct {
+	Source   *Container
+	Target   *Container
+	Required bool
+}
+type containerDependencies []containerDependency
+
+// Does from start after to, directly or through other dependencies
+func (deps containerDependencies) after(from, to *Container) bool {
+	for i := range deps {
+		if deps[i].Source == from && (deps[i].Target == to || deps.after(deps[i].Target, to)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Return the declared dependencies between containers and those implied
+// by links.  A link that would create a cycle does not order the
+// containers.
+func (sources Containers) OrderDependencies(links containerLinks) (containerDependencies, error) {
+	deps := make(containerDependencies, 0)
+
+	for i := range sources {
+		source := &sources[i]
+		for j := range source.Dependencies {
+			dep := &source.Dependencies[j]
+			target, found := sources.Find(dep.To)
+			if !found {
+				return nil, errors.New(fmt.Sprintf("deployment: dependency %s not found for source %s", dep.To, source.Name))
+			}
+			if target == source || deps.after(target, source) {
+				return nil, errors.New(fmt.Sprintf("deployment: the dependency of %s on %s creates a cycle", source.Name, target.Name))
+			}
+			deps = append(deps, containerDependency{source, target, dep.Required})
+		}
+	}
+
+	for i := range links {
+		link := &links[i]
+		if link.Source == link.Target || deps.after(link.Source, link.Target) {
+			continue
+		}
+		if deps.after(link.Target, link.Source) {
+			log.Printf("deployment: Not ordering %s after %s, the link would create a cycle", link.Source.Name, link.Target.Name)
+			continue
+		}
+		deps = append(deps, containerDependency{link.Source, link.Target, false})
+	}
+	return deps, nil
+}
+
+// Each source instance depends on the target instances placed on the
+// same host.
+func (dep containerDependency) appendDependencies() {
+	targetInstances := dep.Target.Instances()
+	for _, instance := range dep.Source.Instances() {
+		if instance.On == nil {
+			continue
+		}
+		for _, target := range targetInstances {
+			if target.On != nil && *target.On == *instance.On {
+				instance.dependencies.Add(target.Id, dep.Required)
+			}
+		}
+	}
+}
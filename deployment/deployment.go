@@ -140,6 +140,16 @@ func (d Deployment) Describe(placement PlacementStrategy, t transport.Transport)
 		}
 	}
 
+	// order the start of instances on the same host
+	deps, errd := sources.OrderDependencies(links)
+	if errd != nil {
+		err = errd
+		return
+	}
+	for i := range deps {
+		deps[i].appendDependencies()
+	}
+
 	// create a copy of instances to return
 	instances := make(Instances, 0, len(added))
 	for i := range sources {
@@ -210,6 +220,16 @@ type Container struct {
 	PublicPorts port.PortPairs `json:"PublicPorts,omitempty"`
 	Links       Links          `json:"Links,omitempty"`
 
+	HealthChecks  containers.HealthChecks   `json:"HealthChecks,omitempty"`
+	RestartPolicy *containers.RestartPolicy `json:"RestartPolicy,omitempty"`
+	Volumes       containers.VolumeMounts   `json:"Volumes,omitempty"`
+	Labels        containers.Labels         `json:"Labels,omitempty"`
+	Dependencies  Dependencies              `json:"Dependencies,omitempty"`
+	// The named security profile the instances are installed with
+	SecurityProfile containers.ProfileIdentifier `json:"SecurityProfile,omitempty"`
+	// Actions run at stages of the lifecycle of each instance
+	Hooks containers.LifecycleHooks `json:"Hooks,omitempty"`
+
 	Count    int
 	Affinity string `json:"Affinity,omitempty"`
 
@@ -272,6 +272,9 @@ func TestPrepareDeploymentInterlink(t *testing.T) {
 			if link.ToPort.Default() {
 				t.Fatalf("Expected all link ports to be assigned %s: %+v", instance.Id, link)
 			}
+			if link.Name != link.from {
+				t.Fatalf("Expected the link to be named for its target %s: %+v", link.from, link)
+			}
 		}
 	}
 
@@ -279,6 +282,47 @@ func TestPrepareDeploymentInterlink(t *testing.T) {
 	// t.Log(string(b))
 }
 
+func TestPrepareDeploymentDottedLinkName(t *testing.T) {
+	dep := createDeployment(`{
+    "containers":[
+      {
+        "name":"web",
+        "count":1,
+        "image":"pmorie/sti-html-app",
+        "links":[
+          {"to":"db.main"}
+        ]
+      },
+      {
+        "name":"db.main",
+        "count":1,
+        "image":"pmorie/sti-db-app",
+        "publicports":[
+          {"internal":5432,"external":0}
+        ]
+      }
+    ]
+  }`)
+	changes, _, err := dep.Describe(oneHost, loopbackTransport)
+	if err != nil {
+		t.Fatal("Should not have received an error", err)
+	}
+	assignPorts(changes)
+	changes.UpdateLinks()
+
+	web := changes.Instances.ReferencesFor("web")[0]
+	if len(web.links) != 1 {
+		t.Fatalf("Expected one link from web: %+v", web.links)
+	}
+	link := web.links[0].NetworkLink
+	if err := link.Check(); err != nil {
+		t.Fatal("Expected a dotted link name to be valid", err)
+	}
+	if link.Name != "db.main" || link.VariablePrefix() != "DB_MAIN" {
+		t.Fatalf("Unexpected link name %s (%s)", link.Name, link.VariablePrefix())
+	}
+}
+
 func TestPrepareDeploymentMongo(t *testing.T) {
 	dep := loadDeployment("./fixtures/mongo_deploy.json")
 	changes, _, err := dep.Describe(oneHost, loopbackTransport)
@@ -360,3 +404,29 @@ func TestReloadDeploymentMongo(t *testing.T) {
 		t.Fatalf("Expected to remove %d instances, got %d", 3, len(removed))
 	}
 }
+
+func TestPrepareDeploymentDependencies(t *testing.T) {
+	dep := loadDeployment("./fixtures/rockmongo_mongo.json")
+	next, _, err := dep.Describe(oneHost, loopbackTransport)
+	if err != nil {
+		t.Fatal("Should not have received an error", err)
+	}
+	rockmongo := next.Instances.ReferencesFor("rockmongo")[0]
+	mongodb := next.Instances.ReferencesFor("mongodb")[0]
+	deps := rockmongo.Dependencies()
+	if len(deps) != 1 || deps[0].Id != mongodb.Id || deps[0].Required {
+		t.Fatalf("Expected rockmongo to want mongodb: %+v", deps)
+	}
+	if len(mongodb.Dependencies()) != 0 {
+		t.Fatalf("Expected mongodb to have no dependencies: %+v", mongodb.Dependencies())
+	}
+
+	dep.Containers[1].Dependencies = Dependencies{{To: "rockmongo"}}
+	if _, _, err := dep.Describe(oneHost, loopbackTransport); err != nil {
+		t.Fatal("A link should not be ordered when it would create a cycle", err)
+	}
+	dep.Containers[0].Dependencies = Dependencies{{To: "mongodb", Required: true}}
+	if _, _, err := dep.Describe(oneHost, loopbackTransport); err == nil {
+		t.Fatal("Expected an error for a dependency cycle")
+	}
+}
@@ -31,6 +31,8 @@ type Instance struct {
 	on transport.Locator
 	// The generated links for this instance
 	links InstanceLinks
+	// The instances on the same host this instance starts after
+	dependencies containers.ContainerDependencies
 	// A cached hostname for this instance
 	hostname string
 }
@@ -41,6 +43,10 @@ func (i *Instance) NetworkLinks() containers.NetworkLinks {
 	return i.links.NetworkLinks()
 }
 
+func (i *Instance) Dependencies() containers.ContainerDependencies {
+	return i.dependencies
+}
+
 func (i *Instance) Added() bool {
 	return i.add
 }
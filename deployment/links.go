@@ -199,6 +199,8 @@ func (link containerLink) appendLinks() error {
 
 						ToPort: mapping.External,
 						ToHost: name,
+						// the container sees <TARGET>_HOST and <TARGET>_PORT
+						Name: link.Target.Name,
 					},
 					from:     link.Target.Name,
 					fromPort: port,
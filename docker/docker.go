@@ -1,13 +1,16 @@
 package docker
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"github.com/fsouza/go-dockerclient"
 	"github.com/fsouza/go-dockerclient/engine"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -28,6 +31,10 @@ func (d *DockerClient) ListContainers() ([]docker.APIContainers, error) {
 	return d.client.ListContainers(docker.ListContainersOptions{All: true})
 }
 
+func (d *DockerClient) RemoveContainer(ID string) error {
+	return d.client.RemoveContainer(docker.RemoveContainerOptions{ID: ID, RemoveVolumes: true, Force: true})
+}
+
 func (d *DockerClient) ForceCleanContainer(ID string) error {
 	if err := d.client.KillContainer(docker.KillContainerOptions{ID: ID}); err != nil {
 		return err
@@ -35,6 +42,16 @@ func (d *DockerClient) ForceCleanContainer(ID string) error {
 	return d.client.RemoveContainer(docker.RemoveContainerOptions{ID, true, true})
 }
 
+// List the top level images, which exclude the intermediate layers of
+// other images.
+func (d *DockerClient) ListImages() ([]docker.APIImages, error) {
+	return d.client.ListImages(false)
+}
+
+func (d *DockerClient) RemoveImage(name string) error {
+	return d.client.RemoveImage(name)
+}
+
 func lookupContainer(containerName string, client *docker.Client, waitForContainer bool) containerLookupResult {
 	timeout := 0
 	if waitForContainer {
@@ -109,6 +126,76 @@ func (d *DockerClient) GetImage(imageName string) (*docker.Image, error) {
 	}
 }
 
+// Pull a tag of a repository, writing the progress of each layer to w.
+// The repository may be prefixed by the host of its registry.
+func (d *DockerClient) PullImage(repository, tag string, w io.Writer) error {
+	return d.client.PullImage(docker.PullImageOptions{Repository: repository, Tag: tag, OutputStream: w}, docker.AuthConfiguration{})
+}
+
+// Split an image name of the form [<registry>/]<repository>[:<tag>] or
+// [<registry>/]<repository>[:<tag>]@<digest>.  A digest is returned as
+// the tag, since it is pulled in place of one and identifies the image
+// even when a tag is also given.  The registry and tag are empty when not
+// specified.
+func ParseImageName(name string) (registry, repository, tag string) {
+	repository = name
+	digest := ""
+	if i := strings.Index(repository, "@"); i != -1 {
+		repository, digest = repository[:i], repository[i+1:]
+	}
+	if i := strings.LastIndex(repository, ":"); i != -1 && !strings.Contains(repository[i+1:], "/") {
+		repository, tag = repository[:i], repository[i+1:]
+	}
+	if digest != "" {
+		tag = digest
+	}
+	if i := strings.Index(repository, "/"); i != -1 {
+		if host := repository[:i]; strings.ContainsAny(host, ".:") || host == "localhost" {
+			registry, repository = host, repository[i+1:]
+		}
+	}
+	return
+}
+
+// Write the image and its parent layers to w as a tar archive.  The
+// client does not support saving or loading images, so the docker
+// command is used.
+func SaveImage(dockerSocket, name string, w io.Writer) error {
+	cmd := exec.Command("/usr/bin/docker", "-H", dockerSocket, "save", name)
+	cmd.Stdout = w
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return errors.New(fmt.Sprintf("docker save: %s %s", err.Error(), strings.TrimSpace(stderr.String())))
+	}
+	return nil
+}
+
+// Load the images in a tar archive written by SaveImage, writing the
+// output of the load to w.
+func LoadImage(dockerSocket string, r io.Reader, w io.Writer) error {
+	cmd := exec.Command("/usr/bin/docker", "-H", dockerSocket, "load")
+	cmd.Stdin = r
+	cmd.Stdout = w
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return errors.New(fmt.Sprintf("docker load: %s %s", err.Error(), strings.TrimSpace(stderr.String())))
+	}
+	return nil
+}
+
+// Create a container that only holds the volumes of an image, the way
+// a unit creates it before the first start of a container.
+func RunDataContainer(dockerSocket, name, image string) error {
+	cmd := exec.Command("/usr/bin/docker", "-H", dockerSocket, "run", "--name", name, "--entrypoint", "true", image)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return errors.New(fmt.Sprintf("docker run: %s %s", err.Error(), strings.TrimSpace(string(output))))
+	}
+	return nil
+}
+
 func (d *DockerClient) GetContainerIPs(ids []string) (map[string]string, error) {
 	ips := make(map[string]string)
 	for _, id := range ids {
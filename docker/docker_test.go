@@ -0,0 +1,21 @@
+package docker
+
+import (
+	"testing"
+)
+
+func TestParseImageName(t *testing.T) {
+	for _, c := range []struct{ name, registry, repository, tag string }{
+		{"busybox", "", "busybox", ""},
+		{"openshift/origin:v1", "", "openshift/origin", "v1"},
+		{"localhost:5000/app", "localhost:5000", "app", ""},
+		{"registry.example.com/team/app:2.0", "registry.example.com", "team/app", "2.0"},
+		{"busybox@sha256:2a9b0e4e", "", "busybox", "sha256:2a9b0e4e"},
+		{"localhost:5000/app:2.0@sha256:2a9b0e4e", "localhost:5000", "app", "sha256:2a9b0e4e"},
+	} {
+		registry, repository, tag := ParseImageName(c.name)
+		if registry != c.registry || repository != c.repository || tag != c.tag {
+			t.Errorf("Expected %s to be %q %q %q, got %q %q %q", c.name, c.registry, c.repository, c.tag, registry, repository, tag)
+		}
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
+	"net/url"
 	"reflect"
 	"strings"
 	"time"
@@ -145,8 +146,15 @@ func (t *TokenConfiguration) Handler(parent http.Handler) http.HandlerFunc {
 		}
 
 		job := jobhttp.HttpContentRequest{ContentRequest: cjobs.ContentRequest{Type: token.Type, Locator: token.Locator}}
+		path, err := url.Parse(job.HttpPath())
+		if err != nil {
+			http.Error(w, "Token is not valid", http.StatusBadRequest)
+			return
+		}
+		jobhttp.AuthorizeContentToken(r, token.Type)
 		r.Method = job.HttpMethod()
-		r.URL.Path = job.HttpPath()
+		// keep escaped separators in the locator
+		r.URL.Path, r.URL.RawPath = path.Path, path.RawPath
 		parent.ServeHTTP(w, r)
 	}
 }
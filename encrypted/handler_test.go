@@ -95,17 +95,19 @@ func TestHandle(t *testing.T) {
 		t.Fatal("Unable to load client private RSA key", err)
 	}
 
-	buf := &bytes.Buffer{}
-	source := &TokenData{Locator: "foo", Type: "env", ExpirationDate: time.Now().Unix() + 10}
-	encoder := json.NewEncoder(buf)
-	encoder.Encode(source)
-	cipher, _ := rsa.EncryptPKCS1v15(rand.Reader, serverPub, buf.Bytes())
-	hash := crypto.SHA256.New()
-	hash.Write(cipher)
-	hashed := hash.Sum(nil)
-	sig, _ := rsa.SignPKCS1v15(rand.Reader, clientPriv, crypto.SHA256, hashed)
+	tokenPath := func(source *TokenData) string {
+		buf := &bytes.Buffer{}
+		encoder := json.NewEncoder(buf)
+		encoder.Encode(source)
+		cipher, _ := rsa.EncryptPKCS1v15(rand.Reader, serverPub, buf.Bytes())
+		hash := crypto.SHA256.New()
+		hash.Write(cipher)
+		hashed := hash.Sum(nil)
+		sig, _ := rsa.SignPKCS1v15(rand.Reader, clientPriv, crypto.SHA256, hashed)
+		return fmt.Sprintf("/key/%s/%s", base64.URLEncoding.EncodeToString(sig), base64.URLEncoding.EncodeToString(cipher))
+	}
 
-	path := fmt.Sprintf("/key/%s/%s", base64.URLEncoding.EncodeToString(sig), base64.URLEncoding.EncodeToString(cipher))
+	path := tokenPath(&TokenData{Locator: "foo", Type: "env", ExpirationDate: time.Now().Unix() + 10})
 
 	test := func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/environment/foo" {
@@ -121,4 +123,18 @@ func TestHandle(t *testing.T) {
 	if w.code != 0 {
 		t.Fatal("Expected code 0", w.code)
 	}
+
+	path = tokenPath(&TokenData{Locator: "openshift/origin:v1", Type: "image", ExpirationDate: time.Now().Unix() + 10})
+	test = func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/images/openshift%2Forigin:v1/export" {
+			t.Fatal("Expected to be called with /images/openshift%2Forigin:v1/export", r.URL.EscapedPath())
+		}
+	}
+	handler = config.Handler(http.HandlerFunc(test))
+	r, _ = http.NewRequest("GET", path, nil)
+	w = &testWriter{test: t, headers: make(http.Header)}
+	handler.ServeHTTP(w, r)
+	if w.code != 0 {
+		t.Fatal("Expected code 0", w.code)
+	}
 }
@@ -4,8 +4,10 @@ package cmd
 
 import (
 	"github.com/openshift/geard/cmd"
+	"github.com/openshift/geard/containers"
 	"github.com/openshift/geard/git"
 	githttp "github.com/openshift/geard/git/http"
+	gitjobs "github.com/openshift/geard/git/jobs"
 	"github.com/openshift/geard/http"
 	sshcmd "github.com/openshift/geard/ssh/cmd"
 )
@@ -14,6 +16,7 @@ func init() {
 	cmd.AddInitializer(git.InitializeData, cmd.ForDaemon)
 
 	http.AddHttpExtension(&githttp.HttpExtension{})
+	containers.AddEnvironmentSourceFetcher("git", gitjobs.OpenRepositoryFile)
 
 	cmd.AddCommandExtension(registerRemote, false)
 	cmd.AddCommandExtension(registerLocal, true)
@@ -0,0 +1,49 @@
+package jobs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/git"
+	"github.com/openshift/geard/utils"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os/exec"
+	"strings"
+)
+
+// Open a file in a repository hosted on this server, referenced as
+// git://<repository>/<path>[#<ref>].  The ref defaults to master.
+func OpenRepositoryFile(u *url.URL) (io.ReadCloser, error) {
+	id, err := containers.NewIdentifier(u.Host)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := NewGitCommitRef(u.Fragment)
+	if err != nil {
+		return nil, err
+	}
+	if ref == EmptyGitCommitRef {
+		ref = GitCommitRef("master")
+	}
+	if strings.HasPrefix(string(ref), "-") {
+		return nil, errors.New("Git ref may not start with '-'")
+	}
+	path := strings.TrimPrefix(u.Path, "/")
+	if path == "" {
+		return nil, errors.New("A git environment source must be of the form git://<repository>/<path>[#<ref>]")
+	}
+
+	cmd := exec.Command("/usr/bin/git", "cat-file", "blob", string(ref)+":"+path)
+	cmd.Env = []string{}
+	cmd.Dir = git.RepoIdentifier(id).RepositoryPathFor()
+	var stderr bytes.Buffer
+	cmd.Stderr = utils.LimitWriter(&stderr, 20*1024)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Failed to read %s from repository %s: %s\n", path, id, err.Error()) + stderr.String())
+	}
+	return ioutil.NopCloser(bytes.NewReader(out)), nil
+}
@@ -0,0 +1,103 @@
+package health
+
+import (
+	"errors"
+	"fmt"
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/docker"
+	"net"
+	"net/http"
+	"os/exec"
+	"time"
+)
+
+// Run a single health check against a container, returning an error
+// describing the failure if the check did not pass.
+func (m *Monitor) runCheck(id containers.Identifier, check *containers.HealthCheck) error {
+	timeout := check.TimeoutDuration()
+	switch check.Type {
+	case containers.HealthCheckTCP:
+		addr, err := m.addressFor(id, check)
+		if err != nil {
+			return err
+		}
+		conn, err := net.DialTimeout("tcp", addr, timeout)
+		if err != nil {
+			return err
+		}
+		conn.Close()
+		return nil
+
+	case containers.HealthCheckHTTP:
+		addr, err := m.addressFor(id, check)
+		if err != nil {
+			return err
+		}
+		client := &http.Client{
+			Transport: &http.Transport{
+				Dial: func(network, a string) (net.Conn, error) {
+					return net.DialTimeout(network, a, timeout)
+				},
+				ResponseHeaderTimeout: timeout,
+				DisableKeepAlives:     true,
+			},
+		}
+		path := check.Path
+		if path == "" {
+			path = "/"
+		}
+		resp, err := client.Get("http://" + addr + path)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+			return errors.New(fmt.Sprintf("GET %s returned %d", path, resp.StatusCode))
+		}
+		return nil
+
+	case containers.HealthCheckCommand:
+		args := append([]string{"--container=" + id.ContainerFor(), "--"}, check.Command...)
+		cmd := exec.Command("/usr/bin/switchns", args...)
+		if err := cmd.Start(); err != nil {
+			return err
+		}
+		done := make(chan error, 1)
+		go func() { done <- cmd.Wait() }()
+		select {
+		case err := <-done:
+			if err != nil {
+				return errors.New(fmt.Sprintf("%s: %v", check.String(), err))
+			}
+			return nil
+		case <-time.After(timeout):
+			cmd.Process.Kill()
+			<-done
+			return errors.New(fmt.Sprintf("%s: timed out after %s", check.String(), timeout))
+		}
+	}
+	return errors.New(fmt.Sprintf("Unrecognized health check type '%s'", check.Type))
+}
+
+// Resolve an internal port to an address reachable from the host,
+// preferring the reserved external port if one is mapped.
+func (m *Monitor) addressFor(id containers.Identifier, check *containers.HealthCheck) (string, error) {
+	if ports, err := containers.GetExistingPorts(id); err == nil {
+		if pair, found := ports.Find(check.Port); found && !pair.External.Default() {
+			return fmt.Sprintf("127.0.0.1:%d", pair.External), nil
+		}
+	}
+
+	client, err := docker.GetConnection(m.dockerSocket)
+	if err != nil {
+		return "", err
+	}
+	container, err := client.GetContainer(id.ContainerFor(), false)
+	if err != nil {
+		return "", err
+	}
+	if container.NetworkSettings == nil || container.NetworkSettings.IPAddress == "" {
+		return "", errors.New("The container has no IP address")
+	}
+	return fmt.Sprintf("%s:%d", container.NetworkSettings.IPAddress, check.Port), nil
+}
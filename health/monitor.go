@@ -0,0 +1,175 @@
+// Periodically runs the health checks defined for installed containers and
+// records whether each running container is available.
+package health
+
+import (
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/systemd"
+	"log"
+	"os"
+	"time"
+)
+
+// How often the set of containers with health checks is refreshed
+const reconcileInterval = 10 * time.Second
+
+type Monitor struct {
+	dockerSocket string
+	watching     map[containers.Identifier]*watcher
+}
+
+type watcher struct {
+	checks  containers.HealthChecks
+	modTime time.Time
+	stop    chan bool
+}
+
+type checkResult struct {
+	index  int
+	active bool
+	err    error
+}
+
+func NewMonitor(dockerSocket string) *Monitor {
+	return &Monitor{dockerSocket, make(map[containers.Identifier]*watcher)}
+}
+
+func (m *Monitor) Start() {
+	go func() {
+		for {
+			m.reconcile()
+			time.Sleep(reconcileInterval)
+		}
+	}()
+}
+
+// Start watching containers whose checks were added or changed, and stop
+// watching those whose checks were removed.
+func (m *Monitor) reconcile() {
+	found := make(map[containers.Identifier]bool)
+	err := containers.WalkContainerUnits(func(id containers.Identifier) {
+		info, err := os.Stat(id.HealthChecksPathFor())
+		if err != nil {
+			return
+		}
+		found[id] = true
+
+		w, ok := m.watching[id]
+		if ok && w.modTime.Equal(info.ModTime()) {
+			return
+		}
+		if ok {
+			close(w.stop)
+			delete(m.watching, id)
+		}
+
+		checks, err := containers.ReadHealthChecks(id)
+		if err != nil {
+			log.Printf("health: Unable to read health checks for %s: %v", id, err)
+			return
+		}
+		w = &watcher{checks, info.ModTime(), make(chan bool)}
+		m.watching[id] = w
+		go m.watch(id, w)
+	})
+	if err != nil {
+		log.Printf("health: Unable to list installed containers: %v", err)
+		return
+	}
+
+	for id, w := range m.watching {
+		if !found[id] {
+			close(w.stop)
+			delete(m.watching, id)
+			containers.SetHealth(id, containers.HealthNone, "")
+		}
+	}
+}
+
+// Combine the results of each check into the health of the container.  A
+// container is healthy once every check has passed Threshold times in a
+// row, and unhealthy as soon as any check fails Threshold times in a row.
+func (m *Monitor) watch(id containers.Identifier, w *watcher) {
+	results := make(chan checkResult)
+	for i := range w.checks {
+		go m.poll(id, i, &w.checks[i], results, w.stop)
+	}
+
+	passed := make([]int, len(w.checks))
+	failed := make([]int, len(w.checks))
+	messages := make([]string, len(w.checks))
+
+	for {
+		select {
+		case r := <-results:
+			if !r.active {
+				for i := range w.checks {
+					passed[i], failed[i], messages[i] = 0, 0, ""
+				}
+				if err := containers.SetHealth(id, containers.HealthNone, ""); err != nil {
+					log.Printf("health: Unable to clear health of %s: %v", id, err)
+				}
+				continue
+			}
+			if r.err != nil {
+				passed[r.index] = 0
+				failed[r.index]++
+				messages[r.index] = r.err.Error()
+			} else {
+				passed[r.index]++
+				failed[r.index] = 0
+				messages[r.index] = ""
+			}
+
+			health := containers.HealthHealthy
+			message := ""
+			for i := range w.checks {
+				threshold := w.checks[i].Threshold
+				if failed[i] >= threshold {
+					health = containers.HealthUnhealthy
+					message = messages[i]
+					break
+				}
+				if passed[i] < threshold {
+					health = containers.HealthStarting
+					if message == "" {
+						message = messages[i]
+					}
+				}
+			}
+			if err := containers.SetHealth(id, health, message); err != nil {
+				log.Printf("health: Unable to record health of %s: %v", id, err)
+			}
+
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// Run a single check on its interval while the container unit is active.
+func (m *Monitor) poll(id containers.Identifier, index int, check *containers.HealthCheck, results chan<- checkResult, stop <-chan bool) {
+	ticker := time.NewTicker(check.IntervalDuration())
+	defer ticker.Stop()
+	for {
+		active, err := systemd.IsUnitProperty(systemd.Connection(), id.UnitNameFor(), func(p map[string]interface{}) bool {
+			return p["ActiveState"] == "active"
+		})
+		result := checkResult{index: index, active: err == nil && active}
+		if result.active {
+			result.err = m.runCheck(id, check)
+		}
+
+		select {
+		case results <- result:
+		case <-stop:
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
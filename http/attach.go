@@ -0,0 +1,329 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/openshift/geard/jobs"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// The protocol a connection is upgraded to when attaching to a job.
+const AttachProtocol = "geard-attach"
+
+// A job that exchanges input and output with the caller while it runs.
+// Over http the connection is upgraded and the job output is written as
+// a series of frames, each a one byte frame type, three bytes of
+// padding, and the four byte big endian length of the payload.  The
+// client writes the input of the job to the connection unframed.
+type HttpAttachable interface {
+	Attached() bool
+	Attach(stdin io.Reader, stdout, stderr io.Writer)
+	Streams() (stdin io.Reader, stdout, stderr io.Writer)
+}
+
+const (
+	frameStdout byte = 1
+	frameStderr byte = 2
+	// A pending value for the response, as "<name>=<value>"
+	framePending byte = 3
+	// The message of a job failure
+	frameFailure byte = 4
+)
+
+type frameWriter struct {
+	w    io.Writer
+	lock sync.Mutex
+}
+
+func (f *frameWriter) WriteFrame(t byte, p []byte) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	header := make([]byte, 8)
+	header[0] = t
+	binary.BigEndian.PutUint32(header[4:], uint32(len(p)))
+	if _, err := f.w.Write(header); err != nil {
+		return err
+	}
+	_, err := f.w.Write(p)
+	return err
+}
+
+func (f *frameWriter) Stream(t byte) io.Writer {
+	return &frameStream{f, t}
+}
+
+type frameStream struct {
+	f *frameWriter
+	t byte
+}
+
+func (s *frameStream) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if err := s.f.WriteFrame(s.t, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func readFrame(r io.Reader) (byte, []byte, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+	p := make([]byte, binary.BigEndian.Uint32(header[4:]))
+	if _, err := io.ReadFull(r, p); err != nil {
+		return 0, nil, err
+	}
+	return header[0], p, nil
+}
+
+// The response to an attached job, written to the upgraded connection.
+type attachedJobResponse struct {
+	out       *frameWriter
+	succeeded bool
+	failed    bool
+}
+
+func (s *attachedJobResponse) StreamResult() bool {
+	return true
+}
+
+func (s *attachedJobResponse) Success(t jobs.ResponseSuccess) {
+	if s.failed {
+		panic("Cannot call Success() after failure")
+	}
+	if s.succeeded {
+		panic("Cannot call Success() twice")
+	}
+	s.succeeded = true
+}
+
+func (s *attachedJobResponse) SuccessWithData(t jobs.ResponseSuccess, data interface{}) {
+	s.Success(t)
+	json.NewEncoder(s.out.Stream(frameStdout)).Encode(&data)
+}
+
+func (s *attachedJobResponse) SuccessWithWrite(t jobs.ResponseSuccess, flush, structured bool) io.Writer {
+	s.Success(t)
+	return s.out.Stream(frameStdout)
+}
+
+func (s *attachedJobResponse) WritePendingSuccess(name string, value interface{}) {
+	h, ok := value.(HeaderSerialization)
+	if !ok {
+		panic("Passed value does not implement HeaderSerialization for http")
+	}
+	s.out.WriteFrame(framePending, []byte(name+"="+h.ToHeader()))
+}
+
+func (s *attachedJobResponse) Failure(err error) {
+	if s.succeeded {
+		panic("May not invoke failure after Success()")
+	}
+	if s.failed {
+		panic("May not write failure twice")
+	}
+	s.failed = true
+	s.out.WriteFrame(frameFailure, []byte(err.Error()))
+}
+
+// The go-json-rest handler wraps the response writer, so the original
+// writer is recorded for requests that may be upgraded.
+var hijackers = struct {
+	sync.Mutex
+	writers map[*http.Request]http.Hijacker
+}{writers: make(map[*http.Request]http.Hijacker)}
+
+func recordHijacker(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h, ok := w.(http.Hijacker); ok && r.Header.Get("Upgrade") != "" {
+			hijackers.Lock()
+			hijackers.writers[r] = h
+			hijackers.Unlock()
+			defer func() {
+				hijackers.Lock()
+				delete(hijackers.writers, r)
+				hijackers.Unlock()
+			}()
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func hijackerFor(r *http.Request) (http.Hijacker, bool) {
+	hijackers.Lock()
+	defer hijackers.Unlock()
+	h, ok := hijackers.writers[r]
+	return h, ok
+}
+
+func (conf *HttpConfiguration) serveAttached(w http.ResponseWriter, r *http.Request, context *jobs.JobContext, job jobs.Job, attach HttpAttachable) {
+	hijacker, ok := hijackerFor(r)
+	if !ok || !strings.EqualFold(r.Header.Get("Upgrade"), AttachProtocol) {
+		http.Error(w, fmt.Sprintf("The connection must be upgraded to %s to attach to this job", AttachProtocol), http.StatusBadRequest)
+		return
+	}
+	conn, buf, err := hijacker.Hijack()
+	if err != nil {
+		log.Printf("http: Unable to take over the connection: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	fmt.Fprintf(buf, "HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: %s\r\n\r\n", AttachProtocol)
+	if err := buf.Flush(); err != nil {
+		return
+	}
+
+	out := &frameWriter{w: conn}
+	attach.Attach(buf.Reader, out.Stream(frameStdout), out.Stream(frameStderr))
+	response := &attachedJobResponse{out: out}
+
+	wait, errd := conf.Dispatcher.Dispatch(context.Id, job, response)
+	if errd != nil {
+		response.Failure(errd)
+		return
+	}
+	<-wait
+}
+
+func (h *HttpTransport) executeAttached(baseUrl *url.URL, job RemoteExecutable, attach HttpAttachable, res jobs.Response) error {
+	body := &bytes.Buffer{}
+	if err := job.MarshalHttpRequestBody(body); err != nil {
+		return err
+	}
+	req, errn := http.NewRequest(job.HttpMethod(), baseUrl.String(), body)
+	if errn != nil {
+		return errn
+	}
+
+	id := job.MarshalRequestIdentifier()
+	if len(id) == 0 {
+		id = jobs.NewRequestIdentifier()
+	}
+	query := &url.Values{}
+	job.MarshalUrlQuery(query)
+
+	req.Header.Set("X-Request-Id", id.String())
+	req.Header.Set("If-Match", "api="+ApiVersion())
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", AttachProtocol)
+	req.URL.Path = job.HttpPath()
+	req.URL.RawQuery = query.Encode()
+
+	conn, err := net.Dial("tcp", baseUrl.Host)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := req.Write(conn); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		defer resp.Body.Close()
+		if resp.Header.Get("Content-Type") == "application/json" {
+			data := httpFailureResponse{}
+			if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+				return err
+			}
+			res.Failure(jobs.SimpleError{jobs.ResponseError, data.Message})
+			return nil
+		}
+		message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.New(fmt.Sprintf("Unable to attach to the remote job (%d): %s", resp.StatusCode, strings.TrimSpace(string(message))))
+	}
+
+	stdin, stdout, stderr := attach.Streams()
+	if stdin != nil {
+		go func() {
+			io.Copy(conn, stdin)
+			if tcp, ok := conn.(*net.TCPConn); ok {
+				tcp.CloseWrite()
+			}
+		}()
+	}
+
+	succeeded := false
+	success := func() {
+		if !succeeded {
+			succeeded = true
+			if stdout == nil {
+				stdout = res.SuccessWithWrite(jobs.ResponseOk, true, false)
+			} else {
+				res.Success(jobs.ResponseOk)
+			}
+			if stderr == nil {
+				stderr = stdout
+			}
+		}
+	}
+
+	for {
+		t, p, err := readFrame(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if succeeded {
+				// the response may not fail once output has been written
+				fmt.Fprintf(stderr, "Connection to the remote job was lost: %v\n", err)
+				return nil
+			}
+			return err
+		}
+		switch t {
+		case frameStdout:
+			success()
+			stdout.Write(p)
+		case frameStderr:
+			success()
+			stderr.Write(p)
+		case framePending:
+			pair := strings.SplitN(string(p), "=", 2)
+			if len(pair) != 2 {
+				continue
+			}
+			headers := http.Header{}
+			headers.Set("X-"+pair[0], pair[1])
+			data, err := job.UnmarshalHttpResponse(headers, nil, ResponseTable)
+			if err != nil {
+				log.Printf("http_remote: Unable to read pending value %s: %v", pair[0], err)
+				continue
+			}
+			if pending, ok := data.(map[string]interface{}); ok {
+				for k := range pending {
+					res.WritePendingSuccess(k, pending[k])
+				}
+			}
+		case frameFailure:
+			if succeeded {
+				fmt.Fprintf(stderr, "%s\n", p)
+				return nil
+			}
+			res.Failure(jobs.SimpleError{jobs.ResponseError, string(p)})
+			return nil
+		}
+	}
+	success()
+	return nil
+}
@@ -9,7 +9,9 @@ import (
 	"github.com/openshift/geard/utils"
 	"github.com/openshift/go-json-rest"
 	"io"
+	"net/url"
 	"regexp"
+	"strconv"
 )
 
 type DefaultRequest struct{}
@@ -38,6 +40,33 @@ func (h *HttpRunContainerRequest) Handler(conf *HttpConfiguration) JobHandler {
 	}
 }
 
+type HttpContainerRunResultRequest struct {
+	cjobs.ContainerRunResultRequest
+	DefaultRequest
+}
+
+func (h *HttpContainerRunResultRequest) HttpMethod() string { return "GET" }
+func (h *HttpContainerRunResultRequest) HttpPath() string {
+	return Inline("/jobs/:id/result", h.Name)
+}
+func (h *HttpContainerRunResultRequest) Handler(conf *HttpConfiguration) JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (jobs.Job, error) {
+		id, err := jobs.NewRequestIdentifierFromString(r.PathParam("id"))
+		if err != nil {
+			return nil, err
+		}
+		job := &cjobs.ContainerRunResultRequest{Name: id.String()}
+		if s := r.URL.Query().Get("lines"); s != "" {
+			lines, err := strconv.Atoi(s)
+			if err != nil || lines < 0 {
+				return nil, errors.New("The number of lines must be a positive integer")
+			}
+			job.Lines = lines
+		}
+		return job, nil
+	}
+}
+
 type HttpInstallContainerRequest struct {
 	cjobs.InstallContainerRequest
 	DefaultRequest
@@ -60,6 +89,7 @@ func (h *HttpInstallContainerRequest) Handler(conf *HttpConfiguration) JobHandle
 		}
 		data.Id = id
 		data.RequestIdentifier = context.Id
+		data.DockerSocket = conf.Docker.Socket
 
 		if err := data.Check(); err != nil {
 			return nil, err
@@ -94,10 +124,74 @@ func (h *HttpListContainersRequest) HttpMethod() string { return "GET" }
 func (h *HttpListContainersRequest) HttpPath() string   { return "/containers" }
 func (h *HttpListContainersRequest) Handler(conf *HttpConfiguration) JobHandler {
 	return func(context *jobs.JobContext, r *rest.Request) (jobs.Job, error) {
-		return &cjobs.ListContainersRequest{}, nil
+		selector, err := containers.NewLabelSelector(r.URL.Query().Get("selector"))
+		if err != nil {
+			return nil, err
+		}
+		return &cjobs.ListContainersRequest{Selector: selector}, nil
 	}
 }
 
+type HttpListContainerStatsRequest struct {
+	cjobs.ListContainerStatsRequest
+	DefaultRequest
+}
+
+func (h *HttpListContainerStatsRequest) HttpMethod() string { return "GET" }
+func (h *HttpListContainerStatsRequest) HttpPath() string   { return "/stats" }
+func (h *HttpListContainerStatsRequest) Handler(conf *HttpConfiguration) JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (jobs.Job, error) {
+		interval, err := statsInterval(r)
+		if err != nil {
+			return nil, err
+		}
+		job := &cjobs.ListContainerStatsRequest{Interval: interval}
+		if err := job.Check(); err != nil {
+			return nil, err
+		}
+		return job, nil
+	}
+}
+
+type HttpContainerStatsRequest struct {
+	cjobs.ContainerStatsRequest
+	DefaultRequest
+}
+
+func (h *HttpContainerStatsRequest) HttpMethod() string { return "GET" }
+func (h *HttpContainerStatsRequest) HttpPath() string {
+	return Inline("/container/:id/stats", string(h.Id))
+}
+func (h *HttpContainerStatsRequest) Handler(conf *HttpConfiguration) JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (jobs.Job, error) {
+		id, errg := containers.NewIdentifier(r.PathParam("id"))
+		if errg != nil {
+			return nil, errg
+		}
+		interval, err := statsInterval(r)
+		if err != nil {
+			return nil, err
+		}
+		job := &cjobs.ContainerStatsRequest{Id: id, Interval: interval}
+		if err := job.Check(); err != nil {
+			return nil, err
+		}
+		return job, nil
+	}
+}
+
+func statsInterval(r *rest.Request) (int, error) {
+	s := r.URL.Query().Get("interval")
+	if s == "" {
+		return 0, nil
+	}
+	interval, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, errors.New("The interval must be an integer number of seconds")
+	}
+	return interval, nil
+}
+
 type HttpListBuildsRequest cjobs.ListBuildsRequest
 
 func (h *HttpListBuildsRequest) HttpMethod() string { return "GET" }
@@ -118,7 +212,58 @@ func (h *HttpListImagesRequest) Handler(conf *HttpConfiguration) JobHandler {
 	}
 }
 
-type HttpContainerLogRequest cjobs.ContainerLogRequest
+type HttpPullImageRequest struct {
+	cjobs.PullImageRequest
+	DefaultRequest
+}
+
+func (h *HttpPullImageRequest) HttpMethod() string { return "POST" }
+func (h *HttpPullImageRequest) HttpPath() string   { return "/images/pull" }
+func (h *HttpPullImageRequest) Handler(conf *HttpConfiguration) JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (jobs.Job, error) {
+		data := cjobs.PullImageRequest{}
+		if r.Body != nil {
+			dec := json.NewDecoder(limitedBodyReader(r))
+			if err := dec.Decode(&data); err != nil && err != io.EOF {
+				return nil, err
+			}
+		}
+		data.DockerSocket = conf.Docker.Socket
+		if err := data.Check(); err != nil {
+			return nil, err
+		}
+		return &data, nil
+	}
+}
+
+type HttpImportImageRequest struct {
+	cjobs.ImportImageRequest
+	DefaultRequest
+}
+
+func (h *HttpImportImageRequest) HttpMethod() string { return "POST" }
+func (h *HttpImportImageRequest) HttpPath() string   { return "/images/import" }
+func (h *HttpImportImageRequest) Handler(conf *HttpConfiguration) JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (jobs.Job, error) {
+		data := cjobs.ImportImageRequest{}
+		if r.Body != nil {
+			dec := json.NewDecoder(limitedBodyReader(r))
+			if err := dec.Decode(&data); err != nil && err != io.EOF {
+				return nil, err
+			}
+		}
+		data.DockerSocket = conf.Docker.Socket
+		if err := data.Check(); err != nil {
+			return nil, err
+		}
+		return &data, nil
+	}
+}
+
+type HttpContainerLogRequest struct {
+	cjobs.ContainerLogRequest
+	DefaultRequest
+}
 
 func (h *HttpContainerLogRequest) HttpMethod() string { return "GET" }
 func (h *HttpContainerLogRequest) HttpPath() string   { return Inline("/container/:id/log", string(h.Id)) }
@@ -128,9 +273,48 @@ func (h *HttpContainerLogRequest) Handler(conf *HttpConfiguration) JobHandler {
 		if errg != nil {
 			return nil, errg
 		}
-		return &cjobs.ContainerLogRequest{
-			id,
-		}, nil
+		query := r.URL.Query()
+		job := &cjobs.ContainerLogRequest{Id: id}
+		job.Since = query.Get("since")
+		job.Until = query.Get("until")
+		job.Priority = query.Get("priority")
+		job.Cursor = query.Get("cursor")
+		job.Follow = query.Get("follow") == "true" || query.Get("follow") == "1"
+		switch output := query.Get("output"); output {
+		case "json":
+			job.Json = true
+		case "", "text":
+		default:
+			return nil, errors.New("The output must be 'text' or 'json'")
+		}
+		if s := query.Get("lines"); s != "" {
+			lines, err := strconv.Atoi(s)
+			if err != nil {
+				return nil, errors.New("The number of lines must be an integer")
+			}
+			job.Lines = lines
+		}
+		if s := query.Get("timeout"); s != "" {
+			timeout, err := strconv.Atoi(s)
+			if err != nil {
+				return nil, errors.New("The timeout must be an integer number of seconds")
+			}
+			job.Timeout = timeout
+		}
+		explicit := false
+		for _, name := range []string{"since", "until", "cursor", "follow", "lines", "timeout"} {
+			if _, ok := query[name]; ok {
+				explicit = true
+			}
+		}
+		if !explicit {
+			job.FollowRecent()
+		}
+		job.Disconnected = r.Context().Done()
+		if err := job.Check(); err != nil {
+			return nil, err
+		}
+		return job, nil
 	}
 }
 
@@ -153,6 +337,36 @@ func (h *HttpContainerStatusRequest) Handler(conf *HttpConfiguration) JobHandler
 	}
 }
 
+type HttpWaitForHealthRequest struct {
+	cjobs.WaitForHealthRequest
+	DefaultRequest
+}
+
+func (h *HttpWaitForHealthRequest) HttpMethod() string { return "GET" }
+func (h *HttpWaitForHealthRequest) HttpPath() string {
+	return Inline("/container/:id/health", string(h.Id))
+}
+func (h *HttpWaitForHealthRequest) Handler(conf *HttpConfiguration) JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (jobs.Job, error) {
+		id, errg := containers.NewIdentifier(r.PathParam("id"))
+		if errg != nil {
+			return nil, errg
+		}
+		job := &cjobs.WaitForHealthRequest{Id: id}
+		if s := r.URL.Query().Get("timeout"); s != "" {
+			timeout, err := strconv.Atoi(s)
+			if err != nil {
+				return nil, errors.New("The timeout must be an integer number of seconds")
+			}
+			job.Timeout = timeout
+		}
+		if err := job.Check(); err != nil {
+			return nil, err
+		}
+		return job, nil
+	}
+}
+
 type HttpListContainerPortsRequest cjobs.ContainerPortsRequest
 
 func (h *HttpListContainerPortsRequest) HttpMethod() string { return "GET" }
@@ -278,7 +492,8 @@ func (h *HttpPutEnvironmentRequest) Handler(conf *HttpConfiguration) JobHandler
 		}
 		data.Id = id
 
-		return &cjobs.PutEnvironmentRequest{data}, nil
+		restart := r.URL.Query().Get("restart")
+		return &cjobs.PutEnvironmentRequest{EnvironmentDescription: data, Restart: restart == "true" || restart == "1"}, nil
 	}
 }
 
@@ -310,7 +525,58 @@ func (h *HttpPatchEnvironmentRequest) Handler(conf *HttpConfiguration) JobHandle
 		}
 		data.Id = id
 
-		return &cjobs.PatchEnvironmentRequest{data}, nil
+		restart := r.URL.Query().Get("restart")
+		return &cjobs.PatchEnvironmentRequest{EnvironmentDescription: data, Restart: restart == "true" || restart == "1"}, nil
+	}
+}
+
+type HttpEnvironmentVersionsRequest struct {
+	cjobs.EnvironmentVersionsRequest
+	DefaultRequest
+}
+
+func (h *HttpEnvironmentVersionsRequest) HttpMethod() string { return "GET" }
+func (h *HttpEnvironmentVersionsRequest) HttpPath() string {
+	return Inline("/environment/:id/versions", string(h.Id))
+}
+func (h *HttpEnvironmentVersionsRequest) Handler(conf *HttpConfiguration) JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (jobs.Job, error) {
+		id, errg := containers.NewIdentifier(r.PathParam("id"))
+		if errg != nil {
+			return nil, errg
+		}
+		return &cjobs.EnvironmentVersionsRequest{Id: id}, nil
+	}
+}
+
+type HttpRollbackEnvironmentRequest struct {
+	cjobs.RollbackEnvironmentRequest
+	DefaultRequest
+}
+
+func (h *HttpRollbackEnvironmentRequest) HttpMethod() string { return "POST" }
+func (h *HttpRollbackEnvironmentRequest) HttpPath() string {
+	return Inline("/environment/:id/rollback", string(h.Id))
+}
+func (h *HttpRollbackEnvironmentRequest) Handler(conf *HttpConfiguration) JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (jobs.Job, error) {
+		id, errg := containers.NewIdentifier(r.PathParam("id"))
+		if errg != nil {
+			return nil, errg
+		}
+
+		data := cjobs.RollbackEnvironmentRequest{}
+		if r.Body != nil {
+			dec := json.NewDecoder(limitedBodyReader(r))
+			if err := dec.Decode(&data); err != nil && err != io.EOF {
+				return nil, err
+			}
+		}
+		if data.Version < 0 {
+			return nil, errors.New("The version to roll back to must be a positive number")
+		}
+		data.Id = id
+		return &data, nil
 	}
 }
 
@@ -325,6 +591,10 @@ func (h *HttpContentRequest) HttpPath() string {
 	switch h.Type {
 	case cjobs.ContentTypeEnvironment:
 		base = "/environment/:id"
+	case cjobs.ContentTypeSecrets:
+		return Inline("/environment/:id/secrets", h.ContentRequest.Locator)
+	case cjobs.ContentTypeImage:
+		return Inline("/images/:id/export", h.ContentRequest.Locator)
 	default:
 		base = "/content/:id"
 	}
@@ -346,10 +616,22 @@ func (h *HttpContentRequest) Handler(conf *HttpConfiguration) JobHandler {
 			return nil, errors.New("You must specify the type of the content you want to access")
 		}
 
+		locator := r.PathParam("id")
+		if contentType == cjobs.ContentTypeImage {
+			// image names may contain an escaped path separator
+			name, err := url.QueryUnescape(locator)
+			if err != nil {
+				return nil, err
+			}
+			locator = name
+		}
+
 		return &cjobs.ContentRequest{
-			contentType,
-			r.PathParam("id"),
-			r.PathParam("*"),
+			Type:         contentType,
+			Locator:      locator,
+			Subpath:      r.PathParam("*"),
+			Authorized:   ContentTokenTypeFor(r.Request) == contentType,
+			DockerSocket: conf.Docker.Socket,
 		}, nil
 	}
 }
@@ -379,6 +661,486 @@ func (h *HttpLinkContainersRequest) Handler(conf *HttpConfiguration) JobHandler
 	}
 }
 
+type HttpExecContainerRequest struct {
+	cjobs.ExecContainerRequest
+	DefaultRequest
+}
+
+func (h *HttpExecContainerRequest) HttpMethod() string { return "POST" }
+func (h *HttpExecContainerRequest) HttpPath() string {
+	return Inline("/container/:id/exec", string(h.Id))
+}
+func (h *HttpExecContainerRequest) Handler(conf *HttpConfiguration) JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (jobs.Job, error) {
+		id, errg := containers.NewIdentifier(r.PathParam("id"))
+		if errg != nil {
+			return nil, errg
+		}
+
+		data := cjobs.ExecContainerRequest{}
+		if r.Body != nil {
+			dec := json.NewDecoder(limitedBodyReader(r))
+			if err := dec.Decode(&data); err != nil && err != io.EOF {
+				return nil, err
+			}
+		}
+		data.Id = id
+
+		if err := data.Check(); err != nil {
+			return nil, err
+		}
+		return &data, nil
+	}
+}
+
+type HttpGetContainerFilesRequest struct {
+	cjobs.GetContainerFilesRequest
+	DefaultRequest
+}
+
+func (h *HttpGetContainerFilesRequest) HttpMethod() string { return "GET" }
+func (h *HttpGetContainerFilesRequest) HttpPath() string {
+	return Inline("/container/:id/files", string(h.Id))
+}
+func (h *HttpGetContainerFilesRequest) Handler(conf *HttpConfiguration) JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (jobs.Job, error) {
+		id, errg := containers.NewIdentifier(r.PathParam("id"))
+		if errg != nil {
+			return nil, errg
+		}
+		job := &cjobs.GetContainerFilesRequest{
+			Id:           id,
+			Path:         r.URL.Query().Get("path"),
+			DockerSocket: conf.Docker.Socket,
+		}
+		if err := job.Check(); err != nil {
+			return nil, err
+		}
+		return job, nil
+	}
+}
+
+type HttpPutContainerFilesRequest struct {
+	cjobs.PutContainerFilesRequest
+	DefaultRequest
+}
+
+func (h *HttpPutContainerFilesRequest) HttpMethod() string { return "PUT" }
+func (h *HttpPutContainerFilesRequest) HttpPath() string {
+	return Inline("/container/:id/files", string(h.Id))
+}
+func (h *HttpPutContainerFilesRequest) Handler(conf *HttpConfiguration) JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (jobs.Job, error) {
+		id, errg := containers.NewIdentifier(r.PathParam("id"))
+		if errg != nil {
+			return nil, errg
+		}
+		job := &cjobs.PutContainerFilesRequest{
+			Id:           id,
+			Path:         r.URL.Query().Get("path"),
+			Archive:      r.Body,
+			DockerSocket: conf.Docker.Socket,
+		}
+		if err := job.Check(); err != nil {
+			return nil, err
+		}
+		return job, nil
+	}
+}
+
+type HttpExportContainerRequest struct {
+	cjobs.ExportContainerRequest
+	DefaultRequest
+}
+
+func (h *HttpExportContainerRequest) HttpMethod() string { return "GET" }
+func (h *HttpExportContainerRequest) HttpPath() string {
+	return Inline("/container/:id/export", string(h.Id))
+}
+func (h *HttpExportContainerRequest) Handler(conf *HttpConfiguration) JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (jobs.Job, error) {
+		id, errg := containers.NewIdentifier(r.PathParam("id"))
+		if errg != nil {
+			return nil, errg
+		}
+		started := r.URL.Query().Get("started")
+		return &cjobs.ExportContainerRequest{Id: id, Started: started == "true" || started == "1", DockerSocket: conf.Docker.Socket}, nil
+	}
+}
+
+type HttpImportContainerRequest struct {
+	cjobs.ImportContainerRequest
+	DefaultRequest
+}
+
+func (h *HttpImportContainerRequest) HttpMethod() string { return "POST" }
+func (h *HttpImportContainerRequest) HttpPath() string {
+	return Inline("/container/:id/import", string(h.Id))
+}
+func (h *HttpImportContainerRequest) Handler(conf *HttpConfiguration) JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (jobs.Job, error) {
+		id, errg := containers.NewIdentifier(r.PathParam("id"))
+		if errg != nil {
+			return nil, errg
+		}
+		job := &cjobs.ImportContainerRequest{
+			RequestIdentifier: context.Id,
+			Id:                id,
+			Archive:           r.Body,
+			DockerSocket:      conf.Docker.Socket,
+		}
+		if err := job.Check(); err != nil {
+			return nil, err
+		}
+		return job, nil
+	}
+}
+
+type HttpPutVolumeRequest struct {
+	cjobs.PutVolumeRequest
+	DefaultRequest
+}
+
+func (h *HttpPutVolumeRequest) HttpMethod() string { return "PUT" }
+func (h *HttpPutVolumeRequest) HttpPath() string   { return Inline("/volume/:id", string(h.Id)) }
+func (h *HttpPutVolumeRequest) Handler(conf *HttpConfiguration) JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (jobs.Job, error) {
+		id, errg := containers.NewVolumeIdentifier(r.PathParam("id"))
+		if errg != nil {
+			return nil, errg
+		}
+		return &cjobs.PutVolumeRequest{Id: id}, nil
+	}
+}
+
+type HttpDeleteVolumeRequest struct {
+	cjobs.DeleteVolumeRequest
+	DefaultRequest
+}
+
+func (h *HttpDeleteVolumeRequest) HttpMethod() string { return "DELETE" }
+func (h *HttpDeleteVolumeRequest) HttpPath() string   { return Inline("/volume/:id", string(h.Id)) }
+func (h *HttpDeleteVolumeRequest) Handler(conf *HttpConfiguration) JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (jobs.Job, error) {
+		id, errg := containers.NewVolumeIdentifier(r.PathParam("id"))
+		if errg != nil {
+			return nil, errg
+		}
+		return &cjobs.DeleteVolumeRequest{Id: id}, nil
+	}
+}
+
+type HttpListVolumesRequest struct {
+	cjobs.ListVolumesRequest
+	DefaultRequest
+}
+
+func (h *HttpListVolumesRequest) HttpMethod() string { return "GET" }
+func (h *HttpListVolumesRequest) HttpPath() string   { return "/volumes" }
+func (h *HttpListVolumesRequest) Handler(conf *HttpConfiguration) JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (jobs.Job, error) {
+		return &cjobs.ListVolumesRequest{}, nil
+	}
+}
+
+type HttpPutScheduleRequest struct {
+	cjobs.PutScheduleRequest
+	DefaultRequest
+}
+
+func (h *HttpPutScheduleRequest) HttpMethod() string { return "PUT" }
+func (h *HttpPutScheduleRequest) HttpPath() string   { return Inline("/schedule/:id", string(h.Id)) }
+func (h *HttpPutScheduleRequest) Handler(conf *HttpConfiguration) JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (jobs.Job, error) {
+		id, errg := containers.NewScheduleIdentifier(r.PathParam("id"))
+		if errg != nil {
+			return nil, errg
+		}
+
+		data := containers.ScheduleDescription{}
+		if r.Body != nil {
+			dec := json.NewDecoder(limitedBodyReader(r))
+			if err := dec.Decode(&data); err != nil && err != io.EOF {
+				return nil, err
+			}
+		}
+		data.Id = id
+		if err := data.Check(); err != nil {
+			return nil, err
+		}
+
+		return &cjobs.PutScheduleRequest{ScheduleDescription: data}, nil
+	}
+}
+
+type HttpDeleteScheduleRequest struct {
+	cjobs.DeleteScheduleRequest
+	DefaultRequest
+}
+
+func (h *HttpDeleteScheduleRequest) HttpMethod() string { return "DELETE" }
+func (h *HttpDeleteScheduleRequest) HttpPath() string   { return Inline("/schedule/:id", string(h.Id)) }
+func (h *HttpDeleteScheduleRequest) Handler(conf *HttpConfiguration) JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (jobs.Job, error) {
+		id, errg := containers.NewScheduleIdentifier(r.PathParam("id"))
+		if errg != nil {
+			return nil, errg
+		}
+		return &cjobs.DeleteScheduleRequest{Id: id}, nil
+	}
+}
+
+type HttpRunScheduleRequest struct {
+	cjobs.RunScheduleRequest
+	DefaultRequest
+}
+
+func (h *HttpRunScheduleRequest) HttpMethod() string { return "POST" }
+func (h *HttpRunScheduleRequest) HttpPath() string {
+	return Inline("/schedule/:id/run", string(h.Id))
+}
+func (h *HttpRunScheduleRequest) Handler(conf *HttpConfiguration) JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (jobs.Job, error) {
+		id, errg := containers.NewScheduleIdentifier(r.PathParam("id"))
+		if errg != nil {
+			return nil, errg
+		}
+		return &cjobs.RunScheduleRequest{Id: id}, nil
+	}
+}
+
+type HttpScheduleRunsRequest struct {
+	cjobs.ScheduleRunsRequest
+	DefaultRequest
+}
+
+func (h *HttpScheduleRunsRequest) HttpMethod() string { return "GET" }
+func (h *HttpScheduleRunsRequest) HttpPath() string {
+	return Inline("/schedule/:id/runs", string(h.Id))
+}
+func (h *HttpScheduleRunsRequest) Handler(conf *HttpConfiguration) JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (jobs.Job, error) {
+		id, errg := containers.NewScheduleIdentifier(r.PathParam("id"))
+		if errg != nil {
+			return nil, errg
+		}
+		return &cjobs.ScheduleRunsRequest{Id: id}, nil
+	}
+}
+
+type HttpListSchedulesRequest struct {
+	cjobs.ListSchedulesRequest
+	DefaultRequest
+}
+
+func (h *HttpListSchedulesRequest) HttpMethod() string { return "GET" }
+func (h *HttpListSchedulesRequest) HttpPath() string   { return "/schedules" }
+func (h *HttpListSchedulesRequest) Handler(conf *HttpConfiguration) JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (jobs.Job, error) {
+		return &cjobs.ListSchedulesRequest{}, nil
+	}
+}
+
+type HttpPutProfileRequest struct {
+	cjobs.PutProfileRequest
+	DefaultRequest
+}
+
+func (h *HttpPutProfileRequest) HttpMethod() string { return "PUT" }
+func (h *HttpPutProfileRequest) HttpPath() string   { return Inline("/profile/:id", string(h.Id)) }
+func (h *HttpPutProfileRequest) Handler(conf *HttpConfiguration) JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (jobs.Job, error) {
+		id, errg := containers.NewProfileIdentifier(r.PathParam("id"))
+		if errg != nil {
+			return nil, errg
+		}
+
+		data := containers.SecurityProfile{}
+		if r.Body != nil {
+			dec := json.NewDecoder(limitedBodyReader(r))
+			if err := dec.Decode(&data); err != nil && err != io.EOF {
+				return nil, err
+			}
+		}
+		data.Id = id
+		if err := data.Check(); err != nil {
+			return nil, err
+		}
+
+		return &cjobs.PutProfileRequest{SecurityProfile: data}, nil
+	}
+}
+
+type HttpDeleteProfileRequest struct {
+	cjobs.DeleteProfileRequest
+	DefaultRequest
+}
+
+func (h *HttpDeleteProfileRequest) HttpMethod() string { return "DELETE" }
+func (h *HttpDeleteProfileRequest) HttpPath() string   { return Inline("/profile/:id", string(h.Id)) }
+func (h *HttpDeleteProfileRequest) Handler(conf *HttpConfiguration) JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (jobs.Job, error) {
+		id, errg := containers.NewProfileIdentifier(r.PathParam("id"))
+		if errg != nil {
+			return nil, errg
+		}
+		return &cjobs.DeleteProfileRequest{Id: id}, nil
+	}
+}
+
+type HttpListProfilesRequest struct {
+	cjobs.ListProfilesRequest
+	DefaultRequest
+}
+
+func (h *HttpListProfilesRequest) HttpMethod() string { return "GET" }
+func (h *HttpListProfilesRequest) HttpPath() string   { return "/profiles" }
+func (h *HttpListProfilesRequest) Handler(conf *HttpConfiguration) JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (jobs.Job, error) {
+		return &cjobs.ListProfilesRequest{}, nil
+	}
+}
+
+type HttpContainerVersionsRequest struct {
+	cjobs.ContainerVersionsRequest
+	DefaultRequest
+}
+
+func (h *HttpContainerVersionsRequest) HttpMethod() string { return "GET" }
+func (h *HttpContainerVersionsRequest) HttpPath() string {
+	return Inline("/container/:id/versions", string(h.Id))
+}
+func (h *HttpContainerVersionsRequest) Handler(conf *HttpConfiguration) JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (jobs.Job, error) {
+		id, errg := containers.NewIdentifier(r.PathParam("id"))
+		if errg != nil {
+			return nil, errg
+		}
+		return &cjobs.ContainerVersionsRequest{Id: id}, nil
+	}
+}
+
+type HttpRollbackContainerRequest struct {
+	cjobs.RollbackContainerRequest
+	DefaultRequest
+}
+
+func (h *HttpRollbackContainerRequest) HttpMethod() string { return "POST" }
+func (h *HttpRollbackContainerRequest) HttpPath() string {
+	return Inline("/container/:id/rollback", string(h.Id))
+}
+func (h *HttpRollbackContainerRequest) Handler(conf *HttpConfiguration) JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (jobs.Job, error) {
+		id, errg := containers.NewIdentifier(r.PathParam("id"))
+		if errg != nil {
+			return nil, errg
+		}
+
+		data := cjobs.RollbackContainerRequest{}
+		if r.Body != nil {
+			dec := json.NewDecoder(limitedBodyReader(r))
+			if err := dec.Decode(&data); err != nil && err != io.EOF {
+				return nil, err
+			}
+		}
+		data.Id = id
+		return &data, nil
+	}
+}
+
+type HttpPutSliceRequest struct {
+	cjobs.PutSliceRequest
+	DefaultRequest
+}
+
+func (h *HttpPutSliceRequest) HttpMethod() string { return "PUT" }
+func (h *HttpPutSliceRequest) HttpPath() string   { return Inline("/slice/:id", string(h.Id)) }
+func (h *HttpPutSliceRequest) Handler(conf *HttpConfiguration) JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (jobs.Job, error) {
+		id, errg := containers.NewSliceIdentifier(r.PathParam("id"))
+		if errg != nil {
+			return nil, errg
+		}
+
+		data := containers.SliceDescription{}
+		if r.Body != nil {
+			dec := json.NewDecoder(limitedBodyReader(r))
+			if err := dec.Decode(&data); err != nil && err != io.EOF {
+				return nil, err
+			}
+		}
+		data.Id = id
+		if err := data.Check(); err != nil {
+			return nil, err
+		}
+
+		return &cjobs.PutSliceRequest{SliceDescription: data}, nil
+	}
+}
+
+type HttpDeleteSliceRequest struct {
+	cjobs.DeleteSliceRequest
+	DefaultRequest
+}
+
+func (h *HttpDeleteSliceRequest) HttpMethod() string { return "DELETE" }
+func (h *HttpDeleteSliceRequest) HttpPath() string   { return Inline("/slice/:id", string(h.Id)) }
+func (h *HttpDeleteSliceRequest) Handler(conf *HttpConfiguration) JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (jobs.Job, error) {
+		id, errg := containers.NewSliceIdentifier(r.PathParam("id"))
+		if errg != nil {
+			return nil, errg
+		}
+		return &cjobs.DeleteSliceRequest{Id: id}, nil
+	}
+}
+
+type HttpListSlicesRequest struct {
+	cjobs.ListSlicesRequest
+	DefaultRequest
+}
+
+func (h *HttpListSlicesRequest) HttpMethod() string { return "GET" }
+func (h *HttpListSlicesRequest) HttpPath() string   { return "/slices" }
+func (h *HttpListSlicesRequest) Handler(conf *HttpConfiguration) JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (jobs.Job, error) {
+		return &cjobs.ListSlicesRequest{}, nil
+	}
+}
+
+type HttpContainerSliceRequest struct {
+	cjobs.ContainerSliceRequest
+	DefaultRequest
+}
+
+func (h *HttpContainerSliceRequest) HttpMethod() string { return "PUT" }
+func (h *HttpContainerSliceRequest) HttpPath() string {
+	return Inline("/container/:id/slice", string(h.Id))
+}
+func (h *HttpContainerSliceRequest) Handler(conf *HttpConfiguration) JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (jobs.Job, error) {
+		id, errg := containers.NewIdentifier(r.PathParam("id"))
+		if errg != nil {
+			return nil, errg
+		}
+
+		data := cjobs.ContainerSliceRequest{}
+		if r.Body != nil {
+			dec := json.NewDecoder(limitedBodyReader(r))
+			if err := dec.Decode(&data); err != nil && err != io.EOF {
+				return nil, err
+			}
+		}
+		data.Id = id
+		data.RequestIdentifier = context.Id
+
+		if err := data.Check(); err != nil {
+			return nil, err
+		}
+		return &data, nil
+	}
+}
+
 var reSplat = regexp.MustCompile("\\:[a-z\\*]+")
 
 func Inline(s string, with ...string) string {
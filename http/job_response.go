@@ -115,14 +115,19 @@ func (s *httpJobResponse) statusCode(t jobs.ResponseSuccess, stream, data bool)
 }
 
 func (s *httpJobResponse) WritePendingSuccess(name string, value interface{}) {
+	h, ok := value.(HeaderSerialization)
+	if !ok {
+		panic("Passed value does not implement HeaderSerialization for http")
+	}
+	// values written after a streaming success are sent as trailers
+	if s.succeeded {
+		s.response.Header().Set(http.TrailerPrefix+"x-"+name, h.ToHeader())
+		return
+	}
 	if s.pending == nil {
 		s.pending = make(map[string]string)
 	}
-	if h, ok := value.(HeaderSerialization); ok {
-		s.pending[name] = h.ToHeader()
-	} else {
-		panic("Passed value does not implement HeaderSerialization for http")
-	}
+	s.pending[name] = h.ToHeader()
 }
 
 func (s *httpJobResponse) Failure(err error) {
@@ -150,6 +155,8 @@ func (s *httpJobResponse) Failure(err error) {
 			code = http.StatusBadRequest
 		case jobs.ResponseNotAcceptable:
 			code = http.StatusNotAcceptable
+		case jobs.ResponseNotAuthorized:
+			code = http.StatusForbidden
 		case jobs.ResponseRateLimit:
 			code = 429 // http.statusTooManyRequests
 		}
@@ -10,6 +10,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"text/tabwriter"
 )
 
@@ -33,6 +34,33 @@ func (h *HttpRunContainerRequest) MarshalHttpRequestBody(w io.Writer) error {
 	return encoder.Encode(h.RunContainerRequest)
 }
 
+func (h *HttpContainerRunResultRequest) MarshalUrlQuery(query *url.Values) {
+	if h.Lines != 0 {
+		query.Set("lines", strconv.Itoa(h.Lines))
+	}
+}
+func (h *HttpContainerRunResultRequest) UnmarshalHttpResponse(headers http.Header, r io.Reader, mode ResponseContentMode) (interface{}, error) {
+	if r == nil {
+		return nil, errors.New("Unexpected empty response body to HttpContainerRunResultRequest")
+	}
+	decoder := json.NewDecoder(r)
+	result := &cjobs.ContainerRunResultResponse{}
+	if err := decoder.Decode(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (h *HttpPullImageRequest) MarshalHttpRequestBody(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	return encoder.Encode(h.PullImageRequest)
+}
+
+func (h *HttpImportImageRequest) MarshalHttpRequestBody(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	return encoder.Encode(h.ImportImageRequest)
+}
+
 func (h *HttpInstallContainerRequest) MarshalHttpRequestBody(w io.Writer) error {
 	encoder := json.NewEncoder(w)
 	return encoder.Encode(h)
@@ -56,17 +84,177 @@ func (h *HttpPutEnvironmentRequest) MarshalHttpRequestBody(w io.Writer) error {
 	encoder := json.NewEncoder(w)
 	return encoder.Encode(h.EnvironmentDescription)
 }
+func (h *HttpPutEnvironmentRequest) MarshalUrlQuery(query *url.Values) {
+	if h.Restart {
+		query.Set("restart", "true")
+	}
+}
 
 func (h *HttpPatchEnvironmentRequest) MarshalHttpRequestBody(w io.Writer) error {
 	encoder := json.NewEncoder(w)
 	return encoder.Encode(h.EnvironmentDescription)
 }
+func (h *HttpPatchEnvironmentRequest) MarshalUrlQuery(query *url.Values) {
+	if h.Restart {
+		query.Set("restart", "true")
+	}
+}
 
 func (h *HttpLinkContainersRequest) MarshalHttpRequestBody(w io.Writer) error {
 	encoder := json.NewEncoder(w)
 	return encoder.Encode(h.LinkContainersRequest)
 }
 
+func (h *HttpPutSliceRequest) MarshalHttpRequestBody(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	return encoder.Encode(h.SliceDescription)
+}
+
+func (h *HttpPutScheduleRequest) MarshalHttpRequestBody(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	return encoder.Encode(h.ScheduleDescription)
+}
+
+func (h *HttpPutProfileRequest) MarshalHttpRequestBody(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	return encoder.Encode(h.SecurityProfile)
+}
+
+func (h *HttpContainerSliceRequest) MarshalHttpRequestBody(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	return encoder.Encode(h.ContainerSliceRequest)
+}
+
+func (h *HttpExecContainerRequest) MarshalHttpRequestBody(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	return encoder.Encode(h.ExecContainerRequest)
+}
+func (h *HttpExecContainerRequest) UnmarshalHttpResponse(headers http.Header, r io.Reader, mode ResponseContentMode) (interface{}, error) {
+	if r == nil {
+		pending := make(map[string]interface{})
+		if s := headers.Get("X-" + cjobs.PendingExitCodeName); s != "" {
+			code, err := strconv.Atoi(s)
+			if err != nil {
+				return nil, err
+			}
+			pending[cjobs.PendingExitCodeName] = cjobs.ExitCode(code)
+		}
+		return pending, nil
+	}
+	return nil, errors.New("Unexpected response body to HttpExecContainerRequest")
+}
+
+func (h *HttpContainerLogRequest) MarshalUrlQuery(query *url.Values) {
+	if h.Since != "" {
+		query.Set("since", h.Since)
+	}
+	if h.Until != "" {
+		query.Set("until", h.Until)
+	}
+	if h.Lines != 0 {
+		query.Set("lines", strconv.Itoa(h.Lines))
+	}
+	// The server follows recent entries unless the entries are selected
+	query.Set("follow", strconv.FormatBool(h.Follow))
+	if h.Timeout != 0 {
+		query.Set("timeout", strconv.Itoa(h.Timeout))
+	}
+	if h.Priority != "" {
+		query.Set("priority", h.Priority)
+	}
+	if h.Cursor != "" {
+		query.Set("cursor", h.Cursor)
+	}
+	if h.Json {
+		query.Set("output", "json")
+	}
+}
+
+func (h *HttpGetContainerFilesRequest) MarshalUrlQuery(query *url.Values) {
+	query.Set("path", h.Path)
+}
+
+func (h *HttpPutContainerFilesRequest) MarshalUrlQuery(query *url.Values) {
+	query.Set("path", h.Path)
+}
+func (h *HttpPutContainerFilesRequest) MarshalHttpRequestBody(w io.Writer) error {
+	_, err := io.Copy(w, h.Archive)
+	return err
+}
+
+func (h *HttpExportContainerRequest) MarshalUrlQuery(query *url.Values) {
+	if h.Started {
+		query.Set("started", "true")
+	}
+}
+
+func (h *HttpImportContainerRequest) MarshalHttpRequestBody(w io.Writer) error {
+	_, err := io.Copy(w, h.Archive)
+	return err
+}
+func (h *HttpImportContainerRequest) UnmarshalHttpResponse(headers http.Header, r io.Reader, mode ResponseContentMode) (interface{}, error) {
+	if r == nil {
+		pending := make(map[string]interface{})
+		if s := headers.Get("X-" + cjobs.PendingPortMappingName); s != "" {
+			ports, err := port.FromPortPairHeader(s)
+			if err != nil {
+				return nil, err
+			}
+			pending[cjobs.PendingPortMappingName] = ports
+		}
+		return pending, nil
+	}
+	return nil, errors.New("Unexpected response body to HttpImportContainerRequest")
+}
+
+func (h *HttpRollbackContainerRequest) MarshalHttpRequestBody(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	return encoder.Encode(h.RollbackContainerRequest)
+}
+func (h *HttpRollbackContainerRequest) UnmarshalHttpResponse(headers http.Header, r io.Reader, mode ResponseContentMode) (interface{}, error) {
+	if r == nil {
+		pending := make(map[string]interface{})
+		if s := headers.Get("X-" + cjobs.PendingPortMappingName); s != "" {
+			ports, err := port.FromPortPairHeader(s)
+			if err != nil {
+				return nil, err
+			}
+			pending[cjobs.PendingPortMappingName] = ports
+		}
+		return pending, nil
+	}
+	return nil, errors.New("Unexpected response body to HttpRollbackContainerRequest")
+}
+
+func (h *HttpContainerVersionsRequest) UnmarshalHttpResponse(headers http.Header, r io.Reader, mode ResponseContentMode) (interface{}, error) {
+	if r == nil {
+		return nil, errors.New("Unexpected empty response body to HttpContainerVersionsRequest")
+	}
+	decoder := json.NewDecoder(r)
+	versions := &cjobs.ContainerVersionsResponse{}
+	if err := decoder.Decode(versions); err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+func (h *HttpRollbackEnvironmentRequest) MarshalHttpRequestBody(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	return encoder.Encode(h.RollbackEnvironmentRequest)
+}
+
+func (h *HttpEnvironmentVersionsRequest) UnmarshalHttpResponse(headers http.Header, r io.Reader, mode ResponseContentMode) (interface{}, error) {
+	if r == nil {
+		return nil, errors.New("Unexpected empty response body to HttpEnvironmentVersionsRequest")
+	}
+	decoder := json.NewDecoder(r)
+	versions := &cjobs.EnvironmentVersionsResponse{}
+	if err := decoder.Decode(versions); err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
 // Apply the "label" from the job to the response
 type ListContainersResponse struct {
 	cjobs.ListContainersResponse
@@ -74,12 +262,12 @@ type ListContainersResponse struct {
 
 func (l *ListContainersResponse) WriteTableTo(w io.Writer) error {
 	tw := tabwriter.NewWriter(w, 8, 4, 1, ' ', tabwriter.DiscardEmptyColumns)
-	if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", "ID", "SERVER", "ACTIVE", "SUB", "LOAD", "TYPE"); err != nil {
+	if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", "ID", "SERVER", "ACTIVE", "SUB", "LOAD", "TYPE", "HEALTH"); err != nil {
 		return err
 	}
 	for i := range l.Containers {
 		container := &l.Containers[i]
-		if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", container.Id, container.Server, container.ActiveState, container.SubState, container.LoadState, container.JobType); err != nil {
+		if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", container.Id, container.Server, container.ActiveState, container.SubState, container.LoadState, container.JobType, container.Health); err != nil {
 			return err
 		}
 	}
@@ -87,6 +275,11 @@ func (l *ListContainersResponse) WriteTableTo(w io.Writer) error {
 	return nil
 }
 
+func (h *HttpListContainersRequest) MarshalUrlQuery(query *url.Values) {
+	if !h.Selector.Empty() {
+		query.Set("selector", h.Selector.String())
+	}
+}
 func (h *HttpListContainersRequest) UnmarshalHttpResponse(headers http.Header, r io.Reader, mode ResponseContentMode) (interface{}, error) {
 	if r == nil {
 		return nil, errors.New("Unexpected empty response body to HttpListContainersRequest")
@@ -102,3 +295,118 @@ func (h *HttpListContainersRequest) UnmarshalHttpResponse(headers http.Header, r
 	}
 	return list, nil
 }
+
+func (h *HttpListSlicesRequest) UnmarshalHttpResponse(headers http.Header, r io.Reader, mode ResponseContentMode) (interface{}, error) {
+	if r == nil {
+		return nil, errors.New("Unexpected empty response body to HttpListSlicesRequest")
+	}
+	decoder := json.NewDecoder(r)
+	list := &cjobs.ListSlicesResponse{}
+	if err := decoder.Decode(list); err != nil {
+		return nil, err
+	}
+	for i := range list.Slices {
+		list.Slices[i].Server = h.Label
+	}
+	return list, nil
+}
+
+func (h *HttpWaitForHealthRequest) MarshalUrlQuery(query *url.Values) {
+	if h.Timeout != 0 {
+		query.Set("timeout", strconv.Itoa(h.Timeout))
+	}
+}
+
+func (h *HttpContainerStatsRequest) MarshalUrlQuery(query *url.Values) {
+	if h.Interval != 0 {
+		query.Set("interval", strconv.Itoa(h.Interval))
+	}
+}
+func (h *HttpContainerStatsRequest) UnmarshalHttpResponse(headers http.Header, r io.Reader, mode ResponseContentMode) (interface{}, error) {
+	return unmarshalContainerStats(r, h.Label)
+}
+
+func (h *HttpListContainerStatsRequest) MarshalUrlQuery(query *url.Values) {
+	if h.Interval != 0 {
+		query.Set("interval", strconv.Itoa(h.Interval))
+	}
+}
+func (h *HttpListContainerStatsRequest) UnmarshalHttpResponse(headers http.Header, r io.Reader, mode ResponseContentMode) (interface{}, error) {
+	return unmarshalContainerStats(r, h.Label)
+}
+
+func unmarshalContainerStats(r io.Reader, server string) (interface{}, error) {
+	if r == nil {
+		// samples streamed at an interval are written directly
+		return nil, nil
+	}
+	decoder := json.NewDecoder(r)
+	stats := &cjobs.ContainerStatsResponse{}
+	if err := decoder.Decode(stats); err != nil {
+		return nil, err
+	}
+	for i := range stats.Containers {
+		stats.Containers[i].Server = server
+	}
+	return stats, nil
+}
+
+func (h *HttpListVolumesRequest) UnmarshalHttpResponse(headers http.Header, r io.Reader, mode ResponseContentMode) (interface{}, error) {
+	if r == nil {
+		return nil, errors.New("Unexpected empty response body to HttpListVolumesRequest")
+	}
+	decoder := json.NewDecoder(r)
+	list := &cjobs.ListVolumesResponse{}
+	if err := decoder.Decode(list); err != nil {
+		return nil, err
+	}
+	for i := range list.Volumes {
+		list.Volumes[i].Server = h.Label
+	}
+	return list, nil
+}
+
+func (h *HttpListProfilesRequest) UnmarshalHttpResponse(headers http.Header, r io.Reader, mode ResponseContentMode) (interface{}, error) {
+	if r == nil {
+		return nil, errors.New("Unexpected empty response body to HttpListProfilesRequest")
+	}
+	decoder := json.NewDecoder(r)
+	list := &cjobs.ListProfilesResponse{}
+	if err := decoder.Decode(list); err != nil {
+		return nil, err
+	}
+	for i := range list.Profiles {
+		list.Profiles[i].Server = h.Label
+	}
+	return list, nil
+}
+
+func (h *HttpListSchedulesRequest) UnmarshalHttpResponse(headers http.Header, r io.Reader, mode ResponseContentMode) (interface{}, error) {
+	if r == nil {
+		return nil, errors.New("Unexpected empty response body to HttpListSchedulesRequest")
+	}
+	decoder := json.NewDecoder(r)
+	list := &cjobs.ListSchedulesResponse{}
+	if err := decoder.Decode(list); err != nil {
+		return nil, err
+	}
+	for i := range list.Schedules {
+		list.Schedules[i].Server = h.Label
+	}
+	return list, nil
+}
+
+func (h *HttpScheduleRunsRequest) UnmarshalHttpResponse(headers http.Header, r io.Reader, mode ResponseContentMode) (interface{}, error) {
+	if r == nil {
+		return nil, errors.New("Unexpected empty response body to HttpScheduleRunsRequest")
+	}
+	decoder := json.NewDecoder(r)
+	list := &cjobs.ScheduleRunsResponse{}
+	if err := decoder.Decode(list); err != nil {
+		return nil, err
+	}
+	for i := range list.Runs {
+		list.Runs[i].Server = h.Label
+	}
+	return list, nil
+}
@@ -51,7 +51,7 @@ func (h *HttpTransport) RemoteJobFor(locator transport.Locator, j jobs.Job) (job
 		job = j
 		return
 	}
-	baseUrl, errl := urlForLocator(locator)
+	baseUrl, errl := UrlForLocator(locator)
 	if errl != nil {
 		err = errors.New("The provided host is not valid '" + locator.String() + "': " + errl.Error())
 		return
@@ -70,7 +70,9 @@ func (h *HttpTransport) RemoteJobFor(locator transport.Locator, j jobs.Job) (job
 	return
 }
 
-func urlForLocator(locator transport.Locator) (*url.URL, error) {
+// The base URL of the agent at locator, on the default port unless one
+// is given.
+func UrlForLocator(locator transport.Locator) (*url.URL, error) {
 	base := locator.String()
 	if strings.Contains(base, ":") {
 		host, port, err := net.SplitHostPort(base)
@@ -92,6 +94,12 @@ func HttpJobFor(job jobs.Job) (exc RemoteExecutable, err error) {
 		exc = &HttpInstallContainerRequest{InstallContainerRequest: *j}
 	case *cjobs.StartedContainerStateRequest:
 		exc = &HttpStartContainerRequest{StartedContainerStateRequest: *j}
+	case *cjobs.ContainerRunResultRequest:
+		exc = &HttpContainerRunResultRequest{ContainerRunResultRequest: *j}
+	case *cjobs.PullImageRequest:
+		exc = &HttpPullImageRequest{PullImageRequest: *j}
+	case *cjobs.ImportImageRequest:
+		exc = &HttpImportImageRequest{ImportImageRequest: *j}
 	case *cjobs.StoppedContainerStateRequest:
 		exc = &HttpStopContainerRequest{StoppedContainerStateRequest: *j}
 	case *cjobs.RestartContainerRequest:
@@ -100,8 +108,14 @@ func HttpJobFor(job jobs.Job) (exc RemoteExecutable, err error) {
 		exc = &HttpPutEnvironmentRequest{PutEnvironmentRequest: *j}
 	case *cjobs.PatchEnvironmentRequest:
 		exc = &HttpPatchEnvironmentRequest{PatchEnvironmentRequest: *j}
+	case *cjobs.EnvironmentVersionsRequest:
+		exc = &HttpEnvironmentVersionsRequest{EnvironmentVersionsRequest: *j}
+	case *cjobs.RollbackEnvironmentRequest:
+		exc = &HttpRollbackEnvironmentRequest{RollbackEnvironmentRequest: *j}
 	case *cjobs.ContainerStatusRequest:
 		exc = &HttpContainerStatusRequest{ContainerStatusRequest: *j}
+	case *cjobs.WaitForHealthRequest:
+		exc = &HttpWaitForHealthRequest{WaitForHealthRequest: *j}
 	case *cjobs.ContentRequest:
 		exc = &HttpContentRequest{ContentRequest: *j}
 	case *cjobs.DeleteContainerRequest:
@@ -110,6 +124,56 @@ func HttpJobFor(job jobs.Job) (exc RemoteExecutable, err error) {
 		exc = &HttpLinkContainersRequest{LinkContainersRequest: *j}
 	case *cjobs.ListContainersRequest:
 		exc = &HttpListContainersRequest{ListContainersRequest: *j}
+	case *cjobs.PutSliceRequest:
+		exc = &HttpPutSliceRequest{PutSliceRequest: *j}
+	case *cjobs.DeleteSliceRequest:
+		exc = &HttpDeleteSliceRequest{DeleteSliceRequest: *j}
+	case *cjobs.ListSlicesRequest:
+		exc = &HttpListSlicesRequest{ListSlicesRequest: *j}
+	case *cjobs.ContainerSliceRequest:
+		exc = &HttpContainerSliceRequest{ContainerSliceRequest: *j}
+	case *cjobs.ContainerLogRequest:
+		exc = &HttpContainerLogRequest{ContainerLogRequest: *j}
+	case *cjobs.ExecContainerRequest:
+		exc = &HttpExecContainerRequest{ExecContainerRequest: *j}
+	case *cjobs.GetContainerFilesRequest:
+		exc = &HttpGetContainerFilesRequest{GetContainerFilesRequest: *j}
+	case *cjobs.PutContainerFilesRequest:
+		exc = &HttpPutContainerFilesRequest{PutContainerFilesRequest: *j}
+	case *cjobs.ExportContainerRequest:
+		exc = &HttpExportContainerRequest{ExportContainerRequest: *j}
+	case *cjobs.ImportContainerRequest:
+		exc = &HttpImportContainerRequest{ImportContainerRequest: *j}
+	case *cjobs.PutVolumeRequest:
+		exc = &HttpPutVolumeRequest{PutVolumeRequest: *j}
+	case *cjobs.DeleteVolumeRequest:
+		exc = &HttpDeleteVolumeRequest{DeleteVolumeRequest: *j}
+	case *cjobs.ContainerStatsRequest:
+		exc = &HttpContainerStatsRequest{ContainerStatsRequest: *j}
+	case *cjobs.ListContainerStatsRequest:
+		exc = &HttpListContainerStatsRequest{ListContainerStatsRequest: *j}
+	case *cjobs.ListVolumesRequest:
+		exc = &HttpListVolumesRequest{ListVolumesRequest: *j}
+	case *cjobs.PutScheduleRequest:
+		exc = &HttpPutScheduleRequest{PutScheduleRequest: *j}
+	case *cjobs.DeleteScheduleRequest:
+		exc = &HttpDeleteScheduleRequest{DeleteScheduleRequest: *j}
+	case *cjobs.RunScheduleRequest:
+		exc = &HttpRunScheduleRequest{RunScheduleRequest: *j}
+	case *cjobs.ScheduleRunsRequest:
+		exc = &HttpScheduleRunsRequest{ScheduleRunsRequest: *j}
+	case *cjobs.ListSchedulesRequest:
+		exc = &HttpListSchedulesRequest{ListSchedulesRequest: *j}
+	case *cjobs.PutProfileRequest:
+		exc = &HttpPutProfileRequest{PutProfileRequest: *j}
+	case *cjobs.DeleteProfileRequest:
+		exc = &HttpDeleteProfileRequest{DeleteProfileRequest: *j}
+	case *cjobs.ListProfilesRequest:
+		exc = &HttpListProfilesRequest{ListProfilesRequest: *j}
+	case *cjobs.ContainerVersionsRequest:
+		exc = &HttpContainerVersionsRequest{ContainerVersionsRequest: *j}
+	case *cjobs.RollbackContainerRequest:
+		exc = &HttpRollbackContainerRequest{RollbackContainerRequest: *j}
 	default:
 		for _, ext := range extensions {
 			req, errr := ext.HttpJobFor(job)
@@ -129,6 +193,10 @@ func HttpJobFor(job jobs.Job) (exc RemoteExecutable, err error) {
 }
 
 func (h *HttpTransport) ExecuteRemote(baseUrl *url.URL, job RemoteExecutable, res jobs.Response) error {
+	if attach, ok := job.(HttpAttachable); ok && attach.Attached() {
+		return h.executeAttached(baseUrl, job, attach, res)
+	}
+
 	reader, writer := io.Pipe()
 	httpreq, errn := http.NewRequest(job.HttpMethod(), baseUrl.String(), reader)
 	if errn != nil {
@@ -186,6 +254,17 @@ func (h *HttpTransport) ExecuteRemote(baseUrl *url.URL, job RemoteExecutable, re
 		if _, err := io.Copy(w, resp.Body); err != nil {
 			return err
 		}
+		if len(resp.Trailer) > 0 {
+			data, err := job.UnmarshalHttpResponse(resp.Trailer, nil, ResponseTable)
+			if err != nil {
+				return err
+			}
+			if pending, ok := data.(map[string]interface{}); ok {
+				for k := range pending {
+					res.WritePendingSuccess(k, pending[k])
+				}
+			}
+		}
 	case code == 204:
 		data, err := job.UnmarshalHttpResponse(resp.Header, nil, ResponseTable)
 		if err != nil {
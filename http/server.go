@@ -3,6 +3,8 @@
 package http
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"github.com/openshift/geard/config"
@@ -47,31 +49,67 @@ func (conf *HttpConfiguration) Handler() http.Handler {
 
 	handlers := []HttpJobHandler{
 		&HttpRunContainerRequest{},
+		&HttpContainerRunResultRequest{},
 
 		&HttpInstallContainerRequest{},
 		&HttpDeleteContainerRequest{},
 		&HttpContainerLogRequest{},
 		&HttpContainerStatusRequest{},
+		&HttpWaitForHealthRequest{},
+		&HttpContainerStatsRequest{},
 		&HttpListContainerPortsRequest{},
 
 		&HttpStartContainerRequest{},
 		&HttpStopContainerRequest{},
 		&HttpRestartContainerRequest{},
+		&HttpContainerSliceRequest{},
+		&HttpExecContainerRequest{},
+		&HttpGetContainerFilesRequest{},
+		&HttpPutContainerFilesRequest{},
+		&HttpExportContainerRequest{},
+		&HttpImportContainerRequest{},
+		&HttpContainerVersionsRequest{},
+		&HttpRollbackContainerRequest{},
 
 		&HttpLinkContainersRequest{},
 
 		&HttpListContainersRequest{},
+		&HttpListContainerStatsRequest{},
 		&HttpListImagesRequest{},
+		&HttpPullImageRequest{},
+		&HttpImportImageRequest{},
 		&HttpListBuildsRequest{},
 
 		&HttpBuildImageRequest{},
 
 		&HttpPatchEnvironmentRequest{},
 		&HttpPutEnvironmentRequest{},
+		&HttpEnvironmentVersionsRequest{},
+		&HttpRollbackEnvironmentRequest{},
+
+		&HttpPutSliceRequest{},
+		&HttpDeleteSliceRequest{},
+		&HttpListSlicesRequest{},
+
+		&HttpPutVolumeRequest{},
+		&HttpDeleteVolumeRequest{},
+		&HttpListVolumesRequest{},
+
+		&HttpPutScheduleRequest{},
+		&HttpDeleteScheduleRequest{},
+		&HttpRunScheduleRequest{},
+		&HttpScheduleRunsRequest{},
+		&HttpListSchedulesRequest{},
+
+		&HttpPutProfileRequest{},
+		&HttpDeleteProfileRequest{},
+		&HttpListProfilesRequest{},
 
 		&HttpContentRequest{},
 		&HttpContentRequest{ContentRequest: cjobs.ContentRequest{Subpath: "*"}},
 		&HttpContentRequest{ContentRequest: cjobs.ContentRequest{Type: cjobs.ContentTypeEnvironment}},
+		&HttpContentRequest{ContentRequest: cjobs.ContentRequest{Type: cjobs.ContentTypeSecrets}},
+		&HttpContentRequest{ContentRequest: cjobs.ContentRequest{Type: cjobs.ContentTypeImage}},
 	}
 
 	for _, ext := range extensions {
@@ -87,7 +125,7 @@ func (conf *HttpConfiguration) Handler() http.Handler {
 	}
 
 	handler.SetRoutes(routes...)
-	return &handler
+	return recordHijacker(&handler)
 }
 
 func (conf *HttpConfiguration) jobRestHandler(handler HttpJobHandler) rest.Route {
@@ -147,6 +185,11 @@ func (conf *HttpConfiguration) handleWithMethod(method JobHandler) func(*rest.Re
 			return
 		}
 
+		if attach, ok := job.(HttpAttachable); ok && attach.Attached() {
+			conf.serveAttached(w.ResponseWriter, r.Request, context, job, attach)
+			return
+		}
+
 		mode := ResponseJson
 		if r.Header.Get("Accept") == "text/plain" {
 			mode = ResponseTable
@@ -184,3 +227,32 @@ func serveRequestError(w http.ResponseWriter, err apiRequestError) {
 	log.Print(err.Message, err.Error)
 	http.Error(w, err.Message, err.Status)
 }
+
+// Requests forwarded by a content token handler carry a header with a
+// value known only to this process, so that a caller cannot claim the
+// authority of a token it does not hold.
+const contentTokenHeader = "X-Content-Token"
+
+var contentTokenSecret = newContentTokenSecret()
+
+func newContentTokenSecret() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Mark a request as authorized by a valid content token of the type.
+func AuthorizeContentToken(r *http.Request, contentType string) {
+	r.Header.Set(contentTokenHeader, contentTokenSecret+":"+contentType)
+}
+
+// The type of the content token that authorized the request, if any.
+func ContentTokenTypeFor(r *http.Request) string {
+	value := r.Header.Get(contentTokenHeader)
+	if !strings.HasPrefix(value, contentTokenSecret+":") {
+		return ""
+	}
+	return strings.TrimPrefix(value, contentTokenSecret+":")
+}
@@ -79,7 +79,7 @@ func (idler *Idler) Run() {
 		case e := <-events:
 			fmt.Printf("[%v] Event: %v\n", time.Now().Format(time.RFC3339), e)
 			switch {
-			case e.Type == containers.Stopped || e.Type == containers.Deleted || e.Type == containers.Errored:
+			case e.Type == containers.Stopped || e.Type == containers.Deleted || e.Type == containers.Errored || e.Type == containers.CrashLooping:
 				iptables.DeleteContainer(e.Id, idler.hostIp)
 			case e.Type == containers.Started:
 				iptables.UnidleContainer(e.Id, idler.hostIp)
@@ -16,6 +16,7 @@ const (
 	ResponseInvalidRequest
 	ResponseRateLimit
 	ResponseNotAcceptable
+	ResponseNotAuthorized
 )
 
 // An error with a code and message to user
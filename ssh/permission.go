@@ -5,8 +5,10 @@ import (
 	"github.com/openshift/geard/config"
 	"github.com/openshift/geard/containers"
 	"github.com/openshift/geard/utils"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 const ContainerPermissionType = "container"
@@ -53,3 +55,49 @@ func SshAccessBasePath(i containers.Identifier) string {
 func SshAccessPathFor(i containers.Identifier, name string) string {
 	return utils.IsolateContentPathWithPerm(filepath.Join(config.ContainerBasePath(), "access", "containers", "ssh"), string(i), name, 0775)
 }
+
+// Return the public keys, in the authorized_keys format, that have been
+// granted access to a container.
+func AccessKeysFor(id containers.Identifier) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(SshAccessBasePath(id), "*"))
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(paths))
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		keys = append(keys, strings.TrimSpace(string(data)))
+	}
+	return keys, nil
+}
+
+// Store public keys in the authorized_keys format and grant them access
+// to an installed container.
+func GrantAccessKeys(id containers.Identifier, keys []string) error {
+	handler, _ := KeyTypeHandlerFor("authorized_keys")
+	value, err := json.Marshal(string(id))
+	if err != nil {
+		return err
+	}
+	permission := utils.RawMessage(value)
+	for _, key := range keys {
+		raw, err := json.Marshal(key)
+		if err != nil {
+			return err
+		}
+		locator, err := handler.CreateKey(utils.RawMessage(raw))
+		if err != nil {
+			return err
+		}
+		if err := (containerPermission{}).CreatePermission(locator, &permission); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,11 +1,16 @@
 package systemd
 
 import (
+	"bufio"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -13,7 +18,14 @@ var ErrLogWriteTimeout = errors.New("journal: Maximum duration exceeded, timeout
 var ErrLogComplete = errors.New("journal: Closed by caller")
 
 func ProcessLogsForUnit(unit string) (io.ReadCloser, error) {
-	cmd := exec.Command("/usr/bin/journalctl", "--since=now", "-q", "-f", "--unit", unit)
+	return OpenLogsForUnit(unit, &LogOptions{Since: "now", Follow: true})
+}
+
+// Stream the journal entries of a unit selected by opts.  The stream
+// ends when it is closed or, if the entries are not followed, once they
+// have all been read.
+func OpenLogsForUnit(unit string, opts *LogOptions) (io.ReadCloser, error) {
+	cmd := exec.Command("/usr/bin/journalctl", opts.args(unit)...)
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
 		return nil, err
@@ -21,17 +33,225 @@ func ProcessLogsForUnit(unit string) (io.ReadCloser, error) {
 	if err := cmd.Start(); err != nil {
 		return nil, err
 	}
-	return stdout, nil
+	if !opts.Json {
+		return &journalReader{stdout, cmd}, nil
+	}
+	r, w := io.Pipe()
+	go func() {
+		w.CloseWithError(copyLogEntries(w, stdout))
+	}()
+	return &journalReader{r, cmd}, nil
+}
+
+// Stream the journal entries written from now on that match every
+// FIELD=value pair, each as a JSON object of its fields.
+func FollowJournalMatches(matches ...string) (io.ReadCloser, error) {
+	args := append([]string{"-q", "--follow", "--lines=0", "--output=json"}, matches...)
+	cmd := exec.Command("/usr/bin/journalctl", args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &journalReader{stdout, cmd}, nil
+}
+
+type journalReader struct {
+	io.ReadCloser
+	cmd *exec.Cmd
+}
+
+func (r *journalReader) Close() error {
+	err := r.ReadCloser.Close()
+	r.cmd.Process.Kill()
+	r.cmd.Wait()
+	return err
+}
+
+// Return the cursor of the newest entry in the journal.  Entries written
+// later can be read with LogOptions.Cursor.
+func CurrentCursor() (string, error) {
+	out, err := exec.Command("/usr/bin/journalctl", "--no-pager", "-q", "-n", "1", "-o", "json").Output()
+	if err != nil {
+		return "", err
+	}
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal(out, &fields); err != nil {
+		return "", err
+	}
+	cursor, _ := fields["__CURSOR"].(string)
+	return cursor, nil
+}
+
+// The default number of entries written before the end of the journal
+// when no time range is given.
+const DefaultLogLines = 30
+
+// Options that select the journal entries of a unit and their format.
+type LogOptions struct {
+	// Limit entries to those at or after / before a time, either an
+	// RFC3339 timestamp or a journalctl time specification like
+	// "today" or "-1h"
+	Since string `json:"Since,omitempty"`
+	Until string `json:"Until,omitempty"`
+	// The number of entries to show before following, 0 for the
+	// default and -1 for all entries in the range
+	Lines int `json:"Lines,omitempty"`
+	// Show only the entries after the entry with this journal cursor,
+	// used to resume a stream without gaps or duplicates
+	Cursor string `json:"Cursor,omitempty"`
+	// Continue writing new entries as they arrive
+	Follow bool `json:"Follow,omitempty"`
+	// The maximum priority of entries to show, a name or number from
+	// 0 (emerg) to 7 (debug), or a range like "err..info"
+	Priority string `json:"Priority,omitempty"`
+	// Write each entry as a JSON object instead of text
+	Json bool `json:"Json,omitempty"`
+}
+
+var priorities = map[string]bool{
+	"emerg": true, "alert": true, "crit": true, "err": true, "warning": true, "notice": true, "info": true, "debug": true,
+	"0": true, "1": true, "2": true, "3": true, "4": true, "5": true, "6": true, "7": true,
+}
+var allowedTimeSpec = regexp.MustCompile("\\A[a-zA-Z0-9:+\\- ]{1,64}\\z")
+var allowedCursor = regexp.MustCompile("\\A[a-zA-Z0-9=;]{1,256}\\z")
+
+func (o *LogOptions) Check() error {
+	if o.Priority != "" {
+		for _, p := range strings.SplitN(o.Priority, "..", 2) {
+			if !priorities[p] {
+				return errors.New(fmt.Sprintf("The priority '%s' must be a name or number from 0 (emerg) to 7 (debug)", o.Priority))
+			}
+		}
+	}
+	for _, spec := range []string{o.Since, o.Until} {
+		if spec == "" {
+			continue
+		}
+		if _, err := time.Parse(time.RFC3339, spec); err == nil {
+			continue
+		}
+		if !allowedTimeSpec.MatchString(spec) {
+			return errors.New(fmt.Sprintf("The time '%s' must be an RFC3339 timestamp or a journalctl time specification", spec))
+		}
+	}
+	if o.Cursor != "" && !allowedCursor.MatchString(o.Cursor) {
+		return errors.New("The cursor must be a journal cursor returned with an earlier entry")
+	}
+	if o.Lines < -1 {
+		return errors.New("The number of lines must be -1 (all) or greater")
+	}
+	return nil
 }
 
+func (o *LogOptions) args(unit string) []string {
+	args := []string{"-q", "--unit", unit}
+	if o.Since != "" {
+		args = append(args, "--since="+journalTime(o.Since))
+	}
+	if o.Until != "" {
+		args = append(args, "--until="+journalTime(o.Until))
+	}
+	if o.Cursor != "" {
+		args = append(args, "--after-cursor="+o.Cursor)
+	}
+	lines := o.Lines
+	if lines == 0 && o.Since == "" && o.Until == "" && o.Cursor == "" {
+		lines = DefaultLogLines
+	}
+	switch {
+	case lines > 0:
+		args = append(args, "--lines="+strconv.Itoa(lines))
+	case o.Follow:
+		args = append(args, "--no-tail")
+	}
+	if o.Priority != "" {
+		args = append(args, "--priority="+o.Priority)
+	}
+	if o.Follow {
+		args = append(args, "--follow")
+	}
+	if o.Json {
+		args = append(args, "--output=json")
+	}
+	return args
+}
+
+// journalctl only accepts timestamps in local time
+func journalTime(spec string) string {
+	if t, err := time.Parse(time.RFC3339, spec); err == nil {
+		return t.Local().Format("2006-01-02 15:04:05")
+	}
+	return spec
+}
+
+// A journal entry written when LogOptions.Json is set.
+type LogEntry struct {
+	Cursor   string
+	Time     time.Time
+	Priority int
+	Message  string
+}
+
+// Convert the journalctl JSON export of entries to LogEntry objects.
+func copyLogEntries(w io.Writer, r io.Reader) error {
+	scan := bufio.NewScanner(r)
+	scan.Buffer(make([]byte, 64*1024), 1024*1024)
+	encoder := json.NewEncoder(w)
+	for scan.Scan() {
+		fields := make(map[string]interface{})
+		if err := json.Unmarshal(scan.Bytes(), &fields); err != nil {
+			log.Printf("journal: Unable to parse entry: %v", err)
+			continue
+		}
+		entry := LogEntry{}
+		if s, ok := fields["__CURSOR"].(string); ok {
+			entry.Cursor = s
+		}
+		if s, ok := fields["__REALTIME_TIMESTAMP"].(string); ok {
+			if usec, err := strconv.ParseInt(s, 10, 64); err == nil {
+				entry.Time = time.Unix(usec/1000000, (usec%1000000)*1000)
+			}
+		}
+		if s, ok := fields["PRIORITY"].(string); ok {
+			entry.Priority, _ = strconv.Atoi(s)
+		}
+		switch m := fields["MESSAGE"].(type) {
+		case string:
+			entry.Message = m
+		case []interface{}:
+			// binary messages are exported as an array of bytes
+			b := make([]byte, 0, len(m))
+			for i := range m {
+				if n, ok := m[i].(float64); ok {
+					b = append(b, byte(n))
+				}
+			}
+			entry.Message = string(b)
+		}
+		if err := encoder.Encode(&entry); err != nil {
+			return err
+		}
+	}
+	return scan.Err()
+}
+
+// Write the journal entries of a unit from the last previous seconds and
+// then follow new entries until the channel is signalled.
 func WriteLogsTo(w io.Writer, unit string, previous int, until <-chan time.Time) error {
-	var arg string
 	if previous == 0 {
-		arg = "--since=now"
-	} else {
-		arg = fmt.Sprintf("--since=-%d", previous)
+		return WriteLogsWithOptionsTo(w, unit, &LogOptions{Since: "now", Follow: true}, until)
 	}
-	cmd := exec.Command("/usr/bin/journalctl", arg, "-f", "-q", "--unit", unit)
+	return WriteLogsWithOptionsTo(w, unit, &LogOptions{Since: fmt.Sprintf("-%d", previous), Follow: true}, until)
+}
+
+// Write the journal entries of a unit selected by opts.  If the entries
+// are followed, writing stops when the channel is signalled, the caller
+// stops reading, or journalctl exits.
+func WriteLogsWithOptionsTo(w io.Writer, unit string, opts *LogOptions, until <-chan time.Time) error {
+	cmd := exec.Command("/usr/bin/journalctl", opts.args(unit)...)
 	stdout, errp := cmd.StdoutPipe()
 	if errp != nil {
 		return errp
@@ -43,10 +263,30 @@ func WriteLogsTo(w io.Writer, unit string, previous int, until <-chan time.Time)
 
 	outch := make(chan error, 1)
 	go func() {
-		_, err := io.Copy(w, stdout)
+		var err error
+		if opts.Json {
+			err = copyLogEntries(w, stdout)
+		} else {
+			_, err = io.Copy(w, stdout)
+		}
 		outch <- err
 		close(outch)
 	}()
+
+	if !opts.Follow {
+		// journalctl exits once the entries are written, and the pipe
+		// must be drained before waiting
+		err := <-outch
+		if err != nil {
+			stdout.Close()
+			cmd.Process.Kill()
+		}
+		if errw := cmd.Wait(); errw != nil && err == nil {
+			err = errw
+		}
+		return err
+	}
+
 	prcch := make(chan error, 1)
 	go func() {
 		err := cmd.Wait()
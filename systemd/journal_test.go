@@ -0,0 +1,63 @@
+package systemd
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLogOptionsArgs(t *testing.T) {
+	opts := &LogOptions{}
+	if args := opts.args("ctr-a.service"); !reflect.DeepEqual(args, []string{"-q", "--unit", "ctr-a.service", "--lines=30"}) {
+		t.Errorf("Unexpected default arguments %v", args)
+	}
+	opts = &LogOptions{Since: "today", Lines: -1, Follow: true, Priority: "err", Json: true}
+	if err := opts.Check(); err != nil {
+		t.Fatal(err)
+	}
+	if args := opts.args("ctr-a.service"); !reflect.DeepEqual(args, []string{"-q", "--unit", "ctr-a.service", "--since=today", "--no-tail", "--priority=err", "--follow", "--output=json"}) {
+		t.Errorf("Unexpected arguments %v", args)
+	}
+	opts = &LogOptions{Cursor: "s=a1;i=2f;b=c3;m=4;t=5;x=6", Follow: true}
+	if err := opts.Check(); err != nil {
+		t.Fatal(err)
+	}
+	if args := opts.args("ctr-a.service"); !reflect.DeepEqual(args, []string{"-q", "--unit", "ctr-a.service", "--after-cursor=s=a1;i=2f;b=c3;m=4;t=5;x=6", "--no-tail", "--follow"}) {
+		t.Errorf("Unexpected arguments %v", args)
+	}
+	for _, opts := range []LogOptions{{Priority: "loud"}, {Since: "--output=cat"}, {Lines: -2}, {Cursor: "s=1\n--merge"}} {
+		if err := opts.Check(); err == nil {
+			t.Errorf("Expected %+v to be invalid", opts)
+		}
+	}
+}
+
+func TestCopyLogEntries(t *testing.T) {
+	export := `{"__CURSOR":"s=1;i=2","__REALTIME_TIMESTAMP":"1400000000000001","PRIORITY":"6","MESSAGE":"started"}
+{"__CURSOR":"s=1;i=3","__REALTIME_TIMESTAMP":"1400000001000000","PRIORITY":"3","MESSAGE":[104,105]}
+`
+	buf := &bytes.Buffer{}
+	if err := copyLogEntries(buf, strings.NewReader(export)); err != nil {
+		t.Fatal(err)
+	}
+	decoder := json.NewDecoder(buf)
+	entries := []LogEntry{}
+	for decoder.More() {
+		entry := LogEntry{}
+		if err := decoder.Decode(&entry); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected two entries, got %+v", entries)
+	}
+	if entries[0].Cursor != "s=1;i=2" || entries[0].Priority != 6 || entries[0].Message != "started" || entries[0].Time.UnixNano() != 1400000000000001000 {
+		t.Errorf("Unexpected entry %+v", entries[0])
+	}
+	if entries[1].Message != "hi" || entries[1].Priority != 3 {
+		t.Errorf("Unexpected entry %+v", entries[1])
+	}
+}
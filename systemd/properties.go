@@ -0,0 +1,21 @@
+package systemd
+
+import (
+	db "github.com/godbus/dbus"
+	"github.com/openshift/go-systemd/dbus"
+)
+
+// Resource control properties that may be applied to a running unit
+// via SetUnitProperties. See systemd.resource-control(5).
+
+func PropMemoryLimit(bytes uint64) dbus.Property {
+	return dbus.Property{Name: "MemoryLimit", Value: db.MakeVariant(bytes)}
+}
+
+func PropCPUShares(shares uint64) dbus.Property {
+	return dbus.Property{Name: "CPUShares", Value: db.MakeVariant(shares)}
+}
+
+func PropTasksMax(tasks uint64) dbus.Property {
+	return dbus.Property{Name: "TasksMax", Value: db.MakeVariant(tasks)}
+}
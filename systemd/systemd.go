@@ -228,6 +228,24 @@ func WriteStatusTo(w io.Writer, unit string) error {
 	return nil
 }
 
+// Return the result of the last run of a service (success, exit-code,
+// start-limit, ...).  The Service interface is not exposed by the dbus
+// client, so ask systemctl.
+func GetServiceResult(unit string) (string, error) {
+	out, err := exec.Command("/usr/bin/systemctl", "show", "-p", "Result", unit).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(strings.TrimSpace(string(out)), "Result="), nil
+}
+
+// Whether a service result means systemd stopped restarting the service
+// because it failed too often.  Newer versions of systemd report
+// start-limit-hit instead of start-limit.
+func IsStartLimitResult(result string) bool {
+	return result == "start-limit" || result == "start-limit-hit"
+}
+
 // Get the custom properties set in the unit file as a map.
 // TODO: Work with upstream to add an API for this.
 func GetUnitFileProperties(path string) (map[string]string, error) {
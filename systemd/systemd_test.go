@@ -0,0 +1,18 @@
+package systemd
+
+import (
+	"testing"
+)
+
+func TestIsStartLimitResult(t *testing.T) {
+	for _, result := range []string{"start-limit", "start-limit-hit"} {
+		if !IsStartLimitResult(result) {
+			t.Errorf("Expected %s to be a start limit result", result)
+		}
+	}
+	for _, result := range []string{"", "success", "exit-code", "timeout"} {
+		if IsStartLimitResult(result) {
+			t.Errorf("Expected %s not to be a start limit result", result)
+		}
+	}
+}